@@ -0,0 +1,201 @@
+package adaptors
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"web_page_analyzer/internal/pkg/errors"
+)
+
+// hostLookuper resolves host names. *net.Resolver satisfies it.
+type hostLookuper interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// lookupLimitedDialer resolves host names itself so it can cap how many DNS
+// lookups are in flight at once. Lookups over the limit wait for a slot. The
+// resolved addresses are then dialed the way net.Dialer dials a host name:
+// addresses of the first address's family first, racing the other family
+// once those fail or FallbackDelay passes (RFC 6555).
+type lookupLimitedDialer struct {
+	dialer   *net.Dialer
+	resolver hostLookuper
+	slots    chan struct{}
+}
+
+// defaultFallbackDelay is what net.Dialer waits before racing the other
+// address family when FallbackDelay is zero
+const defaultFallbackDelay = 300 * time.Millisecond
+
+func newLookupLimitedDialer(maxLookups int, resolver hostLookuper) *lookupLimitedDialer {
+	return &lookupLimitedDialer{
+		dialer:   &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second},
+		resolver: resolver,
+		slots:    make(chan struct{}, maxLookups),
+	}
+}
+
+// newLookupLimitedTransport returns a clone of http.DefaultTransport dialing
+// through d
+func newLookupLimitedTransport(d *lookupLimitedDialer) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.DialContext = d.DialContext
+	return t
+}
+
+func (d *lookupLimitedDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return d.dialer.DialContext(ctx, network, address)
+	}
+
+	addrs, err := d.lookup(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	addrs = addrsForNetwork(network, addrs)
+	if len(addrs) == 0 {
+		return nil, errors.Errorf(`no %s addresses found for %s`, network, host)
+	}
+
+	primaries, fallbacks := splitByFamily(addrs)
+	if len(fallbacks) == 0 {
+		return d.dialSerial(ctx, network, primaries, port)
+	}
+	return d.dialParallel(ctx, network, primaries, fallbacks, port)
+}
+
+// dialSerial dials addrs in order, returning the first connection made
+func (d *lookupLimitedDialer) dialSerial(ctx context.Context, network string, addrs []string, port string) (net.Conn, error) {
+	var dialErr error
+	for _, addr := range addrs {
+		conn, err := d.dialer.DialContext(ctx, network, net.JoinHostPort(addr, port))
+		if err == nil {
+			return conn, nil
+		}
+		dialErr = err
+	}
+	return nil, dialErr
+}
+
+// dialParallel dials primaries, racing fallbacks once the primaries have
+// failed or the fallback delay has passed. The first connection made wins and
+// the other is closed.
+func (d *lookupLimitedDialer) dialParallel(ctx context.Context, network string, primaries, fallbacks []string, port string) (net.Conn, error) {
+	type dialResult struct {
+		conn    net.Conn
+		err     error
+		primary bool
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan dialResult, 2)
+	dial := func(addrs []string, primary bool) {
+		conn, err := d.dialSerial(ctx, network, addrs, port)
+		results <- dialResult{conn: conn, err: err, primary: primary}
+	}
+
+	go dial(primaries, true)
+	delay := d.dialer.FallbackDelay
+	if delay <= 0 {
+		delay = defaultFallbackDelay
+	}
+	fallbackTimer := time.NewTimer(delay)
+	defer fallbackTimer.Stop()
+
+	pending, fallbackStarted := 1, false
+	startFallback := func() {
+		if !fallbackStarted {
+			fallbackStarted = true
+			pending++
+			go dial(fallbacks, false)
+		}
+	}
+	var primaryErr, fallbackErr error
+	for pending > 0 {
+		select {
+		case <-fallbackTimer.C:
+			startFallback()
+		case res := <-results:
+			pending--
+			if res.err == nil {
+				// close the loser should it still connect
+				go func(pending int) {
+					for ; pending > 0; pending-- {
+						if late := <-results; late.conn != nil {
+							late.conn.Close()
+						}
+					}
+				}(pending)
+				return res.conn, nil
+			}
+			if res.primary {
+				primaryErr = res.err
+			} else {
+				fallbackErr = res.err
+			}
+			startFallback()
+		}
+	}
+	if primaryErr != nil {
+		return nil, primaryErr
+	}
+	return nil, fallbackErr
+}
+
+// addrsForNetwork keeps the addresses network can dial, e.g. only IPv4 ones
+// for "tcp4"
+func addrsForNetwork(network string, addrs []string) []string {
+	var keep []string
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		switch {
+		case ip == nil:
+			continue
+		case strings.HasSuffix(network, "4") && ip.To4() == nil:
+			continue
+		case strings.HasSuffix(network, "6") && ip.To4() != nil:
+			continue
+		}
+		keep = append(keep, addr)
+	}
+	return keep
+}
+
+// splitByFamily splits addrs into those of the first address's family and
+// the rest, keeping their order
+func splitByFamily(addrs []string) (primaries []string, fallbacks []string) {
+	isIPv4 := func(addr string) bool { return net.ParseIP(addr).To4() != nil }
+	first := isIPv4(addrs[0])
+	for _, addr := range addrs {
+		if isIPv4(addr) == first {
+			primaries = append(primaries, addr)
+		} else {
+			fallbacks = append(fallbacks, addr)
+		}
+	}
+	return primaries, fallbacks
+}
+
+func (d *lookupLimitedDialer) lookup(ctx context.Context, host string) ([]string, error) {
+	select {
+	case d.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, errors.Wrap(ctx.Err(), `waiting for a dns lookup slot`)
+	}
+	defer func() { <-d.slots }()
+
+	addrs, err := d.resolver.LookupHost(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, errors.Errorf(`no addresses found for %s`, host)
+	}
+	return addrs, nil
+}
@@ -0,0 +1,144 @@
+package adaptors
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowLookuper resolves every host to 127.0.0.1 after a delay, recording the
+// highest number of lookups seen in flight
+type slowLookuper struct {
+	inFlight atomic.Int32
+	peak     atomic.Int32
+}
+
+func (s *slowLookuper) LookupHost(ctx context.Context, host string) ([]string, error) {
+	n := s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+	for {
+		peak := s.peak.Load()
+		if n <= peak || s.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	return []string{"127.0.0.1"}, nil
+}
+
+func TestLookupLimitedDialer_BoundsConcurrentLookups(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	const maxLookups = 3
+	resolver := &slowLookuper{}
+	dialer := newLookupLimitedDialer(maxLookups, resolver)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := dialer.DialContext(context.Background(), "tcp", net.JoinHostPort("example.test", port))
+			if err != nil {
+				t.Errorf("dial failed: %v", err)
+				return
+			}
+			conn.Close()
+		}()
+	}
+	wg.Wait()
+
+	if peak := resolver.peak.Load(); peak > maxLookups {
+		t.Errorf("expected at most %d concurrent lookups, got %d", maxLookups, peak)
+	}
+	if peak := resolver.peak.Load(); peak == 0 {
+		t.Error("expected lookups to go through the resolver")
+	}
+}
+
+func TestLookupLimitedDialer_WaitingLookupHonoursContext(t *testing.T) {
+	dialer := newLookupLimitedDialer(1, &slowLookuper{})
+	dialer.slots <- struct{}{} // occupy the only slot
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := dialer.DialContext(ctx, "tcp", "example.test:80"); err == nil {
+		t.Error("expected an error while waiting for a lookup slot")
+	}
+}
+
+// fixedLookuper resolves every host to addrs
+type fixedLookuper []string
+
+func (f fixedLookuper) LookupHost(ctx context.Context, host string) ([]string, error) {
+	return f, nil
+}
+
+func TestLookupLimitedDialer_FallsBackToOtherFamily(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	// nothing listens on the IPv6 loopback, so only the IPv4 fallback connects
+	dialer := newLookupLimitedDialer(1, fixedLookuper{"::1", "127.0.0.1"})
+	conn, err := dialer.DialContext(context.Background(), "tcp", net.JoinHostPort("example.test", port))
+	if err != nil {
+		t.Fatalf("expected the IPv4 fallback to connect, got %v", err)
+	}
+	defer conn.Close()
+	if got := conn.RemoteAddr().(*net.TCPAddr).IP.String(); got != "127.0.0.1" {
+		t.Errorf("expected a connection to 127.0.0.1, got %s", got)
+	}
+}
+
+func TestAddrsForNetwork(t *testing.T) {
+	addrs := []string{"::1", "127.0.0.1"}
+	tests := []struct {
+		network string
+		want    []string
+	}{
+		{network: "tcp", want: []string{"::1", "127.0.0.1"}},
+		{network: "tcp4", want: []string{"127.0.0.1"}},
+		{network: "tcp6", want: []string{"::1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			got := addrsForNetwork(tt.network, addrs)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWebClient_LinkCheckTransportSharesLookupLimit(t *testing.T) {
+	if got := NewWebClient(time.Second, nil).LinkCheckTransport(); got != http.DefaultTransport {
+		t.Error("expected http.DefaultTransport without a lookup limit")
+	}
+
+	w := NewWebClient(time.Second, nil, WithMaxConcurrentLookups(2))
+	transport, ok := w.LinkCheckTransport().(*http.Transport)
+	if !ok || transport.DialContext == nil {
+		t.Fatal("expected a transport dialing through the lookup limiter")
+	}
+	// occupy every slot of the client's limiter; the probe transport must wait on it
+	w.lookupDialer.slots <- struct{}{}
+	w.lookupDialer.slots <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := transport.DialContext(ctx, "tcp", "example.test:80"); err == nil {
+		t.Error("expected the probe dial to wait for the shared lookup slots")
+	}
+}
@@ -2,60 +2,413 @@ package adaptors
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
 	"time"
+	"web_page_analyzer/internal/domain/adaptors"
 	"web_page_analyzer/internal/pkg/errors"
 
 	"web_page_analyzer/internal/pkg/metrics"
+	"web_page_analyzer/internal/pkg/requestid"
+	"web_page_analyzer/internal/pkg/tracing"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type WebClient struct {
-	client *http.Client
-	log    *log.Logger
+	client          *http.Client
+	log             *log.Logger
+	retries         int
+	maxLookups      int
+	acceptTruncated bool
+	// forwardRequestID sends the inbound request ID along with page fetches
+	forwardRequestID bool
+	// maxBodyBytes caps the response body, zero leaves it unbounded
+	maxBodyBytes int64
+	maxRedirects int
+	userAgent    string
+	// retryBackoff is the wait before the first retry, doubling with each
+	// further one, and retryJitter the most added to it at random
+	retryBackoff time.Duration
+	retryJitter  time.Duration
+	// sleep waits between attempts, sleepContext when nil
+	sleep func(ctx context.Context, d time.Duration) error
+	// lookupDialer bounds DNS lookups when maxLookups is set
+	lookupDialer *lookupLimitedDialer
 }
 
-func NewWebClient(timeout time.Duration, log *log.Logger) *WebClient {
+// defaultMaxBodyBytes is far more than a real page weighs but keeps a huge
+// download from exhausting memory
+const defaultMaxBodyBytes = 10 << 20
+
+// defaultMaxRedirects matches what net/http follows on its own
+const defaultMaxRedirects = 10
+
+// maxRetryWait is the longest the client waits before a retry. A Retry-After
+// asking for more ends the retries instead.
+const maxRetryWait = 30 * time.Second
+
+// defaultUserAgent mimics a desktop browser, so sites serve the page their
+// visitors see
+const defaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36"
+
+type WebClientOption func(*WebClient)
+
+// WithRetries retries a fetch up to n more times after a transport error,
+// a failed body read or a 429/502/503/504 response
+func WithRetries(n int) WebClientOption {
+	return func(w *WebClient) {
+		if n > 0 {
+			w.retries = n
+		}
+	}
+}
+
+// WithRetryBackoff waits base before the first retry, doubling it for each
+// further one, plus up to jitter at random so clients don't retry in step. A
+// Retry-After on the response replaces the backoff. Retries that would wait
+// past the context's deadline are not made. Zero retries at once.
+func WithRetryBackoff(base time.Duration, jitter time.Duration) WebClientOption {
+	return func(w *WebClient) {
+		w.retryBackoff = max(base, 0)
+		w.retryJitter = max(jitter, 0)
+	}
+}
+
+// WithMaxConcurrentLookups caps how many DNS lookups the client runs at once,
+// queueing the rest. Zero or less leaves lookups unbounded.
+func WithMaxConcurrentLookups(n int) WebClientOption {
+	return func(w *WebClient) {
+		w.maxLookups = n
+	}
+}
+
+// WithAcceptTruncatedBody keeps the partial body, flagged as truncated, when
+// the connection drops mid-body on the last attempt instead of failing the
+// fetch. Earlier attempts are still retried.
+func WithAcceptTruncatedBody() WebClientOption {
+	return func(w *WebClient) {
+		w.acceptTruncated = true
+	}
+}
+
+// WithRequestIDForwarding sends the inbound request ID as X-Request-ID with
+// page fetches. Off by default, as the fetched site is usually a third party.
+func WithRequestIDForwarding() WebClientOption {
+	return func(w *WebClient) {
+		w.forwardRequestID = true
+	}
+}
+
+// WithMaxBodyBytes fails fetches whose body is larger than n bytes with
+// adaptors.ErrResponseTooLarge. Zero or less keeps the default of 10 MiB.
+func WithMaxBodyBytes(n int64) WebClientOption {
+	return func(w *WebClient) {
+		if n > 0 {
+			w.maxBodyBytes = n
+		}
+	}
+}
+
+// WithMaxRedirects fails fetches redirected more than n times with
+// adaptors.ErrTooManyRedirects. Zero or less keeps the default of 10.
+func WithMaxRedirects(n int) WebClientOption {
+	return func(w *WebClient) {
+		if n > 0 {
+			w.maxRedirects = n
+		}
+	}
+}
+
+// WithUserAgent sends ua as the User-Agent of page fetches, e.g. to identify
+// the analyzer to site admins. Empty keeps the default browser User-Agent. A
+// User-Agent in the context's request header still wins.
+func WithUserAgent(ua string) WebClientOption {
+	return func(w *WebClient) {
+		if ua != "" {
+			w.userAgent = ua
+		}
+	}
+}
+
+func NewWebClient(timeout time.Duration, log *log.Logger, opts ...WebClientOption) *WebClient {
+	w := &WebClient{log: log, maxBodyBytes: defaultMaxBodyBytes, maxRedirects: defaultMaxRedirects, userAgent: defaultUserAgent}
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	var transport http.RoundTripper = http.DefaultTransport
+	if w.maxLookups > 0 {
+		w.lookupDialer = newLookupLimitedDialer(w.maxLookups, net.DefaultResolver)
+		transport = newLookupLimitedTransport(w.lookupDialer)
+	}
 	rTripper := promhttp.InstrumentRoundTripperDuration(
 		metrics.HTTPClientRequestDuration,
-		promhttp.InstrumentRoundTripperCounter(metrics.HTTPClientRequestsTotal, http.DefaultTransport))
+		promhttp.InstrumentRoundTripperCounter(metrics.HTTPClientRequestsTotal, transport))
 
-	return &WebClient{
-		client: &http.Client{
-			Timeout:   timeout,
-			Transport: rTripper,
-		},
-		log: log,
+	w.client = &http.Client{
+		Timeout:       timeout,
+		Transport:     rTripper,
+		CheckRedirect: w.checkRedirect,
+	}
+	return w
+}
+
+// LinkCheckTransport returns a transport for link and image checks that
+// shares the client's DNS lookup limit, so page fetches and probes together
+// stay within WithMaxConcurrentLookups. Without a limit it is
+// http.DefaultTransport.
+func (w *WebClient) LinkCheckTransport() http.RoundTripper {
+	if w.lookupDialer == nil {
+		return http.DefaultTransport
+	}
+	return newLookupLimitedTransport(w.lookupDialer)
+}
+
+// checkRedirect stops following redirects once the fetch has been redirected
+// maxRedirects times
+func (w *WebClient) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > w.maxRedirects {
+		return errors.Wrap(adaptors.ErrTooManyRedirects, fmt.Sprintf(`stopped after %d redirects`, w.maxRedirects))
 	}
+	return nil
 }
 
-func (w *WebClient) Do(ctx context.Context, url string, method string) ([]byte, int, error) {
+// redirectChain returns the urls that redirected on the way to resp, oldest
+// first. net/http links each redirected request to the response that caused it.
+func redirectChain(resp *http.Response) []string {
+	var chain []string
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		chain = append([]string{req.Response.Request.URL.String()}, chain...)
+	}
+	return chain
+}
+
+func (w *WebClient) Do(ctx context.Context, url string, method string) (*adaptors.WebResponse, error) {
+	ctx, span := tracing.Tracer().Start(ctx, `HTTP `+method, trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String(`url.full`, url)))
+	defer span.End()
+
 	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
 		w.log.WithError(err).Error(`failed to create request`)
-		return nil, 0, errors.Wrap(err, `failed to create request`)
+		return nil, errors.Wrap(err, `failed to create request`)
 	}
 
 	// Set headers to mimic a browser
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/91.0.4472.124 Safari/537.36")
+	req.Header.Set("User-Agent", w.userAgent)
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.5")
+	if reqID, ok := requestid.FromContext(ctx); ok && w.forwardRequestID {
+		req.Header.Set("X-Request-ID", reqID)
+	}
+	for key, values := range adaptors.RequestHeaderFromContext(ctx) {
+		key = http.CanonicalHeaderKey(key)
+		if key == "Host" {
+			// net/http ignores Host in the header map
+			if len(values) > 0 {
+				req.Host = values[0]
+			}
+			continue
+		}
+		req.Header[key] = values
+	}
+
+	sleep := w.sleep
+	if sleep == nil {
+		sleep = sleepContext
+	}
+	retries := 0
+	for {
+		resp, retryReason, err := w.attempt(req)
+		var wait time.Duration
+		retry := retryReason != "" && retries < w.retries && ctx.Err() == nil
+		if retry {
+			wait = w.retryWait(retries+1, resp)
+			retry = wait <= maxRetryWait && fitsDeadline(ctx, wait)
+		}
+		if !retry {
+			if err != nil && resp != nil && resp.Truncated && w.acceptTruncated {
+				w.log.WithError(err).Warnf(`keeping truncated body of %s, %d bytes read`, url, len(resp.Body))
+				span.SetAttributes(attribute.Bool(`http.response.truncated`, true))
+				err = nil
+			}
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, `request failed`)
+				return nil, err
+			}
+			resp.Retries = retries
+			span.SetAttributes(
+				attribute.Int(`http.response.status_code`, resp.StatusCode),
+				attribute.Int(`http.request.resend_count`, retries),
+			)
+			return resp, nil
+		}
+
+		retries++
+		metrics.HTTPClientRetriesTotal.WithLabelValues(retryReason).Inc()
+		w.log.WithError(err).Warnf(`retrying %s after %s in %v, attempt %d of %d`, url, retryReason, wait, retries, w.retries)
+		if err := sleep(ctx, wait); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, `request failed`)
+			return nil, errors.Wrap(err, `gave up waiting to retry`)
+		}
+	}
+}
+
+// retryWait is how long to wait before the given retry, counting from 1: the
+// response's Retry-After when it has one, the backoff otherwise
+func (w *WebClient) retryWait(retry int, resp *adaptors.WebResponse) time.Duration {
+	if resp != nil {
+		if wait, ok := parseRetryAfter(resp.Header.Get(`Retry-After`), time.Now()); ok {
+			return wait
+		}
+	}
+	wait := w.retryBackoff << min(retry-1, 16)
+	if w.retryJitter > 0 {
+		wait += rand.N(w.retryJitter)
+	}
+	return min(wait, maxRetryWait)
+}
+
+// parseRetryAfter reads a Retry-After value, either delay seconds or an HTTP
+// date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(at.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// fitsDeadline reports whether waiting d still leaves ctx time before its
+// deadline
+func fitsDeadline(ctx context.Context, d time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return !ok || time.Until(deadline) > d
+}
+
+// sleepContext waits d, returning early with ctx's error when it is done
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// attempt performs a single request. retryReason is set when the outcome is
+// worth retrying and is used as the retry metric label.
+func (w *WebClient) attempt(req *http.Request) (resp *adaptors.WebResponse, retryReason string, err error) {
+	// Time to first byte, measured from when the request was issued, and the
+	// address dialed; after a redirect it is the last hop's
+	var ttfb time.Duration
+	var remoteAddr string
+	start := time.Now()
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			remoteAddr = info.Conn.RemoteAddr().String()
+		},
+		GotFirstResponseByte: func() {
+			ttfb = time.Since(start)
+		},
+	}))
 
-	resp, err := w.client.Do(req)
+	httpResp, err := w.client.Do(req)
 	if err != nil {
+		err = adaptors.ClassifyTransportError(err)
+		switch {
+		case errors.Is(err, adaptors.ErrHostNotFound):
+			// retrying won't make the name resolve
+			w.log.WithError(err).Error(`host not found`)
+			return nil, ``, errors.Wrap(err, `host not found`)
+		case errors.Is(err, adaptors.ErrConnectionRefused):
+			w.log.WithError(err).Error(`connection refused`)
+			return nil, `connection_refused`, errors.Wrap(err, `connection refused`)
+		case errors.Is(err, adaptors.ErrTooManyRedirects):
+			// a redirect loop won't end on a retry
+			w.log.WithError(err).Error(`too many redirects`)
+			return nil, ``, err
+		}
 		w.log.WithError(err).Error(`url is invalid`)
-		return nil, 0, errors.Wrap(err, `url is invalid`)
+		return nil, `transport_error`, errors.Wrap(err, `url is invalid`)
 	}
-	defer resp.Body.Close()
+	defer httpResp.Body.Close()
 
-	bodyByte, err := io.ReadAll(resp.Body)
+	var body io.Reader = httpResp.Body
+	if w.maxBodyBytes > 0 {
+		// one byte over the limit is enough to tell it was exceeded
+		body = io.LimitReader(httpResp.Body, w.maxBodyBytes+1)
+	}
+	bodyByte, err := io.ReadAll(body)
+	if err == nil && w.maxBodyBytes > 0 && int64(len(bodyByte)) > w.maxBodyBytes {
+		// the same url will be just as large on a retry
+		w.log.Errorf(`response body of %s is over the %d byte limit`, req.URL, w.maxBodyBytes)
+		return nil, ``, errors.Wrap(adaptors.ErrResponseTooLarge, fmt.Sprintf(`body is over %d bytes`, w.maxBodyBytes))
+	}
 	if err != nil {
 		w.log.Errorf(`failed to read response body. error: %v`, err)
-		return nil, 0, errors.Wrap(err, `failed to read response body`)
+		err = errors.Wrap(err, `failed to read response body`)
+		if len(bodyByte) == 0 {
+			return nil, `body_read_error`, err
+		}
+		// the connection dropped mid-body, e.g. an HTTP/2 GOAWAY or a reset;
+		// hand back what was read in case the caller keeps partial bodies
+		resp = newWebResponse(httpResp, bodyByte, ttfb, remoteAddr)
+		resp.Truncated = true
+		return resp, `body_read_error`, err
 	}
 
-	return bodyByte, resp.StatusCode, nil
+	switch httpResp.StatusCode {
+	case http.StatusTooManyRequests:
+		retryReason = `rate_limited`
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		retryReason = `server_error`
+	}
+
+	return newWebResponse(httpResp, bodyByte, ttfb, remoteAddr), retryReason, nil
+}
+
+// newWebResponse builds the WebResponse for httpResp with the body that was
+// read from it and what was traced of its request
+func newWebResponse(httpResp *http.Response, body []byte, ttfb time.Duration, remoteAddr string) *adaptors.WebResponse {
+	resp := &adaptors.WebResponse{
+		Body:       body,
+		StatusCode: httpResp.StatusCode,
+		Header:     httpResp.Header,
+		Proto:      httpResp.Proto,
+		TTFB:       ttfb,
+		RemoteAddr: remoteAddr,
+	}
+	if httpResp.Request != nil {
+		resp.FinalURL = httpResp.Request.URL.String()
+		resp.RedirectChain = redirectChain(httpResp)
+	}
+	if httpResp.TLS != nil && len(httpResp.TLS.PeerCertificates) > 0 {
+		resp.PeerCertificate = httpResp.TLS.PeerCertificates[0]
+	}
+	return resp
 }
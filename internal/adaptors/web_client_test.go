@@ -4,11 +4,21 @@ import (
 	"context"
 	"errors"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
+	"web_page_analyzer/internal/domain/adaptors"
+	"web_page_analyzer/internal/pkg/metrics"
+	"web_page_analyzer/internal/pkg/requestid"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -101,7 +111,7 @@ func TestWebClient_Do(t *testing.T) {
 		tc := tc // capture loop variable
 		t.Run(tc.name, func(t *testing.T) {
 			wc := tc.setup()
-			body, code, err := wc.Do(ctx, tc.url, http.MethodGet)
+			resp, err := wc.Do(ctx, tc.url, http.MethodGet)
 
 			if tc.wantErr {
 				if err == nil {
@@ -113,11 +123,11 @@ func TestWebClient_Do(t *testing.T) {
 				t.Fatalf("unexpected error: %v", err)
 			}
 
-			if got := string(body); got != tc.wantBody {
+			if got := string(resp.Body); got != tc.wantBody {
 				t.Errorf("body = %q; want %q", got, tc.wantBody)
 			}
-			if code != tc.wantCode {
-				t.Errorf("code = %d; want %d", code, tc.wantCode)
+			if resp.StatusCode != tc.wantCode {
+				t.Errorf("code = %d; want %d", resp.StatusCode, tc.wantCode)
 			}
 		})
 	}
@@ -132,3 +142,701 @@ func (e errReadCloser) Read(p []byte) (int, error) {
 func (e errReadCloser) Close() error {
 	return nil
 }
+
+func TestWebClient_DoReportsTTFB(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	}))
+	defer srv.Close()
+
+	wc := NewWebClient(1*time.Second, log.New())
+	resp, err := wc.Do(context.Background(), srv.URL, http.MethodGet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.TTFB < delay {
+		t.Errorf("ttfb = %v; want at least %v", resp.TTFB, delay)
+	}
+	if resp.TTFB > time.Second {
+		t.Errorf("ttfb = %v; want below the client timeout", resp.TTFB)
+	}
+}
+
+func TestWebClient_DoReportsRemoteAddr(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	defer srv.Close()
+
+	wc := NewWebClient(1*time.Second, log.New())
+	resp, err := wc.Do(context.Background(), srv.URL, http.MethodGet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.RemoteAddr != srv.Listener.Addr().String() {
+		t.Errorf("RemoteAddr = %q; want %q", resp.RemoteAddr, srv.Listener.Addr().String())
+	}
+}
+
+func TestWebClient_DoReportsProtocol(t *testing.T) {
+	for _, proto := range []string{"HTTP/1.1", "HTTP/2.0"} {
+		t.Run(proto, func(t *testing.T) {
+			wc := &WebClient{
+				client: &http.Client{
+					Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+						return &http.Response{
+							StatusCode: 200,
+							Proto:      proto,
+							Body:       io.NopCloser(strings.NewReader("OK")),
+							Header:     make(http.Header),
+						}, nil
+					}),
+				},
+				log: log.New(),
+			}
+
+			resp, err := wc.Do(context.Background(), "http://example.com", http.MethodGet)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.Proto != proto {
+				t.Errorf("proto = %q; want %q", resp.Proto, proto)
+			}
+		})
+	}
+}
+
+func TestWebClient_DoRetriesOnce(t *testing.T) {
+	retriesBefore := testutil.ToFloat64(metrics.HTTPClientRetriesTotal.WithLabelValues("transport_error"))
+
+	attempts := 0
+	wc := &WebClient{
+		client: &http.Client{
+			Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				if attempts == 1 {
+					return nil, errors.New("connection reset by peer")
+				}
+				return &http.Response{
+					StatusCode: 200,
+					Body:       io.NopCloser(strings.NewReader("OK")),
+					Header:     make(http.Header),
+				}, nil
+			}),
+		},
+		log:     log.New(),
+		retries: 2,
+	}
+
+	resp, err := wc.Do(context.Background(), "http://example.com", http.MethodGet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d; want 2", attempts)
+	}
+	if resp.Retries != 1 {
+		t.Errorf("Retries = %d; want 1", resp.Retries)
+	}
+	if got := testutil.ToFloat64(metrics.HTTPClientRetriesTotal.WithLabelValues("transport_error")) - retriesBefore; got != 1 {
+		t.Errorf("retry counter moved by %v; want 1", got)
+	}
+}
+
+func TestWebClient_DoGivesUpAfterRetries(t *testing.T) {
+	attempts := 0
+	wc := &WebClient{
+		client: &http.Client{
+			Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				return &http.Response{
+					StatusCode: http.StatusServiceUnavailable,
+					Body:       io.NopCloser(strings.NewReader("busy")),
+					Header:     make(http.Header),
+				}, nil
+			}),
+		},
+		log:     log.New(),
+		retries: 1,
+	}
+
+	resp, err := wc.Do(context.Background(), "http://example.com", http.MethodGet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d; want 2", attempts)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("StatusCode = %d; want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+}
+
+func TestWebClient_DoReportsPeerCertificate(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("OK"))
+	}))
+	defer srv.Close()
+
+	wc := &WebClient{client: srv.Client(), log: log.New()}
+
+	resp, err := wc.Do(context.Background(), srv.URL, http.MethodGet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.PeerCertificate == nil {
+		t.Fatal("PeerCertificate is nil for an https response")
+	}
+	want := srv.Certificate()
+	if resp.PeerCertificate.Subject.String() != want.Subject.String() {
+		t.Errorf("subject = %q; want %q", resp.PeerCertificate.Subject, want.Subject)
+	}
+	if !resp.PeerCertificate.NotAfter.Equal(want.NotAfter) {
+		t.Errorf("NotAfter = %v; want %v", resp.PeerCertificate.NotAfter, want.NotAfter)
+	}
+}
+
+func TestWebClient_DoReportsPeerCertificateWhenTruncated(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// promise more than is sent, then drop the connection
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("<html><bo"))
+		w.(http.Flusher).Flush()
+		panic(http.ErrAbortHandler)
+	}))
+	defer srv.Close()
+
+	wc := &WebClient{client: srv.Client(), log: log.New(), acceptTruncated: true}
+
+	resp, err := wc.Do(context.Background(), srv.URL, http.MethodGet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.Truncated {
+		t.Fatal("Truncated = false; want true")
+	}
+	if resp.PeerCertificate == nil {
+		t.Fatal("PeerCertificate is nil for a truncated https response")
+	}
+	if resp.PeerCertificate.Subject.String() != srv.Certificate().Subject.String() {
+		t.Errorf("subject = %q; want %q", resp.PeerCertificate.Subject, srv.Certificate().Subject)
+	}
+}
+
+// truncatedBody returns its content and then fails as if the connection
+// dropped mid-transfer
+type truncatedBody struct {
+	r *strings.Reader
+}
+
+func (b *truncatedBody) Read(p []byte) (int, error) {
+	if b.r.Len() == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return b.r.Read(p)
+}
+
+func (b *truncatedBody) Close() error { return nil }
+
+func TestWebClient_DoTruncatedBody(t *testing.T) {
+	cases := []struct {
+		name          string
+		retries       int
+		accept        bool
+		wantErr       bool
+		wantBody      string
+		wantTruncated bool
+		wantAttempts  int
+	}{
+		{name: "fails by default", wantErr: true, wantAttempts: 1},
+		{name: "keeps partial body when accepted", accept: true, wantBody: "<html><bo", wantTruncated: true, wantAttempts: 1},
+		{name: "retries before keeping partial body", retries: 1, accept: true, wantBody: "<html><body></body></html>", wantAttempts: 2},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			opts := []WebClientOption{WithRetries(tc.retries)}
+			if tc.accept {
+				opts = append(opts, WithAcceptTruncatedBody())
+			}
+			wc := NewWebClient(time.Second, log.New(), opts...)
+			wc.client.Transport = RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				body := io.ReadCloser(&truncatedBody{r: strings.NewReader("<html><bo")})
+				if attempts > 1 {
+					body = io.NopCloser(strings.NewReader("<html><body></body></html>"))
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: body, Header: make(http.Header)}, nil
+			})
+
+			resp, err := wc.Do(context.Background(), "http://example.com", http.MethodGet)
+			if attempts != tc.wantAttempts {
+				t.Errorf("attempts = %d; want %d", attempts, tc.wantAttempts)
+			}
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(resp.Body) != tc.wantBody {
+				t.Errorf("Body = %q; want %q", resp.Body, tc.wantBody)
+			}
+			if resp.Truncated != tc.wantTruncated {
+				t.Errorf("Truncated = %v; want %v", resp.Truncated, tc.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestWebClient_DoSendsContextHeaders(t *testing.T) {
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+	}))
+	defer srv.Close()
+
+	ctx := adaptors.ContextWithRequestHeader(context.Background(), http.Header{"authorization": {"Bearer s3cr3t"}})
+	if _, err := NewWebClient(time.Second, log.New()).Do(ctx, srv.URL, http.MethodGet); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotAuth != "Bearer s3cr3t" {
+		t.Errorf("Authorization = %q; want %q", gotAuth, "Bearer s3cr3t")
+	}
+}
+
+func TestWebClient_DoSendsRequestID(t *testing.T) {
+	var gotIDs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotIDs = append(gotIDs, r.Header.Get("X-Request-ID"))
+	}))
+	defer srv.Close()
+
+	ctx := requestid.NewContext(context.Background(), "req-42")
+	wc := NewWebClient(time.Second, log.New(), WithRequestIDForwarding())
+	if _, err := wc.Do(ctx, srv.URL, http.MethodGet); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := wc.Do(context.Background(), srv.URL, http.MethodGet); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// without the option the id stays with the service
+	if _, err := NewWebClient(time.Second, log.New()).Do(ctx, srv.URL, http.MethodGet); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(gotIDs) != 3 || gotIDs[0] != "req-42" || gotIDs[1] != "" || gotIDs[2] != "" {
+		t.Errorf("X-Request-ID per request = %q; want [\"req-42\" \"\" \"\"]", gotIDs)
+	}
+}
+
+func TestWebClient_DoSendsUserAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []WebClientOption
+		ctx  context.Context
+		want string
+	}{
+		{name: "default", want: defaultUserAgent},
+		{name: "empty keeps default", opts: []WebClientOption{WithUserAgent("")}, want: defaultUserAgent},
+		{name: "configured", opts: []WebClientOption{WithUserAgent("analyzer-bot/1.0")}, want: "analyzer-bot/1.0"},
+		{name: "per request", opts: []WebClientOption{WithUserAgent("analyzer-bot/1.0")},
+			ctx: adaptors.ContextWithRequestHeader(context.Background(), http.Header{"User-Agent": {"preview/2.0"}}), want: "preview/2.0"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			wc := NewWebClient(time.Second, log.New(), tc.opts...)
+			wc.client.Transport = RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				got = req.Header.Get("User-Agent")
+				return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Header: make(http.Header)}, nil
+			})
+
+			ctx := tc.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			if _, err := wc.Do(ctx, "http://example.com/", http.MethodGet); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("User-Agent = %q; want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWebClient_DoOverridesHost(t *testing.T) {
+	var gotHost, gotURLHost string
+	wc := NewWebClient(time.Second, log.New())
+	wc.client.Transport = RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		gotHost, gotURLHost = req.Host, req.URL.Host
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Header: make(http.Header)}, nil
+	})
+
+	ctx := adaptors.ContextWithRequestHeader(context.Background(), http.Header{"Host": {"www.example.com"}})
+	if _, err := wc.Do(ctx, "http://203.0.113.7:8080/", http.MethodGet); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gotHost != "www.example.com" {
+		t.Errorf("Host = %q; want %q", gotHost, "www.example.com")
+	}
+	if gotURLHost != "203.0.113.7:8080" {
+		t.Errorf("URL host = %q; want the original target", gotURLHost)
+	}
+}
+
+func TestWebClient_DoClassifiesTransportErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantErr      error
+		wantAttempts int
+	}{
+		{
+			name:         "host not found is not retried",
+			err:          &net.DNSError{Err: "no such host", Name: "missing.example", IsNotFound: true},
+			wantErr:      adaptors.ErrHostNotFound,
+			wantAttempts: 1,
+		},
+		{
+			name: "connection refused is retried",
+			err: &net.OpError{Op: "dial", Net: "tcp",
+				Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}},
+			wantErr:      adaptors.ErrConnectionRefused,
+			wantAttempts: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retriesBefore := testutil.ToFloat64(metrics.HTTPClientRetriesTotal.WithLabelValues("connection_refused"))
+			attempts := 0
+			wc := &WebClient{
+				client: &http.Client{
+					Transport: RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+						attempts++
+						return nil, tt.err
+					}),
+				},
+				log:     log.New(),
+				retries: 2,
+			}
+
+			_, err := wc.Do(context.Background(), "http://missing.example", http.MethodGet)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v; want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d; want %d", attempts, tt.wantAttempts)
+			}
+			wantRetries := float64(tt.wantAttempts - 1)
+			if got := testutil.ToFloat64(metrics.HTTPClientRetriesTotal.WithLabelValues("connection_refused")) - retriesBefore; got != wantRetries {
+				t.Errorf("connection_refused retries moved by %v; want %v", got, wantRetries)
+			}
+		})
+	}
+}
+
+func TestWebClient_DoRejectsOversizedBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantErr  error
+		wantBody string
+	}{
+		{name: "at the limit", body: strings.Repeat("a", 16), wantBody: strings.Repeat("a", 16)},
+		{name: "over the limit", body: strings.Repeat("a", 17), wantErr: adaptors.ErrResponseTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			wc := NewWebClient(time.Second, log.New(), WithMaxBodyBytes(16), WithRetries(2))
+			wc.client.Transport = RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(tt.body)),
+					Header:     make(http.Header),
+				}, nil
+			})
+
+			resp, err := wc.Do(context.Background(), "http://example.com", http.MethodGet)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v; want %v", err, tt.wantErr)
+				}
+				if resp != nil {
+					t.Errorf("resp = %+v; want nil", resp)
+				}
+				if attempts != 1 {
+					t.Errorf("attempts = %d; want 1, an oversized body is not retried", attempts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(resp.Body) != tt.wantBody {
+				t.Errorf("body = %q; want %q", resp.Body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestNewWebClientDefaultsMaxBodyBytes(t *testing.T) {
+	if got := NewWebClient(time.Second, log.New()).maxBodyBytes; got != defaultMaxBodyBytes {
+		t.Errorf("maxBodyBytes = %d; want %d", got, defaultMaxBodyBytes)
+	}
+	if got := NewWebClient(time.Second, log.New(), WithMaxBodyBytes(0)).maxBodyBytes; got != defaultMaxBodyBytes {
+		t.Errorf("maxBodyBytes with zero = %d; want the default %d", got, defaultMaxBodyBytes)
+	}
+}
+
+func TestWebClient_DoFollowsRedirects(t *testing.T) {
+	// example.com/a -> /b -> /c, each hop a 302
+	redirectingTransport := func(attempts *int) RoundTripFunc {
+		return func(req *http.Request) (*http.Response, error) {
+			*attempts++
+			next := map[string]string{"/a": "/b", "/b": "/c", "/loop": "/loop"}[req.URL.Path]
+			if next == "" {
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("OK")), Header: make(http.Header), Request: req}, nil
+			}
+			return &http.Response{StatusCode: http.StatusFound, Body: http.NoBody,
+				Header: http.Header{"Location": {next}}, Request: req}, nil
+		}
+	}
+
+	t.Run("two redirects", func(t *testing.T) {
+		attempts := 0
+		wc := NewWebClient(time.Second, log.New())
+		wc.client.Transport = redirectingTransport(&attempts)
+
+		resp, err := wc.Do(context.Background(), "http://example.com/a", http.MethodGet)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.FinalURL != "http://example.com/c" {
+			t.Errorf("FinalURL = %q; want %q", resp.FinalURL, "http://example.com/c")
+		}
+		wantChain := []string{"http://example.com/a", "http://example.com/b"}
+		if strings.Join(resp.RedirectChain, " ") != strings.Join(wantChain, " ") {
+			t.Errorf("RedirectChain = %v; want %v", resp.RedirectChain, wantChain)
+		}
+		if attempts != 3 {
+			t.Errorf("requests = %d; want 3", attempts)
+		}
+	})
+
+	t.Run("capped", func(t *testing.T) {
+		attempts := 0
+		wc := NewWebClient(time.Second, log.New(), WithMaxRedirects(3), WithRetries(2))
+		wc.client.Transport = redirectingTransport(&attempts)
+
+		_, err := wc.Do(context.Background(), "http://example.com/loop", http.MethodGet)
+		if !errors.Is(err, adaptors.ErrTooManyRedirects) {
+			t.Fatalf("err = %v; want %v", err, adaptors.ErrTooManyRedirects)
+		}
+		// the original request and three redirects, with no retry
+		if attempts != 4 {
+			t.Errorf("requests = %d; want 4", attempts)
+		}
+	})
+
+	t.Run("not redirected", func(t *testing.T) {
+		attempts := 0
+		wc := NewWebClient(time.Second, log.New())
+		wc.client.Transport = redirectingTransport(&attempts)
+
+		resp, err := wc.Do(context.Background(), "http://example.com/c", http.MethodGet)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if resp.FinalURL != "http://example.com/c" || len(resp.RedirectChain) != 0 {
+			t.Errorf("FinalURL = %q, RedirectChain = %v; want the requested url and no chain", resp.FinalURL, resp.RedirectChain)
+		}
+	})
+
+	t.Run("truncated after a redirect", func(t *testing.T) {
+		wc := NewWebClient(time.Second, log.New(), WithAcceptTruncatedBody())
+		wc.client.Transport = RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+			if req.URL.Host == "example.com" {
+				return &http.Response{StatusCode: http.StatusFound, Body: http.NoBody,
+					Header: http.Header{"Location": {"http://www.example.org/page"}}, Request: req}, nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Body: &truncatedBody{r: strings.NewReader("<html><bo")},
+				Header: make(http.Header), Request: req}, nil
+		})
+
+		resp, err := wc.Do(context.Background(), "http://example.com/a", http.MethodGet)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !resp.Truncated {
+			t.Error("Truncated = false; want true")
+		}
+		if resp.FinalURL != "http://www.example.org/page" {
+			t.Errorf("FinalURL = %q; want %q", resp.FinalURL, "http://www.example.org/page")
+		}
+		wantChain := []string{"http://example.com/a"}
+		if strings.Join(resp.RedirectChain, " ") != strings.Join(wantChain, " ") {
+			t.Errorf("RedirectChain = %v; want %v", resp.RedirectChain, wantChain)
+		}
+	})
+}
+
+func TestWebClient_DoRetriesWithBackoff(t *testing.T) {
+	cases := []struct {
+		name         string
+		fail         func(attempt int) (*http.Response, error)
+		wantStatus   int
+		wantAttempts int
+		wantWaits    []time.Duration
+	}{
+		{
+			name: "transport errors",
+			fail: func(int) (*http.Response, error) {
+				return nil, errors.New("connection reset by peer")
+			},
+			wantStatus:   http.StatusOK,
+			wantAttempts: 3,
+			wantWaits:    []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		{
+			name: "rate limited with Retry-After",
+			fail: func(int) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusTooManyRequests, Body: http.NoBody,
+					Header: http.Header{"Retry-After": {"2"}}}, nil
+			},
+			wantStatus:   http.StatusOK,
+			wantAttempts: 3,
+			wantWaits:    []time.Duration{2 * time.Second, 2 * time.Second},
+		},
+		{
+			name: "service unavailable",
+			fail: func(int) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody, Header: make(http.Header)}, nil
+			},
+			wantStatus:   http.StatusOK,
+			wantAttempts: 3,
+			wantWaits:    []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+		},
+		{
+			name: "not found is not retried",
+			fail: func(int) (*http.Response, error) {
+				return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody, Header: make(http.Header)}, nil
+			},
+			wantStatus:   http.StatusNotFound,
+			wantAttempts: 1,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			var waits []time.Duration
+			wc := NewWebClient(time.Second, log.New(), WithRetries(3), WithRetryBackoff(100*time.Millisecond, 0))
+			wc.sleep = func(ctx context.Context, d time.Duration) error {
+				waits = append(waits, d)
+				return nil
+			}
+			wc.client.Transport = RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				if attempts <= 2 {
+					return tc.fail(attempts)
+				}
+				return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("<html></html>")), Header: make(http.Header)}, nil
+			})
+
+			resp, err := wc.Do(context.Background(), "http://example.com/", http.MethodGet)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if resp.StatusCode != tc.wantStatus {
+				t.Errorf("StatusCode = %d; want %d", resp.StatusCode, tc.wantStatus)
+			}
+			if attempts != tc.wantAttempts {
+				t.Errorf("attempts = %d; want %d", attempts, tc.wantAttempts)
+			}
+			if resp.Retries != tc.wantAttempts-1 {
+				t.Errorf("Retries = %d; want %d", resp.Retries, tc.wantAttempts-1)
+			}
+			if !slices.Equal(waits, tc.wantWaits) {
+				t.Errorf("waits = %v; want %v", waits, tc.wantWaits)
+			}
+		})
+	}
+}
+
+func TestWebClient_DoDoesNotRetryPastDeadline(t *testing.T) {
+	attempts := 0
+	wc := NewWebClient(time.Second, log.New(), WithRetries(3))
+	wc.sleep = func(ctx context.Context, d time.Duration) error {
+		t.Fatalf("slept %v although the wait ends after the deadline", d)
+		return nil
+	}
+	wc.client.Transport = RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		return &http.Response{StatusCode: http.StatusServiceUnavailable, Body: http.NoBody,
+			Header: http.Header{"Retry-After": {"5"}}}, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	resp, err := wc.Do(ctx, "http://example.com/", http.MethodGet)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || attempts != 1 {
+		t.Errorf("StatusCode = %d after %d attempts; want 503 after 1", resp.StatusCode, attempts)
+	}
+}
+
+func TestWebClient_DoStopsWaitingWhenCancelled(t *testing.T) {
+	wc := NewWebClient(time.Second, log.New(), WithRetries(1), WithRetryBackoff(time.Minute, 0))
+	wc.client.Transport = RoundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusBadGateway, Body: http.NoBody, Header: make(http.Header)}, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	_, err := wc.Do(ctx, "http://example.com/", http.MethodGet)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v; want context.Canceled", err)
+	}
+	if took := time.Since(start); took > 5*time.Second {
+		t.Errorf("Do took %v; want it to stop waiting once cancelled", took)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "", wantOK: false},
+		{value: "3", want: 3 * time.Second, wantOK: true},
+		{value: "Mon, 01 Jan 2024 12:00:10 GMT", want: 10 * time.Second, wantOK: true},
+		{value: "Mon, 01 Jan 2024 11:00:00 GMT", want: 0, wantOK: true},
+		{value: "soon", wantOK: false},
+		{value: "-1", wantOK: false},
+	}
+	for _, tc := range cases {
+		got, ok := parseRetryAfter(tc.value, now)
+		if got != tc.want || ok != tc.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tc.value, got, ok, tc.want, tc.wantOK)
+		}
+	}
+}
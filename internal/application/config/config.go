@@ -3,15 +3,74 @@ package config
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type AppConfig struct {
-	LogLevel    string
-	DebugMode   bool
-	MetricsHost string
+	LogLevel               string
+	RequestLogFormat       string
+	DebugMode              bool
+	MetricsHost            string
+	LinkStripQueryStrings  bool
+	LinkSeparateSamePage   bool
+	LinkCheckRedirects     bool
+	LinkCheckRobotsTxt     bool
+	RedactQueryParams      []string
+	LinkCheckConcurrency   int
+	LinkCheckTimeout       time.Duration
+	LinkIgnoredQueryParams []string
+	AnalyzerConcurrency    int
+	MaxDOMDepth            int
+	MaxMetaTags            int
+	ThinSectionWords       int
+	AnchorTextMaxChars     int
+	AnchorTextMaxLinks     int
+	MaxBodyBytes           int
+	MaxDOMNodes            int
+	AnalyzeCacheControl    string
+	AnalyzeETag            bool
+	AnalyzeBearerTokens    bool
+	ProbeUserAgents        []string
+	FetchRetries           int
+	FetchMaxRedirects      int
+	FetchUserAgent         string
+	FetchRetryBackoff      time.Duration
+	FetchRetryJitter       time.Duration
+	AcceptTruncatedBody    bool
+	ForwardRequestID       bool
+	MaxConcurrentLookups   int
+	MaxConcurrentBatches   int
+	BatchConcurrency       int
+	BatchMaxURLs           int
+	BadgeCacheTTL          time.Duration
+	RateLimitRPS           float64
+	RateLimitBurst         int
+	RateLimitClients       int
+	// MaxConcurrentAnalyses caps analyze and badge requests in flight, and
+	// MaxClientAnalyses how many of those one client may hold
+	MaxConcurrentAnalyses int
+	MaxClientAnalyses     int
+	BatchQueueTimeout     time.Duration
+	OTelExporterEndpoint  string
+	StatsDAddress         string
+	StatsDPrefix          string
+	TLSExpiryWarning      time.Duration
+	LinkCheckSkipHosts    []string
+	ContentFingerprint    bool
+	// BasePath prefixes every route, e.g. "/web-analyzer"
+	BasePath string
+	// HealthBasePath prefixes /ready and /healthz. It follows BasePath unless
+	// APP_HEALTH_BASE_PATH is set, which may be empty to keep them at the root.
+	HealthBasePath string
+	// WarmupEnabled runs a self-test analysis of WarmupURL on startup, giving
+	// up after WarmupTimeout
+	WarmupEnabled bool
+	WarmupURL     string
+	WarmupTimeout time.Duration
 }
 
 func NewAppConfig() (*AppConfig, error) {
@@ -22,8 +81,197 @@ func NewAppConfig() (*AppConfig, error) {
 
 	cfg := AppConfig{}
 	cfg.LogLevel = os.Getenv("APP_LOG_LEVEL")
+	cfg.RequestLogFormat = os.Getenv("APP_REQUEST_LOG_FORMAT")
 	cfg.DebugMode = os.Getenv("APP_ENABLE_DEBUG") == "true"
 	cfg.MetricsHost = os.Getenv("HTTP_APP_METRICS_HOST")
+	cfg.LinkStripQueryStrings = os.Getenv("APP_LINK_STRIP_QUERY_STRINGS") == "true"
+	cfg.LinkSeparateSamePage = os.Getenv("APP_LINK_SEPARATE_SAME_PAGE") == "true"
+	cfg.LinkCheckRedirects = os.Getenv("APP_LINK_CHECK_REDIRECTS") == "true"
+	cfg.LinkCheckRobotsTxt = os.Getenv("APP_LINK_CHECK_RESPECT_ROBOTS") == "true"
+	cfg.LinkIgnoredQueryParams = splitList(os.Getenv("APP_LINK_IGNORED_QUERY_PARAMS"))
+	cfg.LinkCheckSkipHosts = splitList(os.Getenv("APP_LINK_CHECK_SKIP_HOSTS"))
+	cfg.RedactQueryParams = splitList(os.Getenv("APP_REDACT_QUERY_PARAMS"))
+	cfg.AnalyzeCacheControl = os.Getenv("APP_ANALYZE_CACHE_CONTROL")
+	cfg.AnalyzeETag = os.Getenv("APP_ANALYZE_ETAG") == "true"
+	cfg.AnalyzeBearerTokens = os.Getenv("APP_ANALYZE_BEARER_TOKENS") == "true"
+	cfg.ContentFingerprint = os.Getenv("APP_CONTENT_FINGERPRINT") == "true"
+	cfg.AcceptTruncatedBody = os.Getenv("APP_ACCEPT_TRUNCATED_BODY") == "true"
+	cfg.ForwardRequestID = os.Getenv("APP_FORWARD_REQUEST_ID") == "true"
+	cfg.ProbeUserAgents = splitList(os.Getenv("APP_PROBE_USER_AGENTS"))
+	cfg.FetchUserAgent = os.Getenv("APP_FETCH_USER_AGENT")
+	cfg.OTelExporterEndpoint = os.Getenv("APP_OTEL_EXPORTER_ENDPOINT")
+	cfg.StatsDAddress = os.Getenv("APP_STATSD_ADDRESS")
+	cfg.StatsDPrefix = os.Getenv("APP_STATSD_PREFIX")
+	cfg.WarmupEnabled = os.Getenv("APP_WARMUP_ENABLED") == "true"
+	cfg.WarmupURL = os.Getenv("APP_WARMUP_URL")
+	cfg.BasePath = normalizeBasePath(os.Getenv("APP_BASE_PATH"))
+	cfg.HealthBasePath = cfg.BasePath
+	if value, ok := os.LookupEnv("APP_HEALTH_BASE_PATH"); ok {
+		cfg.HealthBasePath = normalizeBasePath(value)
+	}
+
+	cfg.AnalyzerConcurrency, err = envInt("APP_ANALYZER_CONCURRENCY", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.MaxDOMDepth, err = envInt("APP_MAX_DOM_DEPTH", 2000)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.MaxMetaTags, err = envInt("APP_MAX_META_TAGS", 200)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.ThinSectionWords, err = envInt("APP_THIN_SECTION_WORDS", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.AnchorTextMaxChars, err = envInt("APP_ANCHOR_TEXT_MAX_CHARS", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.AnchorTextMaxLinks, err = envInt("APP_ANCHOR_TEXT_MAX_LINKS", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.LinkCheckConcurrency, err = envInt("APP_LINK_CHECK_CONCURRENCY", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if value := os.Getenv("APP_LINK_CHECK_TIMEOUT_DURATION"); value != "" {
+		cfg.LinkCheckTimeout, err = time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf(`APP_LINK_CHECK_TIMEOUT_DURATION: invalid duration: %w`, err)
+		}
+	}
+
+	// one limit for every layer: page fetches stop reading past it and the
+	// analyzer rejects fetched or supplied HTML over it, so the same size
+	// always fails the same way
+	cfg.MaxBodyBytes, err = envInt("APP_MAX_BODY_BYTES", 0)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = 10 << 20
+	}
+
+	cfg.MaxDOMNodes, err = envInt("APP_MAX_DOM_NODES", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	// the defaults match config.env, so a deployment without it retries the same way
+	cfg.FetchRetries, err = envInt("APP_FETCH_RETRIES", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.FetchRetryBackoff = 200 * time.Millisecond
+	if value := os.Getenv("APP_FETCH_RETRY_BACKOFF_DURATION"); value != "" {
+		cfg.FetchRetryBackoff, err = time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf(`APP_FETCH_RETRY_BACKOFF_DURATION: invalid duration: %w`, err)
+		}
+	}
+
+	cfg.FetchRetryJitter = 100 * time.Millisecond
+	if value := os.Getenv("APP_FETCH_RETRY_JITTER_DURATION"); value != "" {
+		cfg.FetchRetryJitter, err = time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf(`APP_FETCH_RETRY_JITTER_DURATION: invalid duration: %w`, err)
+		}
+	}
+
+	cfg.FetchMaxRedirects, err = envInt("APP_FETCH_MAX_REDIRECTS", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.MaxConcurrentLookups, err = envInt("APP_MAX_CONCURRENT_DNS_LOOKUPS", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.MaxConcurrentAnalyses, err = envInt("APP_MAX_CONCURRENT_ANALYSES", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.MaxClientAnalyses, err = envInt("APP_MAX_CONCURRENT_ANALYSES_PER_CLIENT", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if value := os.Getenv("APP_RATE_LIMIT_RPS"); value != "" {
+		cfg.RateLimitRPS, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf(`APP_RATE_LIMIT_RPS: invalid number: %w`, err)
+		}
+	}
+
+	cfg.RateLimitBurst, err = envInt("APP_RATE_LIMIT_BURST", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.RateLimitClients, err = envInt("APP_RATE_LIMIT_MAX_CLIENTS", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.MaxConcurrentBatches, err = envInt("APP_MAX_CONCURRENT_BATCHES", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.BatchConcurrency, err = envInt("APP_BATCH_CONCURRENCY", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.BatchMaxURLs, err = envInt("APP_BATCH_MAX_URLS", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.TLSExpiryWarning = 30 * 24 * time.Hour
+	if value := os.Getenv("APP_TLS_EXPIRY_WARNING_DURATION"); value != "" {
+		cfg.TLSExpiryWarning, err = time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf(`APP_TLS_EXPIRY_WARNING_DURATION: invalid duration: %w`, err)
+		}
+	}
+
+	if value := os.Getenv("APP_BATCH_QUEUE_TIMEOUT_DURATION"); value != "" {
+		cfg.BatchQueueTimeout, err = time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf(`APP_BATCH_QUEUE_TIMEOUT_DURATION: invalid duration: %w`, err)
+		}
+	}
+
+	cfg.BadgeCacheTTL = time.Minute
+	if value := os.Getenv("APP_BADGE_CACHE_TTL_DURATION"); value != "" {
+		cfg.BadgeCacheTTL, err = time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf(`APP_BADGE_CACHE_TTL_DURATION: invalid duration: %w`, err)
+		}
+	}
+
+	cfg.WarmupTimeout = 10 * time.Second
+	if value := os.Getenv("APP_WARMUP_TIMEOUT_DURATION"); value != "" {
+		cfg.WarmupTimeout, err = time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf(`APP_WARMUP_TIMEOUT_DURATION: invalid duration: %w`, err)
+		}
+	}
 
 	err = validate(&cfg)
 	if err != nil {
@@ -43,8 +291,50 @@ func validate(cfg *AppConfig) error {
 		errMsg = append(errMsg, `metrics host is empty`)
 	}
 
+	if cfg.RequestLogFormat != "" && cfg.RequestLogFormat != "json" && cfg.RequestLogFormat != "text" {
+		errMsg = append(errMsg, `request log format must be "json" or "text"`)
+	}
+
+	if cfg.WarmupEnabled && cfg.WarmupURL == "" {
+		errMsg = append(errMsg, `warmup is enabled but the warmup url is empty`)
+	}
+
 	if len(errMsg) != 0 {
 		return fmt.Errorf(`validation failed: %s`, strings.Join(errMsg, "\n"))
 	}
 	return nil
 }
+
+// envInt parses an optional integer env value, returning def when it is unset
+func envInt(name string, def int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf(`%s: invalid integer: %w`, name, err)
+	}
+	return n, nil
+}
+
+// normalizeBasePath returns path with a leading slash and no trailing slash,
+// or "" for the root
+func normalizeBasePath(path string) string {
+	path = strings.Trim(strings.TrimSpace(path), "/")
+	if path == "" {
+		return ""
+	}
+	return "/" + path
+}
+
+// splitList parses a comma separated env value, dropping empty entries
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
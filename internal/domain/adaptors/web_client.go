@@ -2,8 +2,86 @@ package adaptors
 
 import (
 	"context"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"web_page_analyzer/internal/pkg/errors"
 )
 
+// ErrHostNotFound is returned when the host name doesn't resolve. It is
+// permanent, so fetches failing with it are not retried.
+var ErrHostNotFound = errors.New(`host not found`)
+
+// ErrConnectionRefused is returned when the host resolved but refused the
+// connection, which is often transient, e.g. while a server restarts
+var ErrConnectionRefused = errors.New(`connection refused`)
+
+// ErrResponseTooLarge is returned when a response body is larger than the
+// client's maximum body size. The body is not kept, even in part.
+var ErrResponseTooLarge = errors.New(`response too large`)
+
+// ErrTooManyRedirects is returned when a fetch is redirected more times than
+// the client allows. Redirect loops are permanent, so it is not retried.
+var ErrTooManyRedirects = errors.New(`too many redirects`)
+
+type WebResponse struct {
+	Body       []byte
+	StatusCode int
+	Header     http.Header
+	Proto      string
+	TTFB       time.Duration
+	// Retries is how many extra attempts were needed to get this response
+	Retries int
+	// PeerCertificate is the server's leaf certificate for https responses
+	PeerCertificate *x509.Certificate
+	// Truncated is set when the connection dropped mid-body and the partial
+	// body was kept
+	Truncated bool
+	// FinalURL is the url the body was fetched from, after any redirects
+	FinalURL string
+	// RedirectChain lists the urls that redirected, in the order they were
+	// visited, starting with the requested url. It is empty when the fetch
+	// was not redirected.
+	RedirectChain []string
+	// RemoteAddr is the address, host:port, of the server the body was read
+	// from. It is empty when the transport doesn't report connections.
+	RemoteAddr string
+}
+
 type WebClient interface {
-	Do(ctx context.Context, url string, method string) ([]byte, int, error)
+	Do(ctx context.Context, url string, method string) (*WebResponse, error)
+}
+
+// ClassifyTransportError marks err with ErrHostNotFound or
+// ErrConnectionRefused when it is one of those failures and returns it
+// unchanged otherwise
+func ClassifyTransportError(err error) error {
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		return fmt.Errorf(`%w: %w`, ErrHostNotFound, err)
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return fmt.Errorf(`%w: %w`, ErrConnectionRefused, err)
+	}
+	return err
+}
+
+type requestHeaderKey struct{}
+
+// ContextWithRequestHeader returns a copy of ctx whose fetches send header on
+// top of the client's defaults, e.g. an Authorization header. A Host entry
+// overrides the request's Host.
+func ContextWithRequestHeader(ctx context.Context, header http.Header) context.Context {
+	return context.WithValue(ctx, requestHeaderKey{}, header)
+}
+
+// RequestHeaderFromContext returns the extra request header carried by ctx,
+// or nil
+func RequestHeaderFromContext(ctx context.Context) http.Header {
+	header, _ := ctx.Value(requestHeaderKey{}).(http.Header)
+	return header
 }
@@ -0,0 +1,43 @@
+package models
+
+// AnalysisOptions are per-request settings for a single analysis
+type AnalysisOptions struct {
+	// BaseURL, when set, replaces the fetched URL as the base for resolving links
+	BaseURL string
+	// ResolveHost reports the address the page was fetched from, or the
+	// addresses of the url's host when the fetch didn't report one, and their
+	// reverse DNS names
+	ResolveHost bool
+	// StrictHTML also validates the raw markup, reporting problems the lenient
+	// parser would repair
+	StrictHTML bool
+	// CheckStylesheets probes each <link rel="stylesheet"> and reports the ones
+	// that fail to load
+	CheckStylesheets bool
+	// PageSize reports the body size as fetched and as it would be gzipped
+	PageSize bool
+	// Freshness reports the page's Last-Modified time and age from its
+	// response headers
+	Freshness bool
+	// SkipNetworkChecks leaves out the steps that probe the page's links and
+	// images, so nothing but the page itself is fetched
+	SkipNetworkChecks bool
+	// AnchorText reports the text of each link, within the analyzer's anchor
+	// text limits
+	AnchorText bool
+	// BearerToken is sent as "Authorization: Bearer <token>" when fetching the
+	// page. It is never sent with link checks.
+	BearerToken string
+	// HostHeader overrides the Host header of the page fetch while still
+	// connecting to the URL's host
+	HostHeader string
+	// UserAgent overrides the User-Agent of the page fetch. Link checks keep
+	// their own.
+	UserAgent string
+	// OnPageFetched is called once the page is fetched and parsed, before any
+	// analyzer runs
+	OnPageFetched func(*AnalysisResult)
+	// OnLinkCheck is called for each link check as it finishes. Calls are
+	// never concurrent.
+	OnLinkCheck func(LinkCheck)
+}
@@ -1,22 +1,87 @@
 package models
 
 import (
+	"net/http"
 	"net/url"
+	"time"
 
 	"golang.org/x/net/html"
 )
 
 type AnalysisResult struct {
-	BaseUrl           *url.URL
-	HtmlNode          *html.Node
-	BodyByte          []byte
-	HTMLVersion       string
-	Title             string
-	Headings          map[string]int
-	InternalLinks     int
-	ExternalLinks     int
-	InaccessibleLinks int
-	HasLoginForm      bool
-	Error             string
-	StatusCode        int
+	BaseUrl               *url.URL
+	HtmlNode              *html.Node
+	BodyByte              []byte
+	ResponseHeader        http.Header
+	HTMLVersion           string
+	Doctype               string
+	Title                 string
+	Headings              map[string]int
+	Outline               []OutlineNode
+	ThinSections          []string
+	TotalLinks            int
+	UniqueLinks           int
+	InternalLinks         int
+	ExternalLinks         int
+	SamePageLinks         int
+	AnchorLinks           int
+	InaccessibleLinks     int
+	SkippedLinks          int
+	RobotsSkippedLinks    int
+	RedirectingLinks      []LinkRedirect
+	InsecureInternalLinks []string
+	AnchorTexts           []AnchorText
+	ImagesTotal           int
+	InaccessibleImages    int
+	MailtoLinks           int
+	TelLinks              int
+	OtherSchemeLinks      map[string]int
+	LinksByScheme         map[string]int
+	HasLoginForm          bool
+	FormsTotal            int
+	LoginForms            []FormInfo
+	Landmarks             map[string]int
+	HasSkipNavLink        bool
+	InlineEventHandlers   int
+	CommentCount          int
+	ConditionalComments   int
+	BlockingScripts       int
+	AsyncScripts          int
+	DeferScripts          int
+	ResourceHints         []ResourceHint
+	BrokenStylesheets     []string
+	ContentFingerprint    string
+	ContentSecurityPolicy string
+	Warnings              []string
+	ContentType           string
+	PageSizeBytes         int
+	GzippedSizeBytes      int
+	LastModified          time.Time
+	PageAge               time.Duration
+	Charset               string
+	Language              string
+	TTFBMs                int64
+	HTTPProtocol          string
+	FetchRetries          int
+	Timings               map[string]time.Duration
+	FinalURL              string
+	RedirectCount         int
+	Truncated             bool
+	ResolvedIPs           []string
+	ReverseDNS            []string
+	TLSSubject            string
+	TLSIssuer             string
+	TLSNotAfter           time.Time
+	LikelyClientRendered  bool
+	DOMTooDeep            bool
+	MalformedHTML         bool
+	MalformedHTMLDetails  []string
+	MetaRefreshURL        string
+	MetaRefreshDelay      int
+	MetaTagCount          int
+	MetaDescription       string
+	MetaRobots            []string
+	CanonicalURL          string
+	Error                 string
+	StatusCode            int
 }
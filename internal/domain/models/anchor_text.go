@@ -0,0 +1,7 @@
+package models
+
+// AnchorText is the visible text of a link, possibly truncated
+type AnchorText struct {
+	URL  string
+	Text string
+}
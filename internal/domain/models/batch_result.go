@@ -0,0 +1,14 @@
+package models
+
+type BatchResult struct {
+	URL    string
+	Result *AnalysisResult
+	Err    error
+}
+
+// BatchDuplicates groups the URLs of a batch whose pages share a title or a
+// meta description. Each group holds two or more URLs in input order.
+type BatchDuplicates struct {
+	DuplicateTitleGroups       [][]string
+	DuplicateDescriptionGroups [][]string
+}
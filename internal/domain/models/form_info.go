@@ -0,0 +1,22 @@
+package models
+
+// FormKindLogin and FormKindRegistration are the FormInfo.Kind values
+const (
+	FormKindLogin        = "login"
+	FormKindRegistration = "registration"
+)
+
+// FormInfo is a form with a password field. Kind is a guess from its fields:
+// a form asking for the password twice, or for both an email and a username,
+// is taken for a registration form and any other for a login form.
+type FormInfo struct {
+	// Action is resolved against the page's base url, empty when the form
+	// submits to the page itself
+	Action             string
+	Method             string
+	Kind               string
+	HasEmail           bool
+	HasUsername        bool
+	HasPassword        bool
+	HasConfirmPassword bool
+}
@@ -0,0 +1,30 @@
+package models
+
+// SkipReasonHost and SkipReasonRobots are the LinkCheck.SkipReason values
+const (
+	SkipReasonHost   = "host"
+	SkipReasonRobots = "robots"
+)
+
+type LinkCheck struct {
+	URL        string
+	Internal   bool
+	StatusCode int
+	Accessible bool
+	// Skipped links were not probed because their host is excluded or
+	// robots.txt disallows them, SkipReason says which
+	Skipped    bool
+	SkipReason string
+	// Redirect is set when the link answered with a redirect and redirects
+	// are reported
+	Redirect *LinkRedirect
+	Err      error
+}
+
+// LinkRedirect is a checked link that redirected. StatusCode is the status of
+// the redirect and FinalURL where following it led.
+type LinkRedirect struct {
+	SourceURL  string
+	FinalURL   string
+	StatusCode int
+}
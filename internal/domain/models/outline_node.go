@@ -0,0 +1,7 @@
+package models
+
+type OutlineNode struct {
+	Level    int
+	Text     string
+	Children []OutlineNode
+}
@@ -0,0 +1,8 @@
+package models
+
+type ResourceHint struct {
+	URL    string
+	Rel    string
+	As     string
+	Source string
+}
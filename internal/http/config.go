@@ -2,10 +2,10 @@ package http
 
 import (
 	"fmt"
+	"github.com/joho/godotenv"
 	"os"
 	"strings"
 	"time"
-	"github.com/joho/godotenv"
 )
 
 type HTTPServerConfig struct {
@@ -16,7 +16,17 @@ type HTTPServerConfig struct {
 		Write        time.Duration
 		Idle         time.Duration
 		ShutdownWait time.Duration
+		// Handler is the deadline of the analysis routes' work, zero for none
+		Handler time.Duration
 	}
+	// AuxTimeouts apply to the metrics and pprof servers
+	AuxTimeouts ServerTimeouts
+}
+
+type ServerTimeouts struct {
+	Read       time.Duration
+	ReadHeader time.Duration
+	Write      time.Duration
 }
 
 func NewHTTPServerConfig() (*HTTPServerConfig, error) {
@@ -78,9 +88,43 @@ func NewHTTPServerConfig() (*HTTPServerConfig, error) {
 		cfg.Timeouts.ShutdownWait = dur
 	}
 
+	// Optional timeouts: the analysis handler deadline, and the timeouts of the
+	// metrics and pprof servers. The aux write timeout default leaves room for
+	// 30s CPU profiles.
+	parseOptionalDuration := func(envVar string, def time.Duration) (time.Duration, error) {
+		if os.Getenv(envVar) == "" {
+			return def, nil
+		}
+		return parseDuration(envVar)
+	}
+
+	if dur, err := parseOptionalDuration("HTTP_APP_HANDLER_TIMEOUT_DURATION", 0); err != nil {
+		errors = append(errors, err.Error())
+	} else {
+		cfg.Timeouts.Handler = dur
+	}
+
+	if dur, err := parseOptionalDuration("HTTP_AUX_READ_TIMEOUT_DURATION", 10*time.Second); err != nil {
+		errors = append(errors, err.Error())
+	} else {
+		cfg.AuxTimeouts.Read = dur
+	}
+
+	if dur, err := parseOptionalDuration("HTTP_AUX_READ_HEADER_TIMEOUT_DURATION", 5*time.Second); err != nil {
+		errors = append(errors, err.Error())
+	} else {
+		cfg.AuxTimeouts.ReadHeader = dur
+	}
+
+	if dur, err := parseOptionalDuration("HTTP_AUX_WRITE_TIMEOUT_DURATION", 60*time.Second); err != nil {
+		errors = append(errors, err.Error())
+	} else {
+		cfg.AuxTimeouts.Write = dur
+	}
+
 	if len(errors) > 0 {
 		return nil, fmt.Errorf("configuration validation failed:\n%s", strings.Join(errors, "\n"))
 	}
 
 	return cfg, nil
-}
\ No newline at end of file
+}
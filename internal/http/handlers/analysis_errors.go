@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"web_page_analyzer/internal/http/middleware"
+	"web_page_analyzer/internal/pkg/errors"
+	"web_page_analyzer/internal/service"
+)
+
+// statusClientClosedRequest is the status for a request the client gave up on
+// before the response was ready
+const statusClientClosedRequest = middleware.StatusClientClosedRequest
+
+// analysisError picks the message and status of the error response for a
+// failed analysis: a bad url is the client's fault, a page that took too long
+// is a gateway timeout, a request the client cancelled is 499, and any other
+// failure to fetch or use the page is a bad gateway. Deadlines that ran out
+// outside an AnalyzeError still count as timeouts.
+func analysisError(err error) (message string, code int) {
+	if errors.Is(err, context.Canceled) {
+		return `request was cancelled`, statusClientClosedRequest
+	}
+	kind, ok := service.ErrorKindOf(err)
+	if !ok && service.IsTimeout(err) {
+		kind = service.KindTimeout
+	}
+	switch kind {
+	case service.KindInvalidURL:
+		return `url is invalid`, http.StatusBadRequest
+	case service.KindTimeout:
+		return `web page took too long to respond`, http.StatusGatewayTimeout
+	case service.KindUnreachable:
+		return `web page could not be reached`, http.StatusBadGateway
+	case service.KindUpstreamStatus:
+		return `web page answered with an error status`, http.StatusBadGateway
+	case service.KindParseFailed:
+		return `web page could not be parsed`, http.StatusBadGateway
+	case service.KindResourceLimit:
+		return `web page is too large to analyze`, http.StatusBadGateway
+	case service.KindUnsupportedContentType:
+		return `web page is not HTML`, http.StatusBadGateway
+	default:
+		return `failed to analyze web page`, http.StatusBadGateway
+	}
+}
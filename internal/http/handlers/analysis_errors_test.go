@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"web_page_analyzer/internal/domain/adaptors"
+	"web_page_analyzer/internal/http/middleware"
+	"web_page_analyzer/internal/service"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnalysisError(t *testing.T) {
+	tests := []struct {
+		name        string
+		client      *stubWebClient
+		url         string
+		wantMessage string
+		wantCode    int
+	}{
+		{name: "invalid url", client: &stubWebClient{statusCode: http.StatusOK}, url: "http:///path",
+			wantMessage: "url is invalid", wantCode: http.StatusBadRequest},
+		{name: "unsupported scheme", client: &stubWebClient{err: adaptors.ErrHostNotFound}, url: "ftp://example.com",
+			wantMessage: "url is invalid", wantCode: http.StatusBadRequest},
+		{name: "upstream not found", client: &stubWebClient{statusCode: http.StatusNotFound}, url: "https://example.com",
+			wantMessage: "web page answered with an error status", wantCode: http.StatusBadGateway},
+		{name: "upstream server error", client: &stubWebClient{statusCode: http.StatusInternalServerError}, url: "https://example.com",
+			wantMessage: "web page answered with an error status", wantCode: http.StatusBadGateway},
+		{name: "unreachable", client: &stubWebClient{err: adaptors.ErrHostNotFound}, url: "https://example.com",
+			wantMessage: "web page could not be reached", wantCode: http.StatusBadGateway},
+		{name: "timeout", client: &stubWebClient{err: context.DeadlineExceeded}, url: "https://example.com",
+			wantMessage: "web page took too long to respond", wantCode: http.StatusGatewayTimeout},
+		{name: "too large", client: &stubWebClient{body: "<html></html>", statusCode: http.StatusOK}, url: "https://example.com",
+			wantMessage: "web page is too large to analyze", wantCode: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := service.NewAnalyzer(log.New(), tt.client, service.WithResourceLimits(10, 0))
+			_, err := analyzer.Analyze(context.Background(), tt.url)
+
+			assert.Error(t, err)
+			message, code := analysisError(err)
+			assert.Equal(t, tt.wantMessage, message)
+			assert.Equal(t, tt.wantCode, code)
+		})
+	}
+}
+
+func TestWebPageAnalysisHandlerMapsAnalysisErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		client   *stubWebClient
+		wantCode int
+	}{
+		{name: "upstream status", client: &stubWebClient{statusCode: http.StatusForbidden}, wantCode: http.StatusBadGateway},
+		{name: "timeout", client: &stubWebClient{err: context.DeadlineExceeded}, wantCode: http.StatusGatewayTimeout},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewWebPageAnalysisHandler(service.NewAnalyzer(log.New(), tt.client), log.New())
+			req := httptest.NewRequest(http.MethodPost, "/analyze", strings.NewReader(`{"url": "https://example.com"}`))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.Handle(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			var response ErrorResponse
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+			assert.Equal(t, tt.wantCode, response.Code)
+		})
+	}
+}
+
+func TestWebPageAnalysisHandlerTimesOut(t *testing.T) {
+	client := &stubWebClient{body: "<html></html>", statusCode: http.StatusOK, delay: time.Second}
+	handler := middleware.TimeoutMiddleware(20 * time.Millisecond)(
+		http.HandlerFunc(NewWebPageAnalysisHandler(newTestAnalyzer(client), log.New()).Handle))
+	req := httptest.NewRequest(http.MethodPost, "/analyze", strings.NewReader(`{"url": "https://example.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	start := time.Now()
+	handler.ServeHTTP(rec, req)
+
+	assert.Less(t, time.Since(start), time.Second, "the analysis should stop at the deadline")
+	assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	var response ErrorResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, http.StatusGatewayTimeout, response.Code)
+}
+
+// timeoutError is a transport timeout, like the ones net.Dialer returns
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestAnalysisErrorWithoutKind(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantMessage string
+		wantCode    int
+	}{
+		{name: "cancelled", err: context.Canceled,
+			wantMessage: "request was cancelled", wantCode: statusClientClosedRequest},
+		{name: "cancelled fetch", err: &service.AnalyzeError{Kind: service.KindUnreachable, Err: context.Canceled},
+			wantMessage: "request was cancelled", wantCode: statusClientClosedRequest},
+		{name: "deadline", err: fmt.Errorf("probing links: %w", context.DeadlineExceeded),
+			wantMessage: "web page took too long to respond", wantCode: http.StatusGatewayTimeout},
+		{name: "net timeout", err: fmt.Errorf("dial: %w", timeoutError{}),
+			wantMessage: "web page took too long to respond", wantCode: http.StatusGatewayTimeout},
+		{name: "other", err: errors.New("boom"),
+			wantMessage: "failed to analyze web page", wantCode: http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, code := analysisError(tt.err)
+			assert.Equal(t, tt.wantMessage, message)
+			assert.Equal(t, tt.wantCode, code)
+		})
+	}
+}
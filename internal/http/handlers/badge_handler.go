@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"sync"
+	"time"
+	"web_page_analyzer/internal/domain/models"
+	"web_page_analyzer/internal/pkg/errors"
+	"web_page_analyzer/internal/service"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const badgeTemplate = `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[3]s: %[4]s">
+<rect width="%[2]d" height="20" fill="#555"/>
+<rect x="%[2]d" width="%[5]d" height="20" fill="#007ec6"/>
+<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,sans-serif" font-size="11">
+<text x="%[6]d" y="14">%[3]s</text>
+<text x="%[7]d" y="14">%[4]s</text>
+</g>
+</svg>`
+
+// badgeMetrics maps the supported metric query values to the result field they display
+var badgeMetrics = map[string]func(*models.AnalysisResult) int{
+	"internal_links":     func(r *models.AnalysisResult) int { return r.InternalLinks },
+	"external_links":     func(r *models.AnalysisResult) int { return r.ExternalLinks },
+	"inaccessible_links": func(r *models.AnalysisResult) int { return r.InaccessibleLinks },
+}
+
+// defaultBadgeCacheTTL is how long a url's badge values are reused unless
+// WithBadgeCacheTTL says otherwise. Badges are embedded in pages that are
+// reloaded far more often than the analyzed page changes.
+const defaultBadgeCacheTTL = time.Minute
+
+// maxBadgeCacheEntries bounds the badge cache; urls arriving while it is full
+// of live entries are analyzed without being cached
+const maxBadgeCacheEntries = 1000
+
+type BadgeHandler struct {
+	service  *service.Analyzer
+	log      *log.Logger
+	cache    *badgeCache
+	redactor queryRedactor
+}
+
+type BadgeHandlerOption func(*BadgeHandler)
+
+// WithBadgeCacheTTL sets how long the metric values of a url are reused
+// before it is analyzed again. Zero or less disables the cache.
+func WithBadgeCacheTTL(ttl time.Duration) BadgeHandlerOption {
+	return func(h *BadgeHandler) {
+		h.cache = newBadgeCache(ttl)
+	}
+}
+
+// WithBadgeRedactedQueryParams replaces DefaultRedactedQueryParams as the
+// query parameters masked in the URLs error responses quote
+func WithBadgeRedactedQueryParams(params ...string) BadgeHandlerOption {
+	return func(h *BadgeHandler) {
+		h.redactor = newQueryRedactor(params)
+	}
+}
+
+func NewBadgeHandler(service *service.Analyzer, log *log.Logger, opts ...BadgeHandlerOption) *BadgeHandler {
+	h := &BadgeHandler{
+		service:  service,
+		log:      log,
+		cache:    newBadgeCache(defaultBadgeCacheTTL),
+		redactor: newQueryRedactor(DefaultRedactedQueryParams),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *BadgeHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	h.log.Debug(`badge handler called`)
+
+	request := WebPageAnalysisRequest{URL: r.URL.Query().Get(`url`)}
+	if err := request.Validate(); err != nil {
+		h.log.WithError(err).Error(`failed to validate badge request`)
+		sendError(w, `failed to validate badge request`, h.redactor.redactError(err), http.StatusBadRequest)
+		return
+	}
+
+	metric := r.URL.Query().Get(`metric`)
+	if _, ok := badgeMetrics[metric]; !ok {
+		err := errors.New(fmt.Sprintf(`unsupported metric %q`, metric))
+		sendError(w, `failed to validate badge request`, err, http.StatusBadRequest)
+		return
+	}
+
+	values, ok := h.cache.get(request.URL)
+	if !ok {
+		result, err := h.service.Analyze(r.Context(), request.URL)
+		if err != nil {
+			message, code := analysisError(err)
+			sendError(w, message, h.redactor.redactError(err), code)
+			return
+		}
+		values = badgeValues(result)
+		h.cache.put(request.URL, values)
+	}
+
+	w.Header().Set(`Content-Type`, `image/svg+xml`)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(renderBadge(metric, fmt.Sprintf(`%d`, values[metric]))))
+}
+
+// badgeValues picks every supported metric from result, so one analysis
+// serves the badges of all metrics for its url
+func badgeValues(result *models.AnalysisResult) map[string]int {
+	values := make(map[string]int, len(badgeMetrics))
+	for metric, valueOf := range badgeMetrics {
+		values[metric] = valueOf(result)
+	}
+	return values
+}
+
+// badgeCache keeps the badge values of recently analyzed urls. Only the
+// values are kept, not the result with its body and parsed document.
+type badgeCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]badgeCacheEntry
+}
+
+type badgeCacheEntry struct {
+	values  map[string]int
+	expires time.Time
+}
+
+// newBadgeCache returns a cache keeping values for ttl, or nil, which caches
+// nothing, when ttl is zero or less
+func newBadgeCache(ttl time.Duration) *badgeCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &badgeCache{ttl: ttl, entries: map[string]badgeCacheEntry{}}
+}
+
+func (c *badgeCache) get(url string) (map[string]int, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[url]
+	if !ok || time.Now().After(entry.expires) {
+		return nil, false
+	}
+	return entry.values, true
+}
+
+func (c *badgeCache) put(url string, values map[string]int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= maxBadgeCacheEntries {
+		for key, entry := range c.entries {
+			if now.After(entry.expires) {
+				delete(c.entries, key)
+			}
+		}
+		if len(c.entries) >= maxBadgeCacheEntries {
+			return
+		}
+	}
+	c.entries[url] = badgeCacheEntry{values: values, expires: now.Add(c.ttl)}
+}
+
+// renderBadge draws a two-part flat badge, sizing each part from its text length
+func renderBadge(label string, value string) string {
+	labelWidth := 10 + 7*len(label)
+	valueWidth := 10 + 7*len(value)
+	return fmt.Sprintf(badgeTemplate,
+		labelWidth+valueWidth,
+		labelWidth,
+		html.EscapeString(label),
+		html.EscapeString(value),
+		valueWidth,
+		labelWidth/2,
+		labelWidth+valueWidth/2,
+	)
+}
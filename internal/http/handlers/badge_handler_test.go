@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBadgeHandler(t *testing.T) {
+	target := newLinkTargetServer(t)
+	page := `<html><body>
+		<a href="` + target.URL + `/one">One</a>
+		<a href="` + target.URL + `/two">Two</a>
+		<a href="/three">Three</a>
+	</body></html>`
+	handler := NewBadgeHandler(newTestAnalyzer(&stubWebClient{body: page, statusCode: http.StatusOK}), log.New())
+
+	tests := []struct {
+		name      string
+		metric    string
+		wantCode  int
+		wantValue string
+		wantSVG   bool
+	}{
+		{name: "internal links", metric: "internal_links", wantCode: http.StatusOK, wantValue: ">3<", wantSVG: true},
+		{name: "external links", metric: "external_links", wantCode: http.StatusOK, wantValue: ">0<", wantSVG: true},
+		{name: "unsupported metric", metric: "title", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{"url": {target.URL}, "metric": {tt.metric}}
+			req := httptest.NewRequest(http.MethodGet, "/badge?"+query.Encode(), nil)
+			rec := httptest.NewRecorder()
+
+			handler.Handle(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if !tt.wantSVG {
+				return
+			}
+			assert.Equal(t, "image/svg+xml", rec.Header().Get("Content-Type"))
+			body := rec.Body.String()
+			assert.NoError(t, xml.Unmarshal([]byte(body), new(struct{})), "badge must be well-formed XML")
+			assert.True(t, strings.HasPrefix(body, "<svg"))
+			assert.Contains(t, body, tt.metric)
+			assert.Contains(t, body, tt.wantValue)
+		})
+	}
+}
+
+func TestBadgeHandlerReusesCachedValues(t *testing.T) {
+	target := newLinkTargetServer(t)
+	page := `<html><body><a href="/one">One</a><a href="https://other.example.com/">Other</a></body></html>`
+
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		wantCalls int32
+	}{
+		{name: "cached", ttl: time.Minute, wantCalls: 1},
+		{name: "cache disabled", ttl: 0, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &stubWebClient{body: page, statusCode: http.StatusOK}
+			handler := NewBadgeHandler(newTestAnalyzer(client), log.New(), WithBadgeCacheTTL(tt.ttl))
+
+			for _, metric := range []string{"internal_links", "external_links"} {
+				query := url.Values{"url": {target.URL}, "metric": {metric}}
+				rec := httptest.NewRecorder()
+				handler.Handle(rec, httptest.NewRequest(http.MethodGet, "/badge?"+query.Encode(), nil))
+				assert.Equal(t, http.StatusOK, rec.Code)
+				assert.Contains(t, rec.Body.String(), ">1<")
+			}
+			assert.Equal(t, tt.wantCalls, client.calls.Load())
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"web_page_analyzer/internal/domain/models"
+	"web_page_analyzer/internal/pkg/errors"
+	"web_page_analyzer/internal/service"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultMaxBatchURLs caps the urls of one batch request unless
+// WithMaxBatchURLs says otherwise
+const defaultMaxBatchURLs = 20
+
+// BatchAnalysisHandler analyzes several pages in one request. The pages are
+// analyzed on the analyzer's worker pool and a failing page only fails its
+// own entry.
+type BatchAnalysisHandler struct {
+	service  *service.Analyzer
+	log      *log.Logger
+	maxURLs  int
+	redactor queryRedactor
+}
+
+type BatchAnalysisHandlerOption func(*BatchAnalysisHandler)
+
+// WithMaxBatchURLs caps how many urls one batch request may hold
+func WithMaxBatchURLs(n int) BatchAnalysisHandlerOption {
+	return func(h *BatchAnalysisHandler) {
+		if n > 0 {
+			h.maxURLs = n
+		}
+	}
+}
+
+// WithBatchRedactedQueryParams replaces DefaultRedactedQueryParams as the
+// query parameters masked in returned URLs
+func WithBatchRedactedQueryParams(params ...string) BatchAnalysisHandlerOption {
+	return func(h *BatchAnalysisHandler) {
+		h.redactor = newQueryRedactor(params)
+	}
+}
+
+type BatchAnalysisRequest struct {
+	URLs []string `json:"urls"`
+}
+
+// BatchAnalysisResponse holds one result per requested url and, across the
+// pages analyzed successfully, the groups of urls sharing a title or a meta
+// description
+type BatchAnalysisResponse struct {
+	Results                    []BatchAnalysisResult `json:"results"`
+	DuplicateTitleGroups       [][]string            `json:"duplicate_title_groups,omitempty"`
+	DuplicateDescriptionGroups [][]string            `json:"duplicate_description_groups,omitempty"`
+}
+
+// BatchAnalysisResult is the outcome for one url of the batch, in request
+// order. Exactly one of Result and Error is set.
+type BatchAnalysisResult struct {
+	URL    string                   `json:"url"`
+	Result *WebPageAnalysisResponse `json:"result,omitempty"`
+	Error  *ErrorResponse           `json:"error,omitempty"`
+}
+
+func NewBatchAnalysisHandler(service *service.Analyzer, log *log.Logger, opts ...BatchAnalysisHandlerOption) *BatchAnalysisHandler {
+	h := &BatchAnalysisHandler{
+		service:  service,
+		log:      log,
+		maxURLs:  defaultMaxBatchURLs,
+		redactor: newQueryRedactor(DefaultRedactedQueryParams),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+func (h *BatchAnalysisHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	h.log.Debug(`batch analyze handler called`)
+
+	if !isJSONContentType(r.Header.Get(`Content-Type`)) {
+		err := errors.New(fmt.Sprintf(`unsupported content type %q, send the request body as application/json`, r.Header.Get(`Content-Type`)))
+		sendError(w, `request body must be JSON`, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var request BatchAnalysisRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.log.WithError(err).Error(`failed to decode request body`)
+		sendError(w, `failed to decode request body`, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := request.validate(h.maxURLs); err != nil {
+		sendError(w, `failed to validate request body`, err, http.StatusBadRequest)
+		return
+	}
+
+	results, err := h.service.AnalyzeBatch(r.Context(), request.URLs)
+	if err != nil {
+		if errors.Is(err, service.ErrTooManyBatches) {
+			sendError(w, `too many batches in progress, retry later`, err, http.StatusTooManyRequests)
+			return
+		}
+		sendError(w, `failed to analyze batch`, err, http.StatusInternalServerError)
+		return
+	}
+
+	response := BatchAnalysisResponse{Results: make([]BatchAnalysisResult, 0, len(results))}
+	for _, result := range results {
+		response.Results = append(response.Results, h.newBatchAnalysisResult(result))
+	}
+	duplicates := service.FindBatchDuplicates(results)
+	response.DuplicateTitleGroups = h.redactGroups(duplicates.DuplicateTitleGroups)
+	response.DuplicateDescriptionGroups = h.redactGroups(duplicates.DuplicateDescriptionGroups)
+
+	w.Header().Set(`Content-Type`, `application/json`)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.WithError(err).Error(`failed to write response`)
+	}
+}
+
+func (h *BatchAnalysisHandler) newBatchAnalysisResult(result models.BatchResult) BatchAnalysisResult {
+	entry := BatchAnalysisResult{URL: h.redactor.url(result.URL)}
+	if result.Err != nil {
+		message, code := analysisError(result.Err)
+		entry.Error = &ErrorResponse{
+			Message: message,
+			Error:   h.redactor.text(result.Err.Error()),
+			Code:    code,
+		}
+		return entry
+	}
+	if result.Result != nil {
+		response := h.redactor.response(newWebPageAnalysisResponse(result.Result))
+		entry.Result = &response
+	}
+	return entry
+}
+
+// redactGroups masks the urls of duplicate groups the same way as the
+// results they point at
+func (h *BatchAnalysisHandler) redactGroups(groups [][]string) [][]string {
+	for _, group := range groups {
+		for i, u := range group {
+			group[i] = h.redactor.url(u)
+		}
+	}
+	return groups
+}
+
+func (r *BatchAnalysisRequest) validate(maxURLs int) error {
+	if len(r.URLs) == 0 {
+		return errors.New(`urls must not be empty`)
+	}
+	if len(r.URLs) > maxURLs {
+		return errors.New(fmt.Sprintf(`urls holds %d entries, at most %d are allowed`, len(r.URLs), maxURLs))
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchAnalysisHandlerIsolatesErrors(t *testing.T) {
+	page := `<html><head><title>Batch page</title></head><body><h1>Hello</h1></body></html>`
+	handler := NewBatchAnalysisHandler(newTestAnalyzer(&stubWebClient{body: page, statusCode: http.StatusOK}), log.New())
+
+	body := `{"urls": ["https://one.example.com/", "not a url", "https://three.example.com/"]}`
+	req := httptest.NewRequest(http.MethodPost, "/analyze/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handler.Handle(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var response BatchAnalysisResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	if !assert.Len(t, response.Results, 3) {
+		return
+	}
+
+	for _, i := range []int{0, 2} {
+		result := response.Results[i]
+		assert.Nil(t, result.Error, result.URL)
+		if assert.NotNil(t, result.Result, result.URL) {
+			assert.Equal(t, "Batch page", result.Result.Title)
+		}
+	}
+	assert.Equal(t, "https://one.example.com/", response.Results[0].URL)
+	assert.Equal(t, "https://three.example.com/", response.Results[2].URL)
+
+	failed := response.Results[1]
+	assert.Equal(t, "not a url", failed.URL)
+	assert.Nil(t, failed.Result)
+	if assert.NotNil(t, failed.Error) {
+		assert.Equal(t, http.StatusBadRequest, failed.Error.Code)
+		assert.Equal(t, "url is invalid", failed.Error.Message)
+	}
+}
+
+func TestBatchAnalysisHandlerGroupsDuplicateTitles(t *testing.T) {
+	page := `<html><head><title>Shared title</title></head><body></body></html>`
+	handler := NewBatchAnalysisHandler(newTestAnalyzer(&stubWebClient{body: page, statusCode: http.StatusOK}), log.New())
+
+	body := `{"urls": ["https://one.example.com/", "not a url", "https://two.example.com/?token=secret"]}`
+	req := httptest.NewRequest(http.MethodPost, "/analyze/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+
+	handler.Handle(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var response BatchAnalysisResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, [][]string{{"https://one.example.com/", "https://two.example.com/?token=REDACTED"}}, response.DuplicateTitleGroups)
+	assert.Empty(t, response.DuplicateDescriptionGroups, "pages without a description are not grouped")
+}
+
+func TestBatchAnalysisHandlerRejectsBadRequests(t *testing.T) {
+	handler := NewBatchAnalysisHandler(newTestAnalyzer(&stubWebClient{statusCode: http.StatusOK}), log.New(), WithMaxBatchURLs(2))
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantCode    int
+	}{
+		{name: "not json", contentType: "text/plain", body: `{"urls": ["https://example.com"]}`, wantCode: http.StatusUnsupportedMediaType},
+		{name: "malformed body", contentType: "application/json", body: `{"urls": `, wantCode: http.StatusBadRequest},
+		{name: "no urls", contentType: "application/json", body: `{"urls": []}`, wantCode: http.StatusBadRequest},
+		{name: "too many urls", contentType: "application/json", body: `{"urls": ["https://a.example", "https://b.example", "https://c.example"]}`, wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/analyze/batch", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+
+			handler.Handle(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			var response ErrorResponse
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+			assert.Equal(t, tt.wantCode, response.Code)
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+// responseETag hashes the response with the fields that change from one fetch
+// of a page to the next cleared, so repeated analyses of an unchanged page
+// share an ETag. The format is hashed too, since the same result encodes to a
+// different body per format.
+func responseETag(response WebPageAnalysisResponse, format string) (string, error) {
+	response.TTFBMs = 0
+	response.TimingsMs = nil
+	response.PageAgeSeconds = 0
+	response.FetchRetries = 0
+	// DNS round robin hands out a different order, or set, per lookup
+	response.ResolvedIPs = nil
+	response.ReverseDNS = nil
+	body, err := json.Marshal(response)
+	if err != nil {
+		return "", err
+	}
+	return etagFor(append([]byte(format+"\n"), body...)), nil
+}
+
+// etagFor derives a strong ETag from the bytes identifying a representation
+func etagFor(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag.
+// Weak comparison is used as RFC 9110 requires for If-None-Match.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
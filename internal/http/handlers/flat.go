@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Response formats selected with the format query parameter
+const (
+	formatJSON = `json`
+	formatFlat = `flat`
+)
+
+const flatContentType = `text/plain; charset=utf-8`
+
+// flattenResponse renders response as one key=value line per field, sorted
+// by key. Nested keys are joined with dots, like headings.h1=3, and list
+// items are keyed by index. Fields left out of the JSON response are left out
+// here too.
+func flattenResponse(response WebPageAnalysisResponse) ([]byte, error) {
+	body, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// keep numbers as written so large counts don't pass through float64
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	fields := map[string]string{}
+	flattenValue(fields, "", value)
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, key := range keys {
+		fmt.Fprintf(&buf, "%s=%s\n", key, fields[key])
+	}
+	return buf.Bytes(), nil
+}
+
+func flattenValue(fields map[string]string, key string, value any) {
+	switch v := value.(type) {
+	case map[string]any:
+		for k, child := range v {
+			flattenValue(fields, joinKey(key, k), child)
+		}
+	case []any:
+		for i, child := range v {
+			flattenValue(fields, joinKey(key, strconv.Itoa(i)), child)
+		}
+	case string:
+		// a value spanning lines would break the one-field-per-line format
+		if strings.ContainsAny(v, "\r\n") {
+			v = strconv.Quote(v)
+		}
+		fields[key] = v
+	case nil:
+		fields[key] = ""
+	default:
+		fields[key] = fmt.Sprint(v)
+	}
+}
+
+func joinKey(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFlattenResponse(t *testing.T) {
+	response := WebPageAnalysisResponse{
+		HTMLVersion:   "HTML5",
+		Title:         "Two\nlines",
+		Headings:      map[string]int{"h1": 3, "h2": 0},
+		InternalLinks: 12,
+		HasLoginForm:  true,
+		ResourceHints: []ResourceHint{{URL: "https://cdn.example.com", Rel: "preconnect", Source: "link"}},
+	}
+
+	body, err := flattenResponse(response)
+
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+	assert.IsIncreasing(t, lines, "keys are sorted")
+	for _, want := range []string{
+		"html_version=HTML5",
+		`title="Two\nlines"`,
+		"headings.h1=3",
+		"headings.h2=0",
+		"internal_links=12",
+		"has_login_form=true",
+		"resource_hints.0.url=https://cdn.example.com",
+		"resource_hints.0.rel=preconnect",
+	} {
+		assert.Contains(t, lines, want)
+	}
+	for _, line := range lines {
+		assert.NotContains(t, line, "outline", "omitted fields stay omitted")
+	}
+}
+
+func TestWebPageAnalysisHandlerFormat(t *testing.T) {
+	page := `<html><head><title>Flat</title></head><body><h1>One</h1></body></html>`
+	handler := NewWebPageAnalysisHandler(newTestAnalyzer(&stubWebClient{body: page, statusCode: http.StatusOK}), log.New())
+
+	send := func(format string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/analyze?url=https://example.com&format="+format, nil)
+		rec := httptest.NewRecorder()
+		handler.Handle(rec, req)
+		return rec
+	}
+
+	flat := send("flat")
+	assert.Equal(t, http.StatusOK, flat.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", flat.Header().Get("Content-Type"))
+	assert.Contains(t, flat.Body.String(), "title=Flat\n")
+	assert.Contains(t, flat.Body.String(), "headings.h1=1\n")
+
+	plain := send("json")
+	assert.Equal(t, http.StatusOK, plain.Code)
+	assert.Equal(t, "application/json", plain.Header().Get("Content-Type"))
+
+	unknown := send("xml")
+	assert.Equal(t, http.StatusBadRequest, unknown.Code)
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web_page_analyzer/internal/domain/adaptors"
+	"web_page_analyzer/internal/service"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// stubWebClient serves a fixed page for every request, after delay when set,
+// and keeps the extra request header of the last one and the number of calls
+type stubWebClient struct {
+	body       string
+	statusCode int
+	err        error
+	delay      time.Duration
+	gotHeader  http.Header
+	calls      atomic.Int32
+}
+
+func (s *stubWebClient) Do(ctx context.Context, url string, method string) (*adaptors.WebResponse, error) {
+	s.calls.Add(1)
+	s.gotHeader = adaptors.RequestHeaderFromContext(ctx)
+	if s.delay > 0 {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &adaptors.WebResponse{
+		Body:       []byte(s.body),
+		StatusCode: s.statusCode,
+		Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+	}, nil
+}
+
+// newLinkTargetServer starts a server so link checks stay local. Paths under
+// /broken answer 404, everything else 200.
+func newLinkTargetServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/broken") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newTestAnalyzer(client *stubWebClient) *service.Analyzer {
+	return service.NewAnalyzer(log.New(), client)
+}
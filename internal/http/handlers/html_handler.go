@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"web_page_analyzer/internal/domain/models"
+	"web_page_analyzer/internal/pkg/errors"
+	"web_page_analyzer/internal/service"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultMaxHTMLBytes caps the HTML of one request unless WithMaxHTMLBytes
+// says otherwise
+const defaultMaxHTMLBytes = 5 << 20
+
+// HTMLAnalysisHandler analyzes HTML sent in the request body instead of a
+// fetched page, e.g. pages a CI pipeline renders before they are deployed.
+// Links and images are only probed when the request asks for it.
+type HTMLAnalysisHandler struct {
+	service  *service.Analyzer
+	log      *log.Logger
+	maxBytes int64
+	redactor queryRedactor
+}
+
+type HTMLAnalysisHandlerOption func(*HTMLAnalysisHandler)
+
+// WithMaxHTMLBytes caps the size of the HTML one request may send
+func WithMaxHTMLBytes(n int) HTMLAnalysisHandlerOption {
+	return func(h *HTMLAnalysisHandler) {
+		if n > 0 {
+			h.maxBytes = int64(n)
+		}
+	}
+}
+
+// WithHTMLRedactedQueryParams replaces DefaultRedactedQueryParams as the query
+// parameters masked in returned URLs
+func WithHTMLRedactedQueryParams(params ...string) HTMLAnalysisHandlerOption {
+	return func(h *HTMLAnalysisHandler) {
+		h.redactor = newQueryRedactor(params)
+	}
+}
+
+func NewHTMLAnalysisHandler(service *service.Analyzer, log *log.Logger, opts ...HTMLAnalysisHandlerOption) *HTMLAnalysisHandler {
+	h := &HTMLAnalysisHandler{
+		service:  service,
+		log:      log,
+		maxBytes: defaultMaxHTMLBytes,
+		redactor: newQueryRedactor(DefaultRedactedQueryParams),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Handle analyzes the text/html request body. The base_url query parameter
+// is required for resolving relative links, and check_links=true also probes
+// the page's links and images.
+func (h *HTMLAnalysisHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	h.log.Debug(`html analyze handler called`)
+
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get(`Content-Type`)); err != nil || mediaType != `text/html` {
+		err := errors.New(fmt.Sprintf(`unsupported content type %q, send the request body as text/html`, r.Header.Get(`Content-Type`)))
+		sendError(w, `request body must be HTML`, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	baseURL := r.URL.Query().Get(`base_url`)
+	if err := validateHTMLBaseURL(baseURL); err != nil {
+		sendError(w, `failed to validate request`, h.redactor.redactError(err), http.StatusBadRequest)
+		return
+	}
+
+	checkLinks := false
+	if value := r.URL.Query().Get(`check_links`); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			sendError(w, `failed to validate request`, errors.New(`check_links must be true or false`), http.StatusBadRequest)
+			return
+		}
+		checkLinks = parsed
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, h.maxBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			sendError(w, `request body is too large`, err, http.StatusRequestEntityTooLarge)
+			return
+		}
+		h.log.WithError(err).Error(`failed to read request body`)
+		sendError(w, `failed to read request body`, err, http.StatusBadRequest)
+		return
+	}
+	if len(body) == 0 {
+		sendError(w, `failed to validate request`, errors.New(`request body is empty`), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.service.AnalyzeHTMLWithOptions(r.Context(), body, baseURL, models.AnalysisOptions{SkipNetworkChecks: !checkLinks})
+	if err != nil {
+		message, code := analysisError(err)
+		sendError(w, message, h.redactor.redactError(err), code)
+		return
+	}
+
+	w.Header().Set(`Content-Type`, `application/json`)
+	if err := json.NewEncoder(w).Encode(h.redactor.response(newWebPageAnalysisResponse(result))); err != nil {
+		h.log.WithError(err).Error(`failed to write response`)
+	}
+}
+
+func validateHTMLBaseURL(baseURL string) error {
+	if baseURL == "" {
+		return errors.New(`base_url is required`)
+	}
+	if problem := urlProblem(`base_url`, baseURL); problem != "" {
+		return errors.New(problem)
+	}
+	return nil
+}
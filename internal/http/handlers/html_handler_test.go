@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTMLAnalysisHandler(t *testing.T) {
+	page := `<!DOCTYPE html><html><head><title>Preview</title></head><body>
+		<h1>Docs</h1><h2>Install</h2><h2>Usage</h2>
+		<a href="/install">Install</a>
+		<a href="usage">Usage</a>
+		<a href="#top">Top</a>
+		<a href="https://other.example/">Elsewhere</a>
+		<form><input type="text" name="user"><input type="password" name="pass"></form>
+	</body></html>`
+	client := &stubWebClient{err: assert.AnError}
+	handler := NewHTMLAnalysisHandler(newTestAnalyzer(client), log.New())
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze/html?base_url=https://example.com/docs/", strings.NewReader(page))
+	req.Header.Set("Content-Type", "text/html; charset=utf-8")
+	rec := httptest.NewRecorder()
+	handler.Handle(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var response WebPageAnalysisResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, "HTML5", response.HTMLVersion)
+	assert.Equal(t, "Preview", response.Title)
+	assert.Equal(t, 1, response.Headings["h1"])
+	assert.Equal(t, 2, response.Headings["h2"])
+	assert.Equal(t, 4, response.TotalLinks)
+	assert.Equal(t, 2, response.InternalLinks)
+	assert.Equal(t, 1, response.ExternalLinks)
+	assert.Equal(t, 1, response.AnchorLinks)
+	assert.Zero(t, response.InaccessibleLinks, "links are not checked unless asked")
+	assert.True(t, response.HasLoginForm)
+	assert.Nil(t, client.gotHeader, "the page must not be fetched")
+}
+
+func TestHTMLAnalysisHandlerChecksLinks(t *testing.T) {
+	srv := newLinkTargetServer(t)
+	page := `<html><body><a href="/ok">Ok</a><a href="/broken">Broken</a></body></html>`
+	handler := NewHTMLAnalysisHandler(newTestAnalyzer(&stubWebClient{}), log.New())
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze/html?check_links=true&base_url="+srv.URL, strings.NewReader(page))
+	req.Header.Set("Content-Type", "text/html")
+	rec := httptest.NewRecorder()
+	handler.Handle(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var response WebPageAnalysisResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	assert.Equal(t, 1, response.InaccessibleLinks)
+}
+
+func TestHTMLAnalysisHandlerRejectsBadRequests(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		wantCode    int
+		wantError   string
+	}{
+		{name: "missing base url", target: "/analyze/html", contentType: "text/html", body: "<html></html>",
+			wantCode: http.StatusBadRequest, wantError: "base_url is required"},
+		{name: "relative base url", target: "/analyze/html?base_url=/docs", contentType: "text/html", body: "<html></html>",
+			wantCode: http.StatusBadRequest, wantError: "base_url is invalid"},
+		{name: "base url with space", target: "/analyze/html?base_url=https://example.com/a%20b", contentType: "text/html", body: "<html></html>",
+			wantCode: http.StatusBadRequest, wantError: "base_url is malformed"},
+		{name: "bad check_links", target: "/analyze/html?base_url=https://example.com&check_links=maybe", contentType: "text/html",
+			body: "<html></html>", wantCode: http.StatusBadRequest, wantError: "check_links must be true or false"},
+		{name: "json body", target: "/analyze/html?base_url=https://example.com", contentType: "application/json", body: "{}",
+			wantCode: http.StatusUnsupportedMediaType, wantError: "unsupported content type"},
+		{name: "empty body", target: "/analyze/html?base_url=https://example.com", contentType: "text/html",
+			wantCode: http.StatusBadRequest, wantError: "request body is empty"},
+		{name: "too large", target: "/analyze/html?base_url=https://example.com", contentType: "text/html",
+			body: strings.Repeat("x", 65), wantCode: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHTMLAnalysisHandler(newTestAnalyzer(&stubWebClient{}), log.New(), WithMaxHTMLBytes(64))
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			handler.Handle(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			var response ErrorResponse
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+			assert.Contains(t, response.Error, tt.wantError)
+		})
+	}
+}
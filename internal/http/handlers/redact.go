@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+	"web_page_analyzer/internal/pkg/errors"
+)
+
+// DefaultRedactedQueryParams are the query parameters masked in returned URLs
+// unless WithRedactedQueryParams says otherwise
+var DefaultRedactedQueryParams = []string{
+	"token", "access_token", "refresh_token", "id_token", "auth", "api_key", "apikey", "key",
+	"password", "secret", "session", "sessionid", "session_id", "sid", "sig", "signature",
+}
+
+// redactedValue replaces the value of a redacted query parameter
+const redactedValue = `REDACTED`
+
+// queryRedactor masks the values of sensitive query parameters in the URLs a
+// response returns. Parameter names match case-insensitively.
+type queryRedactor struct {
+	params map[string]bool
+}
+
+func newQueryRedactor(params []string) queryRedactor {
+	r := queryRedactor{params: make(map[string]bool, len(params))}
+	for _, param := range params {
+		if param = strings.ToLower(strings.TrimSpace(param)); param != "" {
+			r.params[param] = true
+		}
+	}
+	return r
+}
+
+// url masks the redacted parameters of rawURL, leaving the rest of the query
+// as it was. URLs that don't parse are returned unchanged.
+func (r queryRedactor) url(rawURL string) string {
+	if len(r.params) == 0 {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+
+	pairs := strings.Split(u.RawQuery, "&")
+	redacted := false
+	for i, pair := range pairs {
+		key, _, _ := strings.Cut(pair, "=")
+		if name, err := url.QueryUnescape(key); err == nil && r.params[strings.ToLower(name)] {
+			pairs[i] = key + "=" + redactedValue
+			redacted = true
+		}
+	}
+	if !redacted {
+		return rawURL
+	}
+	u.RawQuery = strings.Join(pairs, "&")
+	return u.String()
+}
+
+func (r queryRedactor) urls(rawURLs []string) []string {
+	if len(rawURLs) == 0 {
+		return rawURLs
+	}
+	redacted := make([]string, 0, len(rawURLs))
+	for _, rawURL := range rawURLs {
+		redacted = append(redacted, r.url(rawURL))
+	}
+	return redacted
+}
+
+// urlInText finds the http(s) URLs quoted in free text, e.g. `Get "..."` in
+// a transport error
+var urlInText = regexp.MustCompile(`https?://[^\s"'<>]+`)
+
+// text masks every http(s) URL in s, e.g. in an error message that quotes
+// the fetched URL or one it redirected to
+func (r queryRedactor) text(s string) string {
+	if len(r.params) == 0 {
+		return s
+	}
+	return urlInText.ReplaceAllStringFunc(s, r.url)
+}
+
+// redactError returns err with the URLs in its message masked, or err itself
+// when there is nothing to mask
+func (r queryRedactor) redactError(err error) error {
+	if redacted := r.text(err.Error()); redacted != err.Error() {
+		return errors.New(redacted)
+	}
+	return err
+}
+
+// response returns resp with every URL it carries redacted. Slices are
+// copied, never modified in place.
+func (r queryRedactor) response(resp WebPageAnalysisResponse) WebPageAnalysisResponse {
+	if len(r.params) == 0 {
+		return resp
+	}
+	resp.FinalURL = r.url(resp.FinalURL)
+	resp.CanonicalURL = r.url(resp.CanonicalURL)
+	resp.InsecureInternalLinks = r.urls(resp.InsecureInternalLinks)
+	resp.BrokenStylesheets = r.urls(resp.BrokenStylesheets)
+	if len(resp.RedirectingLinks) > 0 {
+		redirects := make([]LinkRedirect, 0, len(resp.RedirectingLinks))
+		for _, redirect := range resp.RedirectingLinks {
+			redirect.SourceURL = r.url(redirect.SourceURL)
+			redirect.FinalURL = r.url(redirect.FinalURL)
+			redirects = append(redirects, redirect)
+		}
+		resp.RedirectingLinks = redirects
+	}
+	if len(resp.AnchorTexts) > 0 {
+		texts := make([]AnchorText, 0, len(resp.AnchorTexts))
+		for _, text := range resp.AnchorTexts {
+			text.URL = r.url(text.URL)
+			texts = append(texts, text)
+		}
+		resp.AnchorTexts = texts
+	}
+	if len(resp.LoginForms) > 0 {
+		forms := make([]LoginForm, 0, len(resp.LoginForms))
+		for _, form := range resp.LoginForms {
+			form.Action = r.url(form.Action)
+			forms = append(forms, form)
+		}
+		resp.LoginForms = forms
+	}
+	if len(resp.ResourceHints) > 0 {
+		hints := make([]ResourceHint, 0, len(resp.ResourceHints))
+		for _, hint := range resp.ResourceHints {
+			hint.URL = r.url(hint.URL)
+			hints = append(hints, hint)
+		}
+		resp.ResourceHints = hints
+	}
+	if resp.MetaRefreshURL != "" {
+		// the meta refresh warning quotes the URL
+		warnings := make([]string, 0, len(resp.Warnings))
+		for _, warning := range resp.Warnings {
+			warnings = append(warnings, r.text(warning))
+		}
+		resp.Warnings = warnings
+		resp.MetaRefreshURL = r.url(resp.MetaRefreshURL)
+	}
+	return resp
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryRedactorURL(t *testing.T) {
+	redactor := newQueryRedactor([]string{"token", "SessionID"})
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "https://example.com/a?token=secret", want: "https://example.com/a?token=REDACTED"},
+		{in: "https://example.com/a?page=2&TOKEN=secret&b=1", want: "https://example.com/a?page=2&TOKEN=REDACTED&b=1"},
+		{in: "https://example.com/a?sessionid=abc", want: "https://example.com/a?sessionid=REDACTED"},
+		{in: "https://example.com/a?token", want: "https://example.com/a?token=REDACTED"},
+		{in: "https://example.com/a?tokens=1", want: "https://example.com/a?tokens=1"},
+		{in: "https://example.com/a", want: "https://example.com/a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			assert.Equal(t, tt.want, redactor.url(tt.in))
+		})
+	}
+}
+
+func TestWebPageAnalysisHandlerRedactsQueryParams(t *testing.T) {
+	page := `<html><head><meta http-equiv="refresh" content="0; url=/next?token=secret&page=2"></head>
+		<body><form action="/next?token=secret&page=2" method="post"><input type="password"></form></body></html>`
+
+	tests := []struct {
+		name    string
+		opts    []WebPageAnalysisHandlerOption
+		wantURL string
+	}{
+		{name: "default set", wantURL: "http://example.com/next?token=REDACTED&page=2"},
+		{name: "redaction off", opts: []WebPageAnalysisHandlerOption{WithRedactedQueryParams()}, wantURL: "http://example.com/next?token=secret&page=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewWebPageAnalysisHandler(newTestAnalyzer(&stubWebClient{body: page, statusCode: http.StatusOK}), log.New(), tt.opts...)
+			req := httptest.NewRequest(http.MethodPost, "/analyze", strings.NewReader(`{"url": "http://example.com"}`))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.Handle(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			var response WebPageAnalysisResponse
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+			assert.Equal(t, tt.wantURL, response.MetaRefreshURL)
+			assert.Contains(t, strings.Join(response.Warnings, "\n"), tt.wantURL)
+			if assert.Len(t, response.LoginForms, 1) {
+				assert.Equal(t, tt.wantURL, response.LoginForms[0].Action)
+			}
+		})
+	}
+}
+
+func TestWebPageAnalysisHandlerRedactsErrors(t *testing.T) {
+	pageURL := "https://example.com/private?token=secret"
+	client := &stubWebClient{err: fmt.Errorf(`Get "%s": dial tcp: connection refused`, pageURL)}
+	handler := NewWebPageAnalysisHandler(newTestAnalyzer(client), log.New())
+	body, _ := json.Marshal(WebPageAnalysisRequest{URL: pageURL})
+
+	for _, accept := range []string{"application/json", ndjsonContentType} {
+		t.Run(accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/analyze", strings.NewReader(string(body)))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", accept)
+			rec := httptest.NewRecorder()
+
+			handler.Handle(rec, req)
+
+			assert.Equal(t, http.StatusBadGateway, rec.Code)
+			assert.NotContains(t, rec.Body.String(), "secret")
+			assert.Contains(t, rec.Body.String(), "token=REDACTED")
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"web_page_analyzer/internal/http/middleware"
+)
+
+// NotFound answers requests for unknown routes with the JSON error envelope
+func NotFound(w http.ResponseWriter, r *http.Request) {
+	sendRouteError(w, r, `route not found`, http.StatusNotFound)
+}
+
+// MethodNotAllowed answers requests using the wrong method for a route with
+// the JSON error envelope
+func MethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	sendRouteError(w, r, `method not allowed`, http.StatusMethodNotAllowed)
+}
+
+// sendRouteError writes the error envelope for a request that matched no
+// handler. The request logger already logs these, so unlike sendError it
+// doesn't.
+func sendRouteError(w http.ResponseWriter, r *http.Request, message string, code int) {
+	reqID, _ := middleware.RequestIDFromContext(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(ErrorResponse{
+		Message:   message,
+		Error:     fmt.Sprintf(`%s %s`, r.Method, r.URL.Path),
+		Code:      code,
+		RequestID: reqID,
+	})
+}
@@ -11,6 +11,9 @@ type ErrorResponse struct {
 	Message string `json:"message,omitempty"`
 	Error   string `json:"error,omitempty"`
 	Code    int    `json:"code"`
+	// RequestID is only set on errors for unmatched routes and requests
+	// rejected by a middleware
+	RequestID string `json:"request_id,omitempty"`
 }
 
 func sendError(w http.ResponseWriter, message string, err error, code int) {
@@ -25,6 +28,6 @@ func sendError(w http.ResponseWriter, message string, err error, code int) {
 		Code:    code,
 	}
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusBadRequest)
+	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(response)
-}
\ No newline at end of file
+}
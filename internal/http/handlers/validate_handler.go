@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"web_page_analyzer/internal/pkg/errors"
+	"web_page_analyzer/internal/service"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ValidateHandler checks an analysis request against the same rules as
+// /analyze without fetching anything
+type ValidateHandler struct {
+	log *log.Logger
+}
+
+type ValidateResponse struct {
+	Valid         bool     `json:"valid"`
+	NormalizedURL string   `json:"normalized_url,omitempty"`
+	Errors        []string `json:"errors"`
+}
+
+func NewValidateHandler(log *log.Logger) *ValidateHandler {
+	return &ValidateHandler{
+		log: log,
+	}
+}
+
+func (h *ValidateHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	h.log.Debug(`validate handler called`)
+
+	if !isJSONContentType(r.Header.Get(`Content-Type`)) {
+		err := errors.New(fmt.Sprintf(`unsupported content type %q, send the request body as application/json`, r.Header.Get(`Content-Type`)))
+		sendError(w, `request body must be JSON`, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var request WebPageAnalysisRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		h.log.WithError(err).Error(`failed to decode request body`)
+		sendError(w, `failed to decode request body`, err, http.StatusBadRequest)
+		return
+	}
+
+	response := ValidateResponse{Errors: request.validationErrors()}
+	if response.Errors == nil {
+		response.Errors = []string{}
+	}
+	response.Valid = len(response.Errors) == 0
+	if response.Valid {
+		response.NormalizedURL = normalizeURL(request.URL)
+	}
+
+	w.Header().Set(`Content-Type`, `application/json`)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.log.WithError(err).Error(`failed to write response`)
+	}
+}
+
+// normalizeURL returns the canonical form of a valid http(s) URL: what
+// service.ParseURL fetches, without a default port, with a root path and
+// without a fragment
+func normalizeURL(rawURL string) string {
+	u, err := service.ParseURL(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	host, port := u.Hostname(), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	if port != "" || strings.Contains(host, ":") {
+		// JoinHostPort brackets IPv6 literals; trim the dangling colon when there is no port
+		host = strings.TrimSuffix(net.JoinHostPort(host, port), ":")
+	}
+	u.Host = host
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
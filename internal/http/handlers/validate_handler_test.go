@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateHandler(t *testing.T) {
+	handler := NewValidateHandler(log.New())
+
+	tests := []struct {
+		name           string
+		body           string
+		wantValid      bool
+		wantNormalized string
+		wantErrors     []string
+	}{
+		{
+			name:           "valid url",
+			body:           `{"url": "HTTPS://Example.COM:443#top"}`,
+			wantValid:      true,
+			wantNormalized: "https://example.com/",
+			wantErrors:     []string{},
+		},
+		{
+			name:       "schemeless url",
+			body:       `{"url": "example.com/page"}`,
+			wantErrors: []string{"url is invalid"},
+		},
+		{
+			name:       "invalid scheme",
+			body:       `{"url": "ftp://example.com", "base_url": "file:///tmp"}`,
+			wantErrors: []string{"url is invalid", "base_url is invalid"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.Handle(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			var response ValidateResponse
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+			assert.Equal(t, tt.wantValid, response.Valid)
+			assert.Equal(t, tt.wantNormalized, response.NormalizedURL)
+			assert.Equal(t, tt.wantErrors, response.Errors)
+		})
+	}
+}
+
+func TestNormalizeURL(t *testing.T) {
+	tests := map[string]string{
+		"http://Example.com":             "http://example.com/",
+		"http://example.com:80/a?b=c#d":  "http://example.com/a?b=c",
+		"https://example.com:8443/":      "https://example.com:8443/",
+		"http://[::1]:8080/path":         "http://[::1]:8080/path",
+		"https://[2001:DB8::1]:443/path": "https://[2001:db8::1]/path",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, normalizeURL(in), in)
+	}
+}
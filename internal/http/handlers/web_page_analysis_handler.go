@@ -2,8 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"mime"
 	"net/http"
 	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"web_page_analyzer/internal/domain/models"
 	"web_page_analyzer/internal/pkg/errors"
 	"web_page_analyzer/internal/service"
 
@@ -11,90 +17,547 @@ import (
 )
 
 type WebPageAnalysisHandler struct {
-	service *service.Analyzer
-	metrics struct{}
-	log     *log.Logger
+	service      *service.Analyzer
+	metrics      struct{}
+	log          *log.Logger
+	cacheControl string
+	etag         bool
+	bearerTokens bool
+	redactor     queryRedactor
+}
+
+type WebPageAnalysisHandlerOption func(*WebPageAnalysisHandler)
+
+// WithCacheControl sets the Cache-Control header sent on successful responses
+func WithCacheControl(value string) WebPageAnalysisHandlerOption {
+	return func(h *WebPageAnalysisHandler) {
+		h.cacheControl = value
+	}
+}
+
+// WithETag adds an ETag derived from the analysis result and the response
+// format to successful responses and answers a matching If-None-Match with
+// 304 Not Modified
+func WithETag() WebPageAnalysisHandlerOption {
+	return func(h *WebPageAnalysisHandler) {
+		h.etag = true
+	}
+}
+
+// WithBearerTokens accepts a bearer_token in the request and sends it with the
+// page fetch. Without it such requests are rejected.
+func WithBearerTokens() WebPageAnalysisHandlerOption {
+	return func(h *WebPageAnalysisHandler) {
+		h.bearerTokens = true
+	}
+}
+
+// WithRedactedQueryParams replaces DefaultRedactedQueryParams as the query
+// parameters masked in returned URLs. With no params nothing is masked.
+func WithRedactedQueryParams(params ...string) WebPageAnalysisHandlerOption {
+	return func(h *WebPageAnalysisHandler) {
+		h.redactor = newQueryRedactor(params)
+	}
 }
 
 type WebPageAnalysisRequest struct {
 	URL string `json:"url"`
+	// FailOnBrokenLinks makes the response a 422 when at least this many links
+	// are inaccessible. Zero never fails.
+	FailOnBrokenLinks int `json:"fail_on_broken_links"`
+	// BaseURL overrides the fetched URL as the base for resolving relative links
+	BaseURL string `json:"base_url"`
+	// ResolveHost adds the host's resolved IPs and reverse DNS names to the result
+	ResolveHost bool `json:"resolve_host"`
+	// StrictHTML reports malformed markup instead of silently accepting it
+	StrictHTML bool `json:"strict_html"`
+	// CheckStylesheets reports stylesheets that fail to load
+	CheckStylesheets bool `json:"check_stylesheets"`
+	// PageSize reports the body size as fetched and gzipped
+	PageSize bool `json:"page_size"`
+	// Freshness reports the page's Last-Modified time and age
+	Freshness bool `json:"freshness"`
+	// AnchorText reports the text of each link
+	AnchorText bool `json:"anchor_text"`
+	// BearerToken authenticates the page fetch. It is never logged or echoed.
+	BearerToken string `json:"bearer_token"`
+	// HostHeader is sent as the Host header of the page fetch, e.g. to reach a
+	// staging IP under the production hostname
+	HostHeader string `json:"host_header"`
+	// UserAgent overrides the User-Agent of the page fetch. It comes from the
+	// X-Fetch-User-Agent request header rather than the body.
+	UserAgent string `json:"-"`
 }
 
+// userAgentHeader carries a per-request User-Agent for the page fetch
+const userAgentHeader = `X-Fetch-User-Agent`
+
 type WebPageAnalysisResponse struct {
-	HTMLVersion       string         `json:"html_version"`
-	Title             string         `json:"title"`
-	Headings          map[string]int `json:"headings"`
-	InternalLinks     int            `json:"internal_links"`
-	ExternalLinks     int            `json:"external_links"`
-	InaccessibleLinks int            `json:"inaccessible_links"`
-	HasLoginForm      bool           `json:"has_login_form"`
+	HTMLVersion           string           `json:"html_version"`
+	Doctype               string           `json:"doctype"`
+	Title                 string           `json:"title"`
+	Headings              map[string]int   `json:"headings"`
+	Outline               []OutlineNode    `json:"outline,omitempty"`
+	ThinSections          []string         `json:"thin_sections,omitempty"`
+	TotalLinks            int              `json:"total_links"`
+	UniqueLinks           int              `json:"unique_links"`
+	InternalLinks         int              `json:"internal_links"`
+	ExternalLinks         int              `json:"external_links"`
+	SamePageLinks         int              `json:"same_page_links,omitempty"`
+	AnchorLinks           int              `json:"anchor_links"`
+	InaccessibleLinks     int              `json:"inaccessible_links"`
+	SkippedLinks          int              `json:"skipped_links"`
+	RobotsSkippedLinks    int              `json:"robots_skipped_links,omitempty"`
+	RedirectingLinks      []LinkRedirect   `json:"redirecting_links,omitempty"`
+	InsecureInternalLinks []string         `json:"insecure_internal_links,omitempty"`
+	AnchorTexts           []AnchorText     `json:"anchor_texts,omitempty"`
+	ImagesTotal           int              `json:"images_total"`
+	InaccessibleImages    int              `json:"inaccessible_images"`
+	MailtoLinks           int              `json:"mailto_links"`
+	TelLinks              int              `json:"tel_links"`
+	OtherSchemeLinks      map[string]int   `json:"other_scheme_links,omitempty"`
+	LinksByScheme         map[string]int   `json:"links_by_scheme,omitempty"`
+	HasLoginForm          bool             `json:"has_login_form"`
+	FormsTotal            int              `json:"forms_total"`
+	LoginForms            []LoginForm      `json:"login_forms,omitempty"`
+	Landmarks             map[string]int   `json:"landmarks"`
+	HasSkipNavLink        bool             `json:"has_skip_nav_link"`
+	InlineEventHandlers   int              `json:"inline_event_handlers"`
+	CommentCount          int              `json:"comment_count"`
+	ConditionalComments   int              `json:"conditional_comments"`
+	BlockingScripts       int              `json:"blocking_scripts"`
+	AsyncScripts          int              `json:"async_scripts"`
+	DeferScripts          int              `json:"defer_scripts"`
+	ResourceHints         []ResourceHint   `json:"resource_hints,omitempty"`
+	BrokenStylesheets     []string         `json:"broken_stylesheets,omitempty"`
+	ContentFingerprint    string           `json:"content_fingerprint,omitempty"`
+	ContentType           string           `json:"content_type,omitempty"`
+	PageSizeBytes         int              `json:"page_size_bytes,omitempty"`
+	GzippedSizeBytes      int              `json:"gzipped_size_bytes,omitempty"`
+	LastModified          *time.Time       `json:"last_modified,omitempty"`
+	PageAgeSeconds        int64            `json:"page_age_seconds,omitempty"`
+	Charset               string           `json:"charset,omitempty"`
+	Language              string           `json:"language,omitempty"`
+	TTFBMs                int64            `json:"ttfb_ms"`
+	HTTPProtocol          string           `json:"http_protocol,omitempty"`
+	FetchRetries          int              `json:"fetch_retries"`
+	FinalURL              string           `json:"final_url,omitempty"`
+	RedirectCount         int              `json:"redirect_count"`
+	TimingsMs             map[string]int64 `json:"timings,omitempty"`
+	Truncated             bool             `json:"truncated,omitempty"`
+	ResolvedIPs           []string         `json:"resolved_ips,omitempty"`
+	ReverseDNS            []string         `json:"reverse_dns,omitempty"`
+	TLSSubject            string           `json:"tls_subject,omitempty"`
+	TLSIssuer             string           `json:"tls_issuer,omitempty"`
+	TLSNotAfter           *time.Time       `json:"tls_not_after,omitempty"`
+	LikelyClientRendered  bool             `json:"likely_client_rendered"`
+	DOMTooDeep            bool             `json:"dom_too_deep,omitempty"`
+	MalformedHTML         bool             `json:"malformed_html,omitempty"`
+	MalformedHTMLDetails  []string         `json:"malformed_html_details,omitempty"`
+	MetaRefreshURL        string           `json:"meta_refresh_url,omitempty"`
+	MetaRefreshDelay      int              `json:"meta_refresh_delay,omitempty"`
+	MetaTagCount          int              `json:"meta_tag_count"`
+	MetaDescription       string           `json:"meta_description,omitempty"`
+	MetaRobots            []string         `json:"meta_robots,omitempty"`
+	CanonicalURL          string           `json:"canonical_url,omitempty"`
+	Warnings              []string         `json:"warnings,omitempty"`
+}
+
+type OutlineNode struct {
+	Level    int           `json:"level"`
+	Text     string        `json:"text"`
+	Children []OutlineNode `json:"children,omitempty"`
+}
+
+func newOutline(nodes []models.OutlineNode) []OutlineNode {
+	if len(nodes) == 0 {
+		return nil
+	}
+	outline := make([]OutlineNode, 0, len(nodes))
+	for _, n := range nodes {
+		outline = append(outline, OutlineNode{
+			Level:    n.Level,
+			Text:     n.Text,
+			Children: newOutline(n.Children),
+		})
+	}
+	return outline
+}
+
+type LinkRedirect struct {
+	SourceURL  string `json:"source_url"`
+	FinalURL   string `json:"final_url"`
+	StatusCode int    `json:"status_code"`
+}
+
+func newLinkRedirects(redirects []models.LinkRedirect) []LinkRedirect {
+	if len(redirects) == 0 {
+		return nil
+	}
+	response := make([]LinkRedirect, 0, len(redirects))
+	for _, r := range redirects {
+		response = append(response, LinkRedirect{SourceURL: r.SourceURL, FinalURL: r.FinalURL, StatusCode: r.StatusCode})
+	}
+	return response
+}
+
+// newTimings converts step durations to whole milliseconds
+func newTimings(timings map[string]time.Duration) map[string]int64 {
+	if len(timings) == 0 {
+		return nil
+	}
+	response := make(map[string]int64, len(timings))
+	for name, took := range timings {
+		response[name] = took.Milliseconds()
+	}
+	return response
+}
+
+type AnchorText struct {
+	URL  string `json:"url"`
+	Text string `json:"text"`
+}
+
+func newAnchorTexts(texts []models.AnchorText) []AnchorText {
+	if len(texts) == 0 {
+		return nil
+	}
+	response := make([]AnchorText, 0, len(texts))
+	for _, t := range texts {
+		response = append(response, AnchorText{URL: t.URL, Text: t.Text})
+	}
+	return response
+}
+
+// LoginForm is a form with a password field, Kind being "login" or
+// "registration"
+type LoginForm struct {
+	Action             string `json:"action,omitempty"`
+	Method             string `json:"method"`
+	Kind               string `json:"kind"`
+	HasEmail           bool   `json:"has_email"`
+	HasUsername        bool   `json:"has_username"`
+	HasPassword        bool   `json:"has_password"`
+	HasConfirmPassword bool   `json:"has_confirm_password"`
+}
+
+func newLoginForms(forms []models.FormInfo) []LoginForm {
+	if len(forms) == 0 {
+		return nil
+	}
+	response := make([]LoginForm, 0, len(forms))
+	for _, f := range forms {
+		response = append(response, LoginForm{
+			Action:             f.Action,
+			Method:             f.Method,
+			Kind:               f.Kind,
+			HasEmail:           f.HasEmail,
+			HasUsername:        f.HasUsername,
+			HasPassword:        f.HasPassword,
+			HasConfirmPassword: f.HasConfirmPassword,
+		})
+	}
+	return response
+}
+
+type ResourceHint struct {
+	URL    string `json:"url"`
+	Rel    string `json:"rel"`
+	As     string `json:"as,omitempty"`
+	Source string `json:"source"`
+}
+
+func newResourceHints(hints []models.ResourceHint) []ResourceHint {
+	if len(hints) == 0 {
+		return nil
+	}
+	response := make([]ResourceHint, 0, len(hints))
+	for _, h := range hints {
+		response = append(response, ResourceHint{URL: h.URL, Rel: h.Rel, As: h.As, Source: h.Source})
+	}
+	return response
+}
+
+func newWebPageAnalysisResponse(result *models.AnalysisResult) WebPageAnalysisResponse {
+	var tlsNotAfter *time.Time
+	if !result.TLSNotAfter.IsZero() {
+		tlsNotAfter = &result.TLSNotAfter
+	}
+	var lastModified *time.Time
+	if !result.LastModified.IsZero() {
+		lastModified = &result.LastModified
+	}
+
+	return WebPageAnalysisResponse{
+		HTMLVersion:           result.HTMLVersion,
+		Doctype:               result.Doctype,
+		Title:                 result.Title,
+		Headings:              result.Headings,
+		Outline:               newOutline(result.Outline),
+		ThinSections:          result.ThinSections,
+		TotalLinks:            result.TotalLinks,
+		UniqueLinks:           result.UniqueLinks,
+		InternalLinks:         result.InternalLinks,
+		ExternalLinks:         result.ExternalLinks,
+		SamePageLinks:         result.SamePageLinks,
+		AnchorLinks:           result.AnchorLinks,
+		InaccessibleLinks:     result.InaccessibleLinks,
+		SkippedLinks:          result.SkippedLinks,
+		RobotsSkippedLinks:    result.RobotsSkippedLinks,
+		RedirectingLinks:      newLinkRedirects(result.RedirectingLinks),
+		InsecureInternalLinks: result.InsecureInternalLinks,
+		AnchorTexts:           newAnchorTexts(result.AnchorTexts),
+		ImagesTotal:           result.ImagesTotal,
+		InaccessibleImages:    result.InaccessibleImages,
+		MailtoLinks:           result.MailtoLinks,
+		TelLinks:              result.TelLinks,
+		OtherSchemeLinks:      result.OtherSchemeLinks,
+		LinksByScheme:         result.LinksByScheme,
+		HasLoginForm:          result.HasLoginForm,
+		FormsTotal:            result.FormsTotal,
+		LoginForms:            newLoginForms(result.LoginForms),
+		Landmarks:             result.Landmarks,
+		HasSkipNavLink:        result.HasSkipNavLink,
+		InlineEventHandlers:   result.InlineEventHandlers,
+		CommentCount:          result.CommentCount,
+		ConditionalComments:   result.ConditionalComments,
+		BlockingScripts:       result.BlockingScripts,
+		AsyncScripts:          result.AsyncScripts,
+		DeferScripts:          result.DeferScripts,
+		ResourceHints:         newResourceHints(result.ResourceHints),
+		BrokenStylesheets:     result.BrokenStylesheets,
+		ContentFingerprint:    result.ContentFingerprint,
+		ContentType:           result.ContentType,
+		PageSizeBytes:         result.PageSizeBytes,
+		GzippedSizeBytes:      result.GzippedSizeBytes,
+		LastModified:          lastModified,
+		PageAgeSeconds:        int64(result.PageAge.Seconds()),
+		Charset:               result.Charset,
+		Language:              result.Language,
+		TTFBMs:                result.TTFBMs,
+		HTTPProtocol:          result.HTTPProtocol,
+		FetchRetries:          result.FetchRetries,
+		FinalURL:              result.FinalURL,
+		RedirectCount:         result.RedirectCount,
+		TimingsMs:             newTimings(result.Timings),
+		Truncated:             result.Truncated,
+		ResolvedIPs:           result.ResolvedIPs,
+		ReverseDNS:            result.ReverseDNS,
+		TLSSubject:            result.TLSSubject,
+		TLSIssuer:             result.TLSIssuer,
+		TLSNotAfter:           tlsNotAfter,
+		LikelyClientRendered:  result.LikelyClientRendered,
+		DOMTooDeep:            result.DOMTooDeep,
+		MalformedHTML:         result.MalformedHTML,
+		MalformedHTMLDetails:  result.MalformedHTMLDetails,
+		MetaRefreshURL:        result.MetaRefreshURL,
+		MetaRefreshDelay:      result.MetaRefreshDelay,
+		MetaTagCount:          result.MetaTagCount,
+		MetaDescription:       result.MetaDescription,
+		MetaRobots:            result.MetaRobots,
+		CanonicalURL:          result.CanonicalURL,
+		Warnings:              result.Warnings,
+	}
 }
 
 func (r *WebPageAnalysisRequest) Validate() error {
+	if problems := r.validationErrors(); len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// validationErrors lists every rule the request breaks, worded for display
+func (r *WebPageAnalysisRequest) validationErrors() []string {
+	var problems []string
 
 	if r.URL == "" {
-		return errors.New("url is empty")
+		problems = append(problems, "url is empty")
+	} else if problem := urlProblem("url", r.URL); problem != "" {
+		problems = append(problems, problem)
 	}
 
-	baseURL, err := url.Parse(r.URL)
-	if err != nil {
-		return errors.Wrap(err, `failed to parse url`)
+	if r.BaseURL != "" {
+		if problem := urlProblem("base_url", r.BaseURL); problem != "" {
+			problems = append(problems, problem)
+		}
 	}
 
-	if baseURL.Scheme != "http" && baseURL.Scheme != "https" {
-		return errors.New("url is invalid")
+	if r.FailOnBrokenLinks < 0 {
+		problems = append(problems, "fail_on_broken_links must not be negative")
 	}
 
-	return nil
+	if strings.ContainsFunc(r.BearerToken, unicode.IsSpace) {
+		problems = append(problems, "bearer_token must not contain whitespace")
+	}
+
+	if r.HostHeader != "" {
+		if u, err := url.Parse("http://" + r.HostHeader); err != nil || u.Host != r.HostHeader || u.Hostname() == "" {
+			problems = append(problems, "host_header is invalid")
+		}
+	}
+
+	if strings.ContainsFunc(r.UserAgent, unicode.IsControl) {
+		problems = append(problems, userAgentHeader+" must not contain control characters")
+	}
+
+	return problems
+}
+
+// urlProblem words why rawURL, sent as field, fails service.ParseURL, the
+// check the analysis itself applies. It is empty for a valid url.
+func urlProblem(field string, rawURL string) string {
+	_, err := service.ParseURL(rawURL)
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, service.ErrMissingHost):
+		return field + " has no host"
+	case errors.Is(err, service.ErrMalformedURL):
+		return field + " is malformed, it must not contain spaces or control characters"
+	default:
+		return field + " is invalid"
+	}
 }
 
-func NewWebPageAnalysisHandler(service *service.Analyzer, log *log.Logger) *WebPageAnalysisHandler {
-	return &WebPageAnalysisHandler{
-		service: service,
-		metrics: struct{}{},
-		log:     log,
+// analysisOptions maps the per-request settings onto the analyzer options
+func (r *WebPageAnalysisRequest) analysisOptions() models.AnalysisOptions {
+	return models.AnalysisOptions{
+		BaseURL:          r.BaseURL,
+		ResolveHost:      r.ResolveHost,
+		StrictHTML:       r.StrictHTML,
+		CheckStylesheets: r.CheckStylesheets,
+		PageSize:         r.PageSize,
+		Freshness:        r.Freshness,
+		AnchorText:       r.AnchorText,
+		BearerToken:      r.BearerToken,
+		HostHeader:       r.HostHeader,
+		UserAgent:        r.UserAgent,
 	}
 }
 
+func NewWebPageAnalysisHandler(service *service.Analyzer, log *log.Logger, opts ...WebPageAnalysisHandlerOption) *WebPageAnalysisHandler {
+	h := &WebPageAnalysisHandler{
+		service:  service,
+		metrics:  struct{}{},
+		log:      log,
+		redactor: newQueryRedactor(DefaultRedactedQueryParams),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
 func (h *WebPageAnalysisHandler) Handle(w http.ResponseWriter, r *http.Request) {
 
 	h.log.Debug(`analyze web page handler called`)
 
+	if r.Method == http.MethodPost && !isJSONContentType(r.Header.Get(`Content-Type`)) {
+		err := errors.New(fmt.Sprintf(`unsupported content type %q, send the request body as application/json`, r.Header.Get(`Content-Type`)))
+		h.log.WithError(err).Error(`unsupported request content type`)
+		sendError(w, `request body must be JSON`, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	format := r.URL.Query().Get(`format`)
+	if format != "" && format != formatJSON && format != formatFlat {
+		err := errors.New(fmt.Sprintf(`unsupported format %q, use %q or %q`, format, formatJSON, formatFlat))
+		sendError(w, `failed to validate request`, err, http.StatusBadRequest)
+		return
+	}
+	if format == "" {
+		format = formatJSON
+	}
+
 	var request WebPageAnalysisRequest
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+	if r.Method == http.MethodGet {
+		// GET only takes the target, every other setting keeps its default
+		request.URL = r.URL.Query().Get(`url`)
+	} else if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
 		h.log.WithError(err).Error(`failed to decode request body`)
 		sendError(w, `failed to decode request body`, err, http.StatusBadRequest)
 		return
 	}
+	request.UserAgent = r.Header.Get(userAgentHeader)
 
 	if err := request.Validate(); err != nil {
 		h.log.WithError(err).Error(`failed to validate request body`)
+		sendError(w, `failed to validate request body`, h.redactor.redactError(err), http.StatusBadRequest)
+		return
+	}
+
+	if request.BearerToken != "" && !h.bearerTokens {
+		err := errors.New(`bearer_token is not accepted by this server`)
 		sendError(w, `failed to validate request body`, err, http.StatusBadRequest)
 		return
 	}
 
-	result, err := h.service.Analyze(r.Context(), request.URL)
+	if wantsNDJSON(r) {
+		h.handleStream(w, r, request)
+		return
+	}
+
+	result, err := h.service.AnalyzeWithOptions(r.Context(), request.URL, request.analysisOptions())
 	if err != nil {
-		sendError(w, `failed to analyze web page`, err, result.StatusCode)
+		message, code := analysisError(err)
+		sendError(w, message, h.redactor.redactError(err), code)
 		return
 	}
 
-	response := WebPageAnalysisResponse{
-		HTMLVersion:       result.HTMLVersion,
-		Title:             result.Title,
-		Headings:          result.Headings,
-		InternalLinks:     result.InternalLinks,
-		ExternalLinks:     result.ExternalLinks,
-		InaccessibleLinks: result.InaccessibleLinks,
-		HasLoginForm:      result.HasLoginForm,
+	response := h.redactor.response(newWebPageAnalysisResponse(result))
+
+	statusCode := http.StatusOK
+	if request.FailOnBrokenLinks > 0 && result.InaccessibleLinks >= request.FailOnBrokenLinks {
+		statusCode = http.StatusUnprocessableEntity
 	}
 
-	w.Header().Set(`Content-Type`, `application/json`)
-	w.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(w).Encode(response)
+	contentType := `application/json`
+	var body []byte
+	if format == formatFlat {
+		contentType = flatContentType
+		body, err = flattenResponse(response)
+	} else {
+		body, err = json.Marshal(response)
+		if err == nil {
+			body = append(body, '\n')
+		}
+	}
 	if err != nil {
 		h.log.WithError(err).Error(`failed to encode response`)
 		sendError(w, `failed to encode response`, err, http.StatusInternalServerError)
 		return
 	}
+
+	if statusCode == http.StatusOK {
+		if h.cacheControl != "" {
+			w.Header().Set(`Cache-Control`, h.cacheControl)
+		}
+		if h.etag {
+			etag, err := responseETag(response, format)
+			if err != nil {
+				h.log.WithError(err).Error(`failed to compute etag`)
+				sendError(w, `failed to encode response`, err, http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set(`ETag`, etag)
+			if etagMatches(r.Header.Get(`If-None-Match`), etag) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+	}
+
+	w.Header().Set(`Content-Type`, contentType)
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(body); err != nil {
+		h.log.WithError(err).Error(`failed to write response`)
+	}
+}
+
+// isJSONContentType reports whether a Content-Type header names application/json,
+// ignoring parameters such as charset
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == `application/json`
 }
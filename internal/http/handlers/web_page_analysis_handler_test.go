@@ -0,0 +1,412 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"web_page_analyzer/internal/domain/adaptors"
+	"web_page_analyzer/internal/service"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebPageAnalysisHandlerFailOnBrokenLinks(t *testing.T) {
+	target := newLinkTargetServer(t)
+	page := `<html><body>
+		<a href="/ok">Fine</a>
+		<a href="/broken/one">Broken</a>
+		<a href="/broken/two">Broken</a>
+	</body></html>`
+	handler := NewWebPageAnalysisHandler(newTestAnalyzer(&stubWebClient{body: page, statusCode: http.StatusOK}), log.New())
+
+	tests := []struct {
+		name      string
+		threshold int
+		wantCode  int
+	}{
+		{name: "default never fails", threshold: 0, wantCode: http.StatusOK},
+		{name: "threshold met", threshold: 2, wantCode: http.StatusUnprocessableEntity},
+		{name: "threshold not met", threshold: 3, wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(WebPageAnalysisRequest{URL: target.URL, FailOnBrokenLinks: tt.threshold})
+			req := httptest.NewRequest(http.MethodPost, "/analyze", strings.NewReader(string(body)))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.Handle(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			var response WebPageAnalysisResponse
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+			assert.Equal(t, 2, response.InaccessibleLinks, "results are returned even when failing")
+			assert.Equal(t, 3, response.InternalLinks)
+		})
+	}
+}
+
+func TestWebPageAnalysisRequestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		request WebPageAnalysisRequest
+		wantErr string
+	}{
+		{name: "valid url", request: WebPageAnalysisRequest{URL: "https://example.com"}},
+		{name: "empty url", request: WebPageAnalysisRequest{}, wantErr: "url is empty"},
+		{name: "unsupported scheme", request: WebPageAnalysisRequest{URL: "ftp://example.com"}, wantErr: "url is invalid"},
+		{name: "url without host", request: WebPageAnalysisRequest{URL: "http:///path"}, wantErr: "url has no host"},
+		{name: "url with empty host", request: WebPageAnalysisRequest{URL: "https://"}, wantErr: "url has no host"},
+		{name: "url with space", request: WebPageAnalysisRequest{URL: "https://example.com/a page"}, wantErr: "url is malformed"},
+		{name: "url with control character", request: WebPageAnalysisRequest{URL: "https://example.com/\x7f"}, wantErr: "url is malformed"},
+		{name: "valid base url", request: WebPageAnalysisRequest{URL: "https://example.com", BaseURL: "https://archive.example.com/"}},
+		{name: "invalid base url", request: WebPageAnalysisRequest{URL: "https://example.com", BaseURL: "file:///tmp"}, wantErr: "base_url is invalid"},
+		{name: "base url without host", request: WebPageAnalysisRequest{URL: "https://example.com", BaseURL: "http:///docs/"}, wantErr: "base_url has no host"},
+		{name: "negative broken link threshold", request: WebPageAnalysisRequest{URL: "https://example.com", FailOnBrokenLinks: -1}, wantErr: "fail_on_broken_links must not be negative"},
+		{name: "host header", request: WebPageAnalysisRequest{URL: "http://203.0.113.7", HostHeader: "www.example.com:8443"}},
+		{name: "host header with path", request: WebPageAnalysisRequest{URL: "http://203.0.113.7", HostHeader: "www.example.com/admin"}, wantErr: "host_header is invalid"},
+		{name: "host header with whitespace", request: WebPageAnalysisRequest{URL: "http://203.0.113.7", HostHeader: "www.example.com\r\nX-Evil: 1"}, wantErr: "host_header is invalid"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.request.Validate()
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWebPageAnalysisHandlerETag(t *testing.T) {
+	target := newLinkTargetServer(t)
+	handler := NewWebPageAnalysisHandler(
+		newTestAnalyzer(&stubWebClient{body: `<html><head><title>Cached</title></head></html>`, statusCode: http.StatusOK}),
+		log.New(),
+		WithCacheControl("public, max-age=300"),
+		WithETag(),
+	)
+	body, _ := json.Marshal(WebPageAnalysisRequest{URL: target.URL})
+
+	send := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/analyze", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler.Handle(rec, req)
+		return rec
+	}
+
+	first := send("")
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, "public, max-age=300", first.Header().Get("Cache-Control"))
+	etag := first.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	cached := send(etag)
+	assert.Equal(t, http.StatusNotModified, cached.Code)
+	assert.Equal(t, etag, cached.Header().Get("ETag"))
+	assert.Empty(t, cached.Body.String())
+
+	stale := send(`"something-else"`)
+	assert.Equal(t, http.StatusOK, stale.Code)
+}
+
+func TestWebPageAnalysisHandlerETagPerFormat(t *testing.T) {
+	target := newLinkTargetServer(t)
+	handler := NewWebPageAnalysisHandler(
+		newTestAnalyzer(&stubWebClient{body: `<html><head><title>Formats</title></head></html>`, statusCode: http.StatusOK}),
+		log.New(),
+		WithETag(),
+	)
+
+	send := func(format string, ifNoneMatch string) *httptest.ResponseRecorder {
+		query := url.Values{"url": {target.URL}}
+		if format != "" {
+			query.Set("format", format)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/analyze?"+query.Encode(), nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rec := httptest.NewRecorder()
+		handler.Handle(rec, req)
+		return rec
+	}
+
+	jsonETag := send("", "").Header().Get("ETag")
+	assert.Equal(t, jsonETag, send("json", "").Header().Get("ETag"), "json is the default format")
+
+	flat := send("flat", jsonETag)
+	assert.Equal(t, http.StatusOK, flat.Code, "a JSON ETag must not validate the flat body")
+	assert.NotEqual(t, jsonETag, flat.Header().Get("ETag"))
+	assert.Equal(t, http.StatusNotModified, send("flat", flat.Header().Get("ETag")).Code)
+}
+
+// refetchedWebClient serves the same page on every fetch, with the details
+// that differ between fetches, like the time to first byte, changing each time
+type refetchedWebClient struct {
+	body    string
+	fetches atomic.Int32
+}
+
+func (c *refetchedWebClient) Do(ctx context.Context, url string, method string) (*adaptors.WebResponse, error) {
+	n := int(c.fetches.Add(1))
+	return &adaptors.WebResponse{
+		Body:       []byte(c.body),
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/html"}, "Age": {strconv.Itoa(60 * n)}},
+		TTFB:       time.Duration(n) * time.Millisecond,
+		Retries:    n,
+	}, nil
+}
+
+func TestWebPageAnalysisHandlerETagStableAcrossFetches(t *testing.T) {
+	target := newLinkTargetServer(t)
+	client := &refetchedWebClient{body: `<html><head><title>Unchanged</title></head><body><a href="/a">A</a></body></html>`}
+	handler := NewWebPageAnalysisHandler(service.NewAnalyzer(log.New(), client), log.New(), WithETag())
+	body, _ := json.Marshal(WebPageAnalysisRequest{URL: target.URL, Freshness: true})
+
+	var etags []string
+	for range 2 {
+		req := httptest.NewRequest(http.MethodPost, "/analyze", strings.NewReader(string(body)))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.Handle(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		etags = append(etags, rec.Header().Get("ETag"))
+	}
+
+	assert.NotEmpty(t, etags[0])
+	assert.Equal(t, etags[0], etags[1], "an unchanged page must keep its ETag")
+}
+
+func TestWebPageAnalysisHandlerRejectsNonJSON(t *testing.T) {
+	handler := NewWebPageAnalysisHandler(newTestAnalyzer(&stubWebClient{statusCode: http.StatusOK}), log.New())
+
+	tests := []struct {
+		name        string
+		contentType string
+		wantCode    int
+	}{
+		{name: "plain text", contentType: "text/plain", wantCode: http.StatusUnsupportedMediaType},
+		{name: "form encoded", contentType: "application/x-www-form-urlencoded", wantCode: http.StatusUnsupportedMediaType},
+		{name: "missing", contentType: "", wantCode: http.StatusUnsupportedMediaType},
+		{name: "json with charset", contentType: "application/json; charset=utf-8", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/analyze", strings.NewReader(`url=https://example.com`))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.Handle(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			var response ErrorResponse
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+			assert.Equal(t, tt.wantCode, response.Code)
+			if tt.wantCode == http.StatusUnsupportedMediaType {
+				assert.Contains(t, response.Error, "application/json")
+			}
+		})
+	}
+}
+
+func TestWebPageAnalysisHandlerStreamsNDJSON(t *testing.T) {
+	target := newLinkTargetServer(t)
+	page := `<html><head><title>Links</title></head><body>
+		<a href="/one">One</a>
+		<a href="/two">Two</a>
+		<a href="/broken">Broken</a>
+	</body></html>`
+	handler := NewWebPageAnalysisHandler(newTestAnalyzer(&stubWebClient{body: page, statusCode: http.StatusOK}), log.New())
+
+	body, _ := json.Marshal(WebPageAnalysisRequest{URL: target.URL})
+	req := httptest.NewRequest(http.MethodPost, "/analyze", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/x-ndjson")
+	rec := httptest.NewRecorder()
+
+	handler.Handle(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	assert.Len(t, lines, 5)
+	var events []StreamEvent
+	for _, line := range lines {
+		var event StreamEvent
+		assert.NoError(t, json.Unmarshal([]byte(line), &event), line)
+		events = append(events, event)
+	}
+
+	assert.Equal(t, "page", events[0].Type)
+	assert.Equal(t, http.StatusOK, events[0].Page.StatusCode)
+	broken := 0
+	for _, event := range events[1:4] {
+		assert.Equal(t, "link", event.Type)
+		if !event.Link.Accessible {
+			broken++
+		}
+	}
+	assert.Equal(t, 1, broken)
+	assert.Equal(t, "result", events[4].Type)
+	assert.Equal(t, "Links", events[4].Result.Title)
+	assert.Equal(t, 1, events[4].Result.InaccessibleLinks)
+}
+
+func TestWebPageAnalysisHandlerBearerToken(t *testing.T) {
+	const token = "s3cr3t-t0ken"
+	target := newLinkTargetServer(t)
+
+	tests := []struct {
+		name       string
+		client     *stubWebClient
+		opts       []WebPageAnalysisHandlerOption
+		wantCode   int
+		wantHeader string
+	}{
+		{
+			name:       "sent with the fetch",
+			client:     &stubWebClient{body: `<html><head><title>Gated</title></head></html>`, statusCode: http.StatusOK},
+			opts:       []WebPageAnalysisHandlerOption{WithBearerTokens()},
+			wantCode:   http.StatusOK,
+			wantHeader: "Bearer " + token,
+		},
+		{
+			name:       "kept out of failure logs",
+			client:     &stubWebClient{statusCode: http.StatusUnauthorized},
+			opts:       []WebPageAnalysisHandlerOption{WithBearerTokens()},
+			wantCode:   http.StatusBadGateway,
+			wantHeader: "Bearer " + token,
+		},
+		{
+			name:     "rejected unless enabled",
+			client:   &stubWebClient{statusCode: http.StatusOK},
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger, hook := test.NewNullLogger()
+			logger.SetLevel(log.DebugLevel)
+			globalHook := test.NewGlobal()
+			defer globalHook.Reset()
+			handler := NewWebPageAnalysisHandler(service.NewAnalyzer(logger, tt.client), logger, tt.opts...)
+
+			body, _ := json.Marshal(WebPageAnalysisRequest{URL: target.URL, BearerToken: token})
+			req := httptest.NewRequest(http.MethodPost, "/analyze", strings.NewReader(string(body)))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+
+			handler.Handle(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantHeader, tt.client.gotHeader.Get("Authorization"))
+			assert.NotContains(t, rec.Body.String(), token)
+			for _, entry := range append(hook.AllEntries(), globalHook.AllEntries()...) {
+				line, _ := entry.String()
+				assert.NotContains(t, line, token)
+			}
+		})
+	}
+}
+
+func TestWebPageAnalysisHandlerUserAgent(t *testing.T) {
+	target := newLinkTargetServer(t)
+	tests := []struct {
+		name     string
+		method   string
+		header   string
+		wantCode int
+		wantUA   string
+	}{
+		{name: "post", method: http.MethodPost, header: "preview-bot/2.0", wantCode: http.StatusOK, wantUA: "preview-bot/2.0"},
+		{name: "get", method: http.MethodGet, header: "preview-bot/2.0", wantCode: http.StatusOK, wantUA: "preview-bot/2.0"},
+		{name: "not set", method: http.MethodPost, wantCode: http.StatusOK},
+		{name: "control character", method: http.MethodPost, header: "bot\x00", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &stubWebClient{body: `<html></html>`, statusCode: http.StatusOK}
+			handler := NewWebPageAnalysisHandler(newTestAnalyzer(client), log.New())
+
+			req := httptest.NewRequest(http.MethodGet, "/analyze?url="+url.QueryEscape(target.URL), nil)
+			if tt.method == http.MethodPost {
+				body, _ := json.Marshal(WebPageAnalysisRequest{URL: target.URL})
+				req = httptest.NewRequest(http.MethodPost, "/analyze", strings.NewReader(string(body)))
+				req.Header.Set("Content-Type", "application/json")
+			}
+			if tt.header != "" {
+				req.Header.Set("X-Fetch-User-Agent", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.Handle(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.wantUA, client.gotHeader.Get("User-Agent"))
+		})
+	}
+}
+
+func TestWebPageAnalysisHandlerGet(t *testing.T) {
+	target := newLinkTargetServer(t)
+	page := `<html><head><title>Query</title></head><body><a href="/ok">Fine</a></body></html>`
+	handler := NewWebPageAnalysisHandler(newTestAnalyzer(&stubWebClient{body: page, statusCode: http.StatusOK}), log.New())
+
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantTitle string
+	}{
+		{name: "url param", query: "?url=" + url.QueryEscape(target.URL), wantCode: http.StatusOK, wantTitle: "Query"},
+		{name: "missing url param", query: "", wantCode: http.StatusBadRequest},
+		{name: "invalid url param", query: "?url=ftp://example.com", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/analyze"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			handler.Handle(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			if tt.wantCode != http.StatusOK {
+				var response ErrorResponse
+				assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+				assert.Equal(t, tt.wantCode, response.Code)
+				return
+			}
+			var response WebPageAnalysisResponse
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+			assert.Equal(t, tt.wantTitle, response.Title)
+			assert.Equal(t, 1, response.InternalLinks)
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"web_page_analyzer/internal/domain/models"
+)
+
+const ndjsonContentType = `application/x-ndjson`
+
+// StreamEvent is one line of an NDJSON analyze response. Type is "page" for
+// the fetched page metadata, "link" for each finished link check, then either
+// "result" with the full analysis or "error".
+type StreamEvent struct {
+	Type   string                   `json:"type"`
+	Page   *StreamPage              `json:"page,omitempty"`
+	Link   *StreamLink              `json:"link,omitempty"`
+	Result *WebPageAnalysisResponse `json:"result,omitempty"`
+	Error  string                   `json:"error,omitempty"`
+}
+
+type StreamPage struct {
+	URL          string `json:"url"`
+	StatusCode   int    `json:"status_code"`
+	ContentType  string `json:"content_type,omitempty"`
+	Charset      string `json:"charset,omitempty"`
+	TTFBMs       int64  `json:"ttfb_ms"`
+	HTTPProtocol string `json:"http_protocol,omitempty"`
+}
+
+type StreamLink struct {
+	URL        string `json:"url"`
+	Internal   bool   `json:"internal"`
+	StatusCode int    `json:"status_code,omitempty"`
+	Accessible bool   `json:"accessible"`
+	Skipped    bool   `json:"skipped,omitempty"`
+	SkipReason string `json:"skip_reason,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func wantsNDJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get(`Accept`), ndjsonContentType)
+}
+
+// handleStream writes the analysis as newline-delimited JSON, flushing each
+// link check as soon as it finishes
+func (h *WebPageAnalysisHandler) handleStream(w http.ResponseWriter, r *http.Request, request WebPageAnalysisRequest) {
+	// the controller unwraps the middleware recorders to reach the flusher
+	controller := http.NewResponseController(w)
+	encoder := json.NewEncoder(w)
+	started := false
+	write := func(event StreamEvent) {
+		if !started {
+			w.Header().Set(`Content-Type`, ndjsonContentType)
+			w.WriteHeader(http.StatusOK)
+			started = true
+		}
+		if err := encoder.Encode(event); err != nil {
+			h.log.WithError(err).Error(`failed to write stream event`)
+			return
+		}
+		if err := controller.Flush(); err != nil {
+			h.log.WithError(err).Debug(`failed to flush stream event`)
+		}
+	}
+
+	opts := request.analysisOptions()
+	opts.OnPageFetched = func(result *models.AnalysisResult) {
+		write(StreamEvent{Type: `page`, Page: &StreamPage{
+			URL:          h.redactor.url(request.URL),
+			StatusCode:   result.StatusCode,
+			ContentType:  result.ContentType,
+			Charset:      result.Charset,
+			TTFBMs:       result.TTFBMs,
+			HTTPProtocol: result.HTTPProtocol,
+		}})
+	}
+	opts.OnLinkCheck = func(check models.LinkCheck) {
+		link := &StreamLink{
+			URL:        h.redactor.url(check.URL),
+			Internal:   check.Internal,
+			StatusCode: check.StatusCode,
+			Accessible: check.Accessible,
+			Skipped:    check.Skipped,
+			SkipReason: check.SkipReason,
+		}
+		if check.Err != nil {
+			link.Error = h.redactor.text(check.Err.Error())
+		}
+		write(StreamEvent{Type: `link`, Link: link})
+	}
+
+	result, err := h.service.AnalyzeWithOptions(r.Context(), request.URL, opts)
+	if err != nil {
+		if !started {
+			message, code := analysisError(err)
+			sendError(w, message, h.redactor.redactError(err), code)
+			return
+		}
+		h.log.WithError(err).Error(`failed to analyze web page`)
+		write(StreamEvent{Type: `error`, Error: h.redactor.text(err.Error())})
+		return
+	}
+
+	response := h.redactor.response(newWebPageAnalysisResponse(result))
+	write(StreamEvent{Type: `result`, Result: &response})
+}
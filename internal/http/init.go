@@ -5,8 +5,10 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"web_page_analyzer/internal/application/config"
+	"web_page_analyzer/internal/service"
 
 	"github.com/go-chi/chi/v5"
 	log "github.com/sirupsen/logrus"
@@ -15,6 +17,11 @@ import (
 type Router struct {
 	httpRouter *chi.Mux
 	log        *log.Logger
+	appCfg     *config.AppConfig
+	// analyzer is the analyzer behind the API routes, set by initRoutes
+	analyzer *service.Analyzer
+	// handlerTimeout bounds the work of the analysis routes, zero for none
+	handlerTimeout time.Duration
 }
 
 func Init(ctx context.Context, log *log.Logger, appCfg *config.AppConfig) {
@@ -28,14 +35,23 @@ func Init(ctx context.Context, log *log.Logger, appCfg *config.AppConfig) {
 
 	chiRouter := chi.NewRouter()
 	router := &Router{
-		httpRouter: chiRouter,
-		log:        log,
+		httpRouter:     chiRouter,
+		log:            log,
+		appCfg:         appCfg,
+		handlerTimeout: cfg.Timeouts.Handler,
 	}
 
 	initRoutes(ctx, router)
 
+	// Self-test before any server starts, so a failure never reports ready
+	if appCfg.WarmupEnabled {
+		if err := runWarmup(ctx, log, router.analyzer, appCfg.WarmupURL, appCfg.WarmupTimeout); err != nil {
+			log.Fatalf(`Startup self-test failed: %v`, err)
+		}
+	}
+
 	// Create metrics server
-	MetricsServer := NewMetricsServer(appCfg.MetricsHost, cfg.Timeouts.ShutdownWait, log)
+	MetricsServer := NewMetricsServer(appCfg.MetricsHost, cfg.Timeouts.ShutdownWait, cfg.AuxTimeouts, log)
 	go MetricsServer.Start()
 
 	// Create HTTP server
@@ -43,7 +59,7 @@ func Init(ctx context.Context, log *log.Logger, appCfg *config.AppConfig) {
 	go httpServer.Start()
 
 	// Create pprof server (uses default http.DefaultServeMux)
-	pprofServer := NewPprofServer(":6060", cfg.Timeouts.ShutdownWait, log)
+	pprofServer := NewPprofServer(":6060", cfg.Timeouts.ShutdownWait, cfg.AuxTimeouts, log)
 	go pprofServer.Start()
 
 	<-sigs
@@ -8,8 +8,8 @@ import (
 	"web_page_analyzer/internal/pkg/errors"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"web_page_analyzer/internal/pkg/metrics"
 	log "github.com/sirupsen/logrus"
+	"web_page_analyzer/internal/pkg/metrics"
 )
 
 type MetricsServer struct {
@@ -19,7 +19,7 @@ type MetricsServer struct {
 	log     *log.Logger
 }
 
-func NewMetricsServer(host string, timeout time.Duration, log *log.Logger) *MetricsServer {
+func NewMetricsServer(host string, timeout time.Duration, serverTimeouts ServerTimeouts, log *log.Logger) *MetricsServer {
 	reg := metrics.MetricsRegister()
 
 	mux := http.NewServeMux()
@@ -27,8 +27,11 @@ func NewMetricsServer(host string, timeout time.Duration, log *log.Logger) *Metr
 
 	return &MetricsServer{
 		server: &http.Server{
-			Addr:    host,
-			Handler: mux,
+			Addr:              host,
+			Handler:           mux,
+			ReadTimeout:       serverTimeouts.Read,
+			ReadHeaderTimeout: serverTimeouts.ReadHeader,
+			WriteTimeout:      serverTimeouts.Write,
 		},
 		host:    host,
 		timeout: timeout,
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// ClientConcurrencyMiddleware caps how many requests run at once, both in
+// total and per client, so one client flooding the service queues behind its
+// own cap instead of taking every slot. A request waits for a per-client slot
+// before it competes for a global one. Zero or less leaves a limit unbounded.
+// Clients are keyed by remote IP. A request cancelled while it waits is
+// answered with 499.
+func ClientConcurrencyMiddleware(maxTotal int, perClient int) func(http.Handler) http.Handler {
+	l := &clientLimiter{perClient: perClient, clients: make(map[string]*clientSlots)}
+	if maxTotal > 0 {
+		l.global = make(chan struct{}, maxTotal)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			release, ok := l.acquire(r.Context(), clientKey(r))
+			if !ok {
+				writeError(w, r, `request was cancelled`, `request cancelled while waiting for an analysis slot`, StatusClientClosedRequest)
+				return
+			}
+			defer release()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type clientLimiter struct {
+	global    chan struct{}
+	perClient int
+
+	mu      sync.Mutex
+	clients map[string]*clientSlots
+}
+
+// clientSlots is a client's semaphore and how many requests hold or wait on
+// it, so idle clients can be forgotten
+type clientSlots struct {
+	sem   chan struct{}
+	users int
+}
+
+func (l *clientLimiter) acquire(ctx context.Context, key string) (func(), bool) {
+	releaseClient, ok := l.acquireClient(ctx, key)
+	if !ok {
+		return nil, false
+	}
+	if l.global == nil {
+		return releaseClient, true
+	}
+	select {
+	case l.global <- struct{}{}:
+		return func() {
+			<-l.global
+			releaseClient()
+		}, true
+	case <-ctx.Done():
+		releaseClient()
+		return nil, false
+	}
+}
+
+func (l *clientLimiter) acquireClient(ctx context.Context, key string) (func(), bool) {
+	if l.perClient <= 0 {
+		return func() {}, true
+	}
+
+	l.mu.Lock()
+	slots, ok := l.clients[key]
+	if !ok {
+		slots = &clientSlots{sem: make(chan struct{}, l.perClient)}
+		l.clients[key] = slots
+	}
+	slots.users++
+	l.mu.Unlock()
+
+	done := func() {
+		l.mu.Lock()
+		slots.users--
+		if slots.users == 0 {
+			delete(l.clients, key)
+		}
+		l.mu.Unlock()
+	}
+
+	select {
+	case slots.sem <- struct{}{}:
+		return func() {
+			<-slots.sem
+			done()
+		}, true
+	case <-ctx.Done():
+		done()
+		return nil, false
+	}
+}
+
+// clientKey identifies the client by remote IP, ignoring the port
+func clientKey(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"web_page_analyzer/internal/pkg/requestid"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientConcurrencyMiddlewareKeepsQuietClientsMoving(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	running := map[string]int{}
+	handler := ClientConcurrencyMiddleware(3, 1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		running[clientKey(r)]++
+		mu.Unlock()
+		if clientKey(r) == "10.0.0.1" {
+			<-release // the flooding client's analyses hang
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	// the noisy client fires many requests that never finish on their own
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodPost, "/analyze", nil)
+			req.RemoteAddr = "10.0.0.1:40000"
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	req := httptest.NewRequest(http.MethodPost, "/analyze", nil)
+	req.RemoteAddr = "10.0.0.2:50000"
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req.WithContext(ctx))
+
+	assert.Equal(t, http.StatusOK, rec.Code, "the quiet client gets a slot")
+	mu.Lock()
+	assert.Equal(t, 1, running["10.0.0.1"], "the noisy client is held to its own cap")
+	mu.Unlock()
+
+	close(release)
+	wg.Wait()
+}
+
+func TestClientConcurrencyMiddlewareGivesUpWhenCancelled(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	handler := ClientConcurrencyMiddleware(0, 1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+
+	first := httptest.NewRequest(http.MethodPost, "/analyze", nil)
+	go handler.ServeHTTP(httptest.NewRecorder(), first)
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	ctx = requestid.NewContext(ctx, "req-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/analyze", nil).WithContext(ctx))
+	assert.Equal(t, StatusClientClosedRequest, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var body errorResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, errorResponse{
+		Message:   "request was cancelled",
+		Error:     "request cancelled while waiting for an analysis slot",
+		Code:      StatusClientClosedRequest,
+		RequestID: "req-1",
+	}, body)
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// StatusClientClosedRequest is the non-standard status, popularized by nginx,
+// for a request the client gave up on before the response was ready
+const StatusClientClosedRequest = 499
+
+// errorResponse has the fields of handlers.ErrorResponse, which imports this
+// package, so a request rejected by a middleware gets the same JSON error as
+// any other
+type errorResponse struct {
+	Message   string `json:"message"`
+	Error     string `json:"error"`
+	Code      int    `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// writeError answers r with the JSON error envelope, tagged with its request
+// ID when it has one
+func writeError(w http.ResponseWriter, r *http.Request, message string, errMessage string, code int) {
+	reqID, _ := RequestIDFromContext(r.Context())
+	w.Header().Set(`Content-Type`, `application/json`)
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(errorResponse{
+		Message:   message,
+		Error:     errMessage,
+		Code:      code,
+		RequestID: reqID,
+	})
+}
@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -22,16 +21,7 @@ func MetricsMiddleware(next http.Handler) http.Handler {
 		if route == "" {
 			route = r.URL.Path
 		}
-		duration := time.Since(start).Seconds()
-
-		codeStr := strconv.Itoa(srw.status)
-		metrics.HTTPRequestsTotal.WithLabelValues(r.Method, route, codeStr).Inc()
-		metrics.HTTPRequestDuration.WithLabelValues(r.Method, route).Observe(duration)
-
-		// Increment error counter on 4xx or 5xx
-		if srw.status >= 400 {
-			metrics.HTTPRequestErrorsTotal.WithLabelValues(r.Method, route, codeStr).Inc()
-		}
+		metrics.ObserveHTTPRequest(r.Method, route, srw.status, time.Since(start))
 	})
 }
 
@@ -44,3 +34,9 @@ func (r *metricsStatusRecorder) WriteHeader(code int) {
 	r.status = code
 	r.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap exposes the underlying writer to http.ResponseController, so
+// streaming handlers can still flush
+func (r *metricsStatusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
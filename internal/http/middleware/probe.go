@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ProbeMiddleware answers health probes from recognized load balancer
+// User-Agents directly with probe, before any later middleware runs. It must
+// be registered first so frequent probes skip request logging and metrics.
+// userAgents are matched case-insensitively as substrings of the User-Agent.
+func ProbeMiddleware(userAgents []string, probe http.Handler, paths ...string) func(http.Handler) http.Handler {
+	probePaths := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		probePaths[p] = true
+	}
+	agents := make([]string, 0, len(userAgents))
+	for _, ua := range userAgents {
+		agents = append(agents, strings.ToLower(ua))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if probePaths[r.URL.Path] && isProbeUserAgent(r.UserAgent(), agents) {
+				probe.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isProbeUserAgent(userAgent string, agents []string) bool {
+	userAgent = strings.ToLower(userAgent)
+	for _, agent := range agents {
+		if agent != "" && strings.Contains(userAgent, agent) {
+			return true
+		}
+	}
+	return false
+}
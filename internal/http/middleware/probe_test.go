@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeMiddlewareSkipsLogging(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := ProbeMiddleware([]string{"kube-probe", "ELB-HealthChecker"}, ok, "/ready", "/healthz")(
+		RequestIDLoggerMiddleware(logger)(ok),
+	)
+
+	tests := []struct {
+		name       string
+		path       string
+		userAgent  string
+		wantLogged bool
+	}{
+		{name: "kubernetes probe", path: "/ready", userAgent: "kube-probe/1.29", wantLogged: false},
+		{name: "elb probe case insensitive", path: "/healthz", userAgent: "elb-healthchecker/2.0", wantLogged: false},
+		{name: "browser on ready", path: "/ready", userAgent: "Mozilla/5.0", wantLogged: true},
+		{name: "probe agent on other path", path: "/analyze", userAgent: "kube-probe/1.29", wantLogged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hook.Reset()
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("User-Agent", tt.userAgent)
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			if tt.wantLogged {
+				assert.Len(t, hook.AllEntries(), 1)
+			} else {
+				assert.Empty(t, hook.AllEntries())
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultRateLimitClients is how many client buckets are kept unless
+// RateLimitMiddleware is given another bound
+const defaultRateLimitClients = 10000
+
+// RateLimitMiddleware gives each client, keyed by remote IP, a token bucket
+// refilling at rps tokens a second and holding up to burst. A request without
+// a token is answered with 429 and a Retry-After header. Only the maxClients
+// most recently seen clients keep a bucket; a client evicted from it starts
+// over with a full one. Zero or less rps disables the limit.
+func RateLimitMiddleware(rps float64, burst int, maxClients int) func(http.Handler) http.Handler {
+	if rps <= 0 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	l := newRateLimiter(rps, burst, maxClients)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if ok, wait := l.allow(clientKey(r)); !ok {
+				seconds := retryAfterSeconds(wait)
+				w.Header().Set(`Retry-After`, strconv.Itoa(seconds))
+				writeError(w, r, `rate limit exceeded, retry later`,
+					fmt.Sprintf(`too many requests from this client, retry in %ds`, seconds), http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+type rateLimiter struct {
+	rps        float64
+	burst      float64
+	maxClients int
+	now        func() time.Time
+
+	mu sync.Mutex
+	// order holds the buckets, most recently used first
+	order   *list.List
+	clients map[string]*list.Element
+}
+
+type rateLimitBucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(rps float64, burst int, maxClients int) *rateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	if maxClients < 1 {
+		maxClients = defaultRateLimitClients
+	}
+	return &rateLimiter{
+		rps:        rps,
+		burst:      float64(burst),
+		maxClients: maxClients,
+		now:        time.Now,
+		order:      list.New(),
+		clients:    make(map[string]*list.Element),
+	}
+}
+
+// allow takes a token from key's bucket. When there is none it returns false
+// and how long until the next token.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	bucket := l.bucket(key, now)
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rps)
+	bucket.last = now
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - bucket.tokens) / l.rps * float64(time.Second))
+}
+
+// bucket returns key's bucket, creating a full one and evicting the least
+// recently used client when over maxClients. l.mu must be held.
+func (l *rateLimiter) bucket(key string, now time.Time) *rateLimitBucket {
+	if el, ok := l.clients[key]; ok {
+		l.order.MoveToFront(el)
+		return el.Value.(*rateLimitBucket)
+	}
+	bucket := &rateLimitBucket{key: key, tokens: l.burst, last: now}
+	l.clients[key] = l.order.PushFront(bucket)
+	if l.order.Len() > l.maxClients {
+		oldest := l.order.Back()
+		l.order.Remove(oldest)
+		delete(l.clients, oldest.Value.(*rateLimitBucket).key)
+	}
+	return bucket
+}
+
+// retryAfterSeconds rounds wait up to whole seconds, as Retry-After needs
+func retryAfterSeconds(wait time.Duration) int {
+	return max(1, int(math.Ceil(wait.Seconds())))
+}
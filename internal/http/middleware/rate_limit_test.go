@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitMiddlewareAllowsBurstThenRejects(t *testing.T) {
+	handler := RateLimitMiddleware(1, 3, 0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/analyze", nil)
+		req.RemoteAddr = remoteAddr
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, send("10.0.0.1:40000").Code, "request %d is within the burst", i+1)
+	}
+	rec := send("10.0.0.1:40001")
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var body errorResponse
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, http.StatusTooManyRequests, body.Code)
+	assert.Equal(t, "rate limit exceeded, retry later", body.Message)
+
+	assert.Equal(t, http.StatusOK, send("10.0.0.2:40000").Code, "other clients have their own bucket")
+}
+
+func TestRateLimiterRefills(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newRateLimiter(2, 2, 0)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		ok, _ := l.allow("client")
+		assert.True(t, ok)
+	}
+	ok, wait := l.allow("client")
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	now = now.Add(500 * time.Millisecond)
+	ok, _ = l.allow("client")
+	assert.True(t, ok, "one token refilled")
+	ok, _ = l.allow("client")
+	assert.False(t, ok)
+
+	now = now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		ok, _ = l.allow("client")
+		assert.True(t, ok, "the bucket refills up to the burst")
+	}
+	ok, _ = l.allow("client")
+	assert.False(t, ok, "and no further")
+}
+
+func TestRateLimiterEvictsLeastRecentlyUsedClients(t *testing.T) {
+	l := newRateLimiter(1, 1, 2)
+	l.now = func() time.Time { return time.Unix(0, 0) }
+
+	l.allow("a")
+	l.allow("b")
+	l.allow("a") // a is now the most recently used
+	l.allow("c") // evicts b
+
+	assert.Equal(t, 2, l.order.Len())
+	assert.Len(t, l.clients, 2)
+	assert.NotContains(t, l.clients, "b")
+
+	ok, _ := l.allow("a")
+	assert.False(t, ok, "a kept its empty bucket")
+	ok, _ = l.allow("b")
+	assert.True(t, ok, "b starts over with a full bucket")
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	assert.Equal(t, 1, retryAfterSeconds(0))
+	assert.Equal(t, 1, retryAfterSeconds(200*time.Millisecond))
+	assert.Equal(t, 2, retryAfterSeconds(1500*time.Millisecond))
+}
+
+func TestRateLimitMiddlewareDisabled(t *testing.T) {
+	handler := RateLimitMiddleware(0, 1, 0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for i := 0; i < 10; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/analyze", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+}
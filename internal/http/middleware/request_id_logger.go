@@ -7,19 +7,45 @@ import (
 	"net/http"
 	"runtime/debug"
 	"time"
+	"web_page_analyzer/internal/pkg/requestid"
 
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 )
 
-type ctxKeyRequestID struct{}
+type RequestLoggerOption func(*requestLoggerConfig)
 
-func RequestIDLoggerMiddleware(logger *log.Logger) func(http.Handler) http.Handler {
-	// configure global format once
-	logger.SetFormatter(&log.TextFormatter{
+type requestLoggerConfig struct {
+	formatter log.Formatter
+}
+
+// WithRequestLogFormatter writes the request log lines with formatter instead
+// of the logger's own. The logger passed to the middleware is not modified.
+func WithRequestLogFormatter(formatter log.Formatter) RequestLoggerOption {
+	return func(c *requestLoggerConfig) {
+		c.formatter = formatter
+	}
+}
+
+// TextRequestLogFormatter is the human readable request log format
+func TextRequestLogFormatter() log.Formatter {
+	return &log.TextFormatter{
 		TimestampFormat: time.RFC3339,
 		FullTimestamp:   true,
-	})
+	}
+}
+
+// RequestIDLoggerMiddleware tags each request with an x-request-id and logs
+// its outcome through logger, keeping logger's formatter unless
+// WithRequestLogFormatter says otherwise
+func RequestIDLoggerMiddleware(logger *log.Logger, opts ...RequestLoggerOption) func(http.Handler) http.Handler {
+	var cfg requestLoggerConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.formatter != nil {
+		logger = withFormatter(logger, cfg.formatter)
+	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -38,7 +64,7 @@ func RequestIDLoggerMiddleware(logger *log.Logger) func(http.Handler) http.Handl
 			}
 
 			w.Header().Set(`x-request-id`, reqID)
-			ctx := context.WithValue(r.Context(), ctxKeyRequestID{}, reqID)
+			ctx := requestid.NewContext(r.Context(), reqID)
 			srw := &requestIdStatusRecorder{ResponseWriter: w, status: http.StatusOK}
 
 			start := time.Now()
@@ -77,6 +103,19 @@ func RequestIDLoggerMiddleware(logger *log.Logger) func(http.Handler) http.Handl
 	}
 }
 
+// withFormatter returns a logger writing to the same output, at the same
+// level and with the same hooks as logger, but formatted by formatter
+func withFormatter(logger *log.Logger, formatter log.Formatter) *log.Logger {
+	return &log.Logger{
+		Out:          logger.Out,
+		Hooks:        logger.Hooks,
+		Formatter:    formatter,
+		ReportCaller: logger.ReportCaller,
+		Level:        logger.GetLevel(),
+		ExitFunc:     logger.ExitFunc,
+	}
+}
+
 // statusRecorder captures HTTP status codes
 type requestIdStatusRecorder struct {
 	http.ResponseWriter
@@ -87,3 +126,14 @@ func (r *requestIdStatusRecorder) WriteHeader(code int) {
 	r.status = code
 	r.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (r *requestIdStatusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// RequestIDFromContext returns the request ID RequestIDLoggerMiddleware put on
+// ctx. ok is false outside of it.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	return requestid.FromContext(ctx)
+}
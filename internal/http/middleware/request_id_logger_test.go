@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDLoggerMiddlewareKeepsFormatter(t *testing.T) {
+	var out bytes.Buffer
+	logger := log.New()
+	logger.SetOutput(&out)
+	formatter := &log.JSONFormatter{}
+	logger.SetFormatter(formatter)
+
+	handler := RequestIDLoggerMiddleware(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	assert.Same(t, formatter, logger.Formatter)
+
+	req := httptest.NewRequest(http.MethodGet, "/analyze", nil)
+	req.Header.Set("x-request-id", "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var line map[string]any
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &line), "request log line should be JSON: %s", out.String())
+	assert.Equal(t, "req-1", line["request_id"])
+	assert.Equal(t, "request completed", line["msg"])
+}
+
+func TestRequestIDLoggerMiddlewareWithFormatter(t *testing.T) {
+	var out bytes.Buffer
+	logger := log.New()
+	logger.SetOutput(&out)
+	formatter := &log.JSONFormatter{}
+	logger.SetFormatter(formatter)
+
+	handler := RequestIDLoggerMiddleware(logger, WithRequestLogFormatter(TextRequestLogFormatter()))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	assert.Same(t, formatter, logger.Formatter, "the shared logger must keep its formatter")
+
+	req := httptest.NewRequest(http.MethodGet, "/analyze", nil)
+	req.Header.Set("x-request-id", "req-2")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Contains(t, out.String(), `msg="request completed"`)
+	assert.Contains(t, out.String(), "request_id=req-2")
+}
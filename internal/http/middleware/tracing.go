@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"net/http"
+	"web_page_analyzer/internal/pkg/tracing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware starts the parent span for a request, continuing any
+// incoming trace context. It must run after RequestIDLoggerMiddleware so the
+// request id can be attached to the span.
+func TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Tracer().Start(ctx, r.Method+` `+r.URL.Path, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		if reqID, ok := RequestIDFromContext(r.Context()); ok {
+			span.SetAttributes(tracing.RequestIDKey.String(reqID))
+		}
+
+		srw := &metricsStatusRecorder{ResponseWriter: w}
+		next.ServeHTTP(srw, r.WithContext(ctx))
+		if srw.status == 0 {
+			srw.status = http.StatusOK
+		}
+
+		if route := chi.RouteContext(r.Context()); route != nil && route.RoutePattern() != "" {
+			span.SetName(r.Method + ` ` + route.RoutePattern())
+		}
+		span.SetAttributes(
+			attribute.String(`http.request.method`, r.Method),
+			attribute.Int(`http.response.status_code`, srw.status),
+		)
+		if srw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(srw.status))
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"web_page_analyzer/internal/pkg/tracing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestTracingMiddlewareTagsRequestID(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	var childParent trace.SpanContext
+	handler := RequestIDLoggerMiddleware(log.New())(TracingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, child := tracing.Tracer().Start(r.Context(), "child")
+		childParent = trace.SpanContextFromContext(r.Context())
+		child.End()
+		w.WriteHeader(http.StatusAccepted)
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/analyze", nil)
+	req.Header.Set("x-request-id", "req-123")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	assert.Len(t, spans, 2)
+	parent := spans[1]
+	assert.Equal(t, "GET /analyze", parent.Name)
+	assert.Contains(t, parent.Attributes, tracing.RequestIDKey.String("req-123"))
+	assert.Equal(t, parent.SpanContext.SpanID(), childParent.SpanID())
+}
@@ -17,11 +17,14 @@ type PprofServer struct {
 	log     *log.Logger
 }
 
-func NewPprofServer(host string, timeout time.Duration, log *log.Logger) *PprofServer {
+func NewPprofServer(host string, timeout time.Duration, serverTimeouts ServerTimeouts, log *log.Logger) *PprofServer {
 	return &PprofServer{
 		server: &http.Server{
-			Addr:    host,
-			Handler: nil,
+			Addr:              host,
+			Handler:           nil,
+			ReadTimeout:       serverTimeouts.Read,
+			ReadHeaderTimeout: serverTimeouts.ReadHeader,
+			WriteTimeout:      serverTimeouts.Write,
 		},
 		host:    host,
 		timeout: timeout,
@@ -0,0 +1,23 @@
+package http
+
+import (
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPprofServerTimeouts(t *testing.T) {
+	timeouts := ServerTimeouts{
+		Read:       3 * time.Second,
+		ReadHeader: 2 * time.Second,
+		Write:      45 * time.Second,
+	}
+
+	s := NewPprofServer(":0", time.Second, timeouts, log.New())
+
+	assert.Equal(t, timeouts.ReadHeader, s.server.ReadHeaderTimeout)
+	assert.Equal(t, timeouts.Read, s.server.ReadTimeout)
+	assert.Equal(t, timeouts.Write, s.server.WriteTimeout)
+}
@@ -2,17 +2,159 @@ package http
 
 import (
 	"context"
+	"net/http"
 	"time"
 	"web_page_analyzer/internal/adaptors"
 	"web_page_analyzer/internal/http/handlers"
 	"web_page_analyzer/internal/http/middleware"
 	"web_page_analyzer/internal/service"
+
+	"github.com/go-chi/chi/v5"
 )
 
 func initRoutes(_ context.Context, r *Router) {
+	readyHandler := handlers.NewReadyHandler()
+	healthPath := r.appCfg.HealthBasePath
+	r.httpRouter.Use(middleware.ProbeMiddleware(r.appCfg.ProbeUserAgents, http.HandlerFunc(readyHandler.Handle),
+		healthPath+"/ready", healthPath+"/healthz"))
 	r.httpRouter.Use(middleware.MetricsMiddleware)
-	r.httpRouter.Use(middleware.RequestIDLoggerMiddleware(r.log))
+	var requestLogOpts []middleware.RequestLoggerOption
+	if r.appCfg.RequestLogFormat == "text" {
+		requestLogOpts = append(requestLogOpts, middleware.WithRequestLogFormatter(middleware.TextRequestLogFormatter()))
+	}
+	r.httpRouter.Use(middleware.RequestIDLoggerMiddleware(r.log, requestLogOpts...))
+	r.httpRouter.Use(middleware.TracingMiddleware)
+	// set before mounting so the prefixed subrouters inherit them
+	r.httpRouter.NotFound(handlers.NotFound)
+	r.httpRouter.MethodNotAllowed(handlers.MethodNotAllowed)
+
+	analyzerOpts := []service.AnalyzerOption{
+		service.WithIgnoredQueryParams(r.appCfg.LinkIgnoredQueryParams...),
+		service.WithAnalyzerConcurrency(r.appCfg.AnalyzerConcurrency),
+		service.WithMaxDOMDepth(r.appCfg.MaxDOMDepth),
+		service.WithMaxMetaTags(r.appCfg.MaxMetaTags),
+		service.WithThinSectionWords(r.appCfg.ThinSectionWords),
+		service.WithAnchorTextLimits(r.appCfg.AnchorTextMaxChars, r.appCfg.AnchorTextMaxLinks),
+		service.WithResourceLimits(r.appCfg.MaxBodyBytes, r.appCfg.MaxDOMNodes),
+		service.WithMaxConcurrentBatches(r.appCfg.MaxConcurrentBatches, r.appCfg.BatchQueueTimeout),
+		service.WithBatchConcurrency(r.appCfg.BatchConcurrency),
+		// a batch runs many analyses, each gets the handler timeout instead of
+		// the whole batch sharing it
+		service.WithBatchPageTimeout(r.handlerTimeout),
+		service.WithCertExpiryWarning(r.appCfg.TLSExpiryWarning),
+		service.WithSkippedLinkHosts(r.appCfg.LinkCheckSkipHosts...),
+		service.WithLinkCheckConcurrency(r.appCfg.LinkCheckConcurrency),
+		service.WithLinkCheckTimeout(r.appCfg.LinkCheckTimeout),
+	}
+	if r.appCfg.LinkStripQueryStrings {
+		analyzerOpts = append(analyzerOpts, service.WithStripQueryStrings())
+	}
+	if r.appCfg.LinkSeparateSamePage {
+		analyzerOpts = append(analyzerOpts, service.WithSamePageLinks())
+	}
+	if r.appCfg.LinkCheckRedirects {
+		analyzerOpts = append(analyzerOpts, service.WithLinkRedirects())
+	}
+	if r.appCfg.LinkCheckRobotsTxt {
+		analyzerOpts = append(analyzerOpts, service.WithRobotsTxt())
+	}
+	if r.appCfg.ContentFingerprint {
+		analyzerOpts = append(analyzerOpts, service.WithContentFingerprint())
+	}
+	webClientOpts := []adaptors.WebClientOption{
+		adaptors.WithRetries(r.appCfg.FetchRetries),
+		adaptors.WithRetryBackoff(r.appCfg.FetchRetryBackoff, r.appCfg.FetchRetryJitter),
+		adaptors.WithMaxConcurrentLookups(r.appCfg.MaxConcurrentLookups),
+		adaptors.WithMaxBodyBytes(int64(r.appCfg.MaxBodyBytes)),
+		adaptors.WithMaxRedirects(r.appCfg.FetchMaxRedirects),
+		adaptors.WithUserAgent(r.appCfg.FetchUserAgent),
+	}
+	if r.appCfg.AcceptTruncatedBody {
+		webClientOpts = append(webClientOpts, adaptors.WithAcceptTruncatedBody())
+	}
+	if r.appCfg.ForwardRequestID {
+		webClientOpts = append(webClientOpts, adaptors.WithRequestIDForwarding())
+		analyzerOpts = append(analyzerOpts, service.WithRequestIDForwarding())
+	}
+	webClient := adaptors.NewWebClient(5*time.Second, r.log, webClientOpts...)
+	analyzerOpts = append(analyzerOpts, service.WithLinkCheckTransport(webClient.LinkCheckTransport()))
+	analyzer := service.NewAnalyzer(r.log, webClient, analyzerOpts...)
+	r.analyzer = analyzer
+
+	var analysisHandlerOpts []handlers.WebPageAnalysisHandlerOption
+	if r.appCfg.AnalyzeCacheControl != "" {
+		analysisHandlerOpts = append(analysisHandlerOpts, handlers.WithCacheControl(r.appCfg.AnalyzeCacheControl))
+	}
+	if r.appCfg.AnalyzeETag {
+		analysisHandlerOpts = append(analysisHandlerOpts, handlers.WithETag())
+	}
+	if len(r.appCfg.RedactQueryParams) > 0 {
+		analysisHandlerOpts = append(analysisHandlerOpts, handlers.WithRedactedQueryParams(r.appCfg.RedactQueryParams...))
+	}
+	if r.appCfg.AnalyzeBearerTokens {
+		analysisHandlerOpts = append(analysisHandlerOpts, handlers.WithBearerTokens())
+	}
+
+	analysisHandler := handlers.NewWebPageAnalysisHandler(analyzer, r.log, analysisHandlerOpts...)
+	batchHandlerOpts := []handlers.BatchAnalysisHandlerOption{
+		handlers.WithMaxBatchURLs(r.appCfg.BatchMaxURLs),
+	}
+	if len(r.appCfg.RedactQueryParams) > 0 {
+		batchHandlerOpts = append(batchHandlerOpts, handlers.WithBatchRedactedQueryParams(r.appCfg.RedactQueryParams...))
+	}
+	batchHandler := handlers.NewBatchAnalysisHandler(analyzer, r.log, batchHandlerOpts...)
+	htmlHandlerOpts := []handlers.HTMLAnalysisHandlerOption{
+		handlers.WithMaxHTMLBytes(r.appCfg.MaxBodyBytes),
+	}
+	if len(r.appCfg.RedactQueryParams) > 0 {
+		htmlHandlerOpts = append(htmlHandlerOpts, handlers.WithHTMLRedactedQueryParams(r.appCfg.RedactQueryParams...))
+	}
+	htmlHandler := handlers.NewHTMLAnalysisHandler(analyzer, r.log, htmlHandlerOpts...)
+	badgeHandlerOpts := []handlers.BadgeHandlerOption{
+		handlers.WithBadgeCacheTTL(r.appCfg.BadgeCacheTTL),
+	}
+	if len(r.appCfg.RedactQueryParams) > 0 {
+		badgeHandlerOpts = append(badgeHandlerOpts, handlers.WithBadgeRedactedQueryParams(r.appCfg.RedactQueryParams...))
+	}
+	badgeHandler := handlers.NewBadgeHandler(analyzer, r.log, badgeHandlerOpts...)
+
 	// Routes
-	r.httpRouter.Get("/ready", handlers.NewReadyHandler().Handle)
-	r.httpRouter.Post("/analyze", handlers.NewWebPageAnalysisHandler(service.NewAnalyzer(r.log, adaptors.NewWebClient(5*time.Second, r.log)), r.log).Handle)
+	healthRoutes := func(router chi.Router) {
+		router.Get("/ready", readyHandler.Handle)
+		router.Get("/healthz", readyHandler.Handle)
+	}
+	// the rate limit runs first so rejected requests never wait for a slot
+	rateLimit := middleware.RateLimitMiddleware(r.appCfg.RateLimitRPS, r.appCfg.RateLimitBurst, r.appCfg.RateLimitClients)
+	analysisLimit := middleware.ClientConcurrencyMiddleware(r.appCfg.MaxConcurrentAnalyses, r.appCfg.MaxClientAnalyses)
+	// the deadline starts once a slot is held, so it bounds the analysis itself
+	analysisTimeout := middleware.TimeoutMiddleware(r.handlerTimeout)
+	apiRoutes := func(router chi.Router) {
+		analysis := router.With(rateLimit, analysisLimit, analysisTimeout)
+		analysis.Post("/analyze", analysisHandler.Handle)
+		analysis.Get("/analyze", analysisHandler.Handle)
+		router.With(rateLimit, analysisLimit).Post("/analyze/batch", batchHandler.Handle)
+		analysis.Post("/analyze/html", htmlHandler.Handle)
+		analysis.Get("/badge", badgeHandler.Handle)
+		router.Post("/validate", handlers.NewValidateHandler(r.log).Handle)
+	}
+
+	// chi allows mounting a prefix only once
+	if healthPath == r.appCfg.BasePath {
+		mount(r.httpRouter, r.appCfg.BasePath, func(router chi.Router) {
+			healthRoutes(router)
+			apiRoutes(router)
+		})
+		return
+	}
+	mount(r.httpRouter, healthPath, healthRoutes)
+	mount(r.httpRouter, r.appCfg.BasePath, apiRoutes)
+}
+
+// mount registers routes under prefix, or at the root when prefix is empty
+func mount(router chi.Router, prefix string, routes func(chi.Router)) {
+	if prefix == "" {
+		router.Group(routes)
+		return
+	}
+	router.Route(prefix, routes)
 }
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"web_page_analyzer/internal/application/config"
+	"web_page_analyzer/internal/http/handlers"
+
+	"github.com/go-chi/chi/v5"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitRoutesBasePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.AppConfig
+		method   string
+		path     string
+		wantCode int
+	}{
+		{name: "analyze under prefix", cfg: config.AppConfig{BasePath: "/web-analyzer", HealthBasePath: "/web-analyzer"},
+			method: http.MethodPost, path: "/web-analyzer/analyze", wantCode: http.StatusUnsupportedMediaType},
+		{name: "analyze at root is gone", cfg: config.AppConfig{BasePath: "/web-analyzer", HealthBasePath: "/web-analyzer"},
+			method: http.MethodPost, path: "/analyze", wantCode: http.StatusNotFound},
+		{name: "health under prefix", cfg: config.AppConfig{BasePath: "/web-analyzer", HealthBasePath: "/web-analyzer"},
+			method: http.MethodGet, path: "/web-analyzer/ready", wantCode: http.StatusOK},
+		{name: "health kept at root", cfg: config.AppConfig{BasePath: "/web-analyzer"},
+			method: http.MethodGet, path: "/healthz", wantCode: http.StatusOK},
+		{name: "no prefix", cfg: config.AppConfig{},
+			method: http.MethodGet, path: "/ready", wantCode: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := &Router{httpRouter: chi.NewRouter(), log: log.New(), appCfg: &tt.cfg}
+			initRoutes(context.Background(), router)
+
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader("not json"))
+			req.Header.Set("Content-Type", "text/plain")
+			rec := httptest.NewRecorder()
+			router.httpRouter.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+		})
+	}
+}
+
+func TestInitRoutesUnmatchedRoutesReturnJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.AppConfig
+		method   string
+		path     string
+		wantCode int
+	}{
+		{name: "unknown path", method: http.MethodGet, path: "/nope", wantCode: http.StatusNotFound},
+		{name: "unknown path under prefix", cfg: config.AppConfig{BasePath: "/web-analyzer", HealthBasePath: "/web-analyzer"},
+			method: http.MethodGet, path: "/web-analyzer/nope", wantCode: http.StatusNotFound},
+		{name: "wrong method", method: http.MethodPut, path: "/analyze", wantCode: http.StatusMethodNotAllowed},
+		{name: "wrong method under prefix", cfg: config.AppConfig{BasePath: "/web-analyzer", HealthBasePath: "/web-analyzer"},
+			method: http.MethodDelete, path: "/web-analyzer/analyze", wantCode: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := &Router{httpRouter: chi.NewRouter(), log: log.New(), appCfg: &tt.cfg}
+			initRoutes(context.Background(), router)
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			req.Header.Set("x-request-id", "req-123")
+			rec := httptest.NewRecorder()
+			router.httpRouter.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			var response handlers.ErrorResponse
+			assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+			assert.Equal(t, tt.wantCode, response.Code)
+			assert.NotEmpty(t, response.Message)
+			assert.Equal(t, "req-123", response.RequestID)
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package http
+
+import (
+	"context"
+	"time"
+
+	"web_page_analyzer/internal/domain/models"
+	"web_page_analyzer/internal/pkg/errors"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// warmupAnalyzer is the part of the analyzer the startup self-test uses
+type warmupAnalyzer interface {
+	Analyze(ctx context.Context, url string) (*models.AnalysisResult, error)
+}
+
+// runWarmup analyzes url once as a startup self-test, failing when the
+// analysis errors or doesn't finish within timeout
+func runWarmup(ctx context.Context, log *log.Logger, analyzer warmupAnalyzer, url string, timeout time.Duration) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	if _, err := analyzer.Analyze(ctx, url); err != nil {
+		return errors.Wrap(err, `warmup analysis of `+url+` failed`)
+	}
+	log.Infof(`Warmup analysis of %s passed in %v`, url, time.Since(start))
+	return nil
+}
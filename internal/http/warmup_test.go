@@ -0,0 +1,54 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"web_page_analyzer/internal/domain/models"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubAnalyzer struct {
+	err   error
+	delay time.Duration
+}
+
+func (s stubAnalyzer) Analyze(ctx context.Context, url string) (*models.AnalysisResult, error) {
+	select {
+	case <-time.After(s.delay):
+		return &models.AnalysisResult{}, s.err
+	case <-ctx.Done():
+		return &models.AnalysisResult{}, ctx.Err()
+	}
+}
+
+func TestRunWarmup(t *testing.T) {
+	tests := []struct {
+		name      string
+		analyzer  stubAnalyzer
+		wantErr   bool
+		wantCause error
+	}{
+		{name: "passes", analyzer: stubAnalyzer{}},
+		{name: "analysis fails", analyzer: stubAnalyzer{err: errors.New("url is invalid")}, wantErr: true},
+		{name: "times out", analyzer: stubAnalyzer{delay: time.Second}, wantErr: true, wantCause: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runWarmup(context.Background(), log.New(), tt.analyzer, "http://example.com", 50*time.Millisecond)
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, "warmup analysis of http://example.com failed")
+			if tt.wantCause != nil {
+				assert.ErrorIs(t, err, tt.wantCause)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package metrics
 
 import (
 	"runtime"
+	"strconv"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -56,6 +57,65 @@ var (
 		},
 		[]string{"method", "code"},
 	)
+	HTTPClientRetriesTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_client_retries_total",
+			Help: "Total number of outbound HTTP requests retried, by reason.",
+		},
+		[]string{"reason"},
+	)
+
+	// --- Analysis metrics ---
+	AnalysisTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "analysis_total",
+			Help: "Total number of page analyses, by outcome (success, invalid_request, fetch_error, unsupported_content_type, parse_error, timeout, resource_limit, canceled, internal_error).",
+		},
+		[]string{"outcome"},
+	)
+	AnalysesInFlight = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "analyses_in_flight",
+			Help: "Number of page analyses currently running.",
+		},
+	)
+
+	AnalysisPageLinks = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "analysis_page_links",
+			Help:    "Links found per successfully analyzed page.",
+			Buckets: []float64{0, 10, 25, 50, 100, 250, 500, 1000, 2500},
+		},
+	)
+	AnalysisHTMLVersionTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "analysis_html_version_total",
+			Help: "Total number of successfully analyzed pages, by detected HTML version: HTML5, a known legacy version, other or unknown.",
+		},
+		[]string{"version"},
+	)
+	AnalysisLoginFormTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "analysis_login_form_total",
+			Help: "Total number of successfully analyzed pages, by whether they have a login form.",
+		},
+		[]string{"has_login_form"},
+	)
+	AnalysisInaccessibleLinkRatio = promauto.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "analysis_inaccessible_link_ratio",
+			Help:    "Share of checked links that were inaccessible, per successfully analyzed page with checked links.",
+			Buckets: []float64{0, 0.01, 0.05, 0.1, 0.25, 0.5, 0.75, 1},
+		},
+	)
+
+	// --- Batch metrics ---
+	BatchJobsInFlight = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "batch_jobs_in_flight",
+			Help: "Number of batch analyses currently running.",
+		},
+	)
 
 	// --- Runtime metrics ---
 	CPUCount = promauto.NewGaugeFunc(
@@ -79,8 +139,36 @@ func MetricsRegister() *prometheus.Registry {
 		HTTPClientRequestsTotal,
 		HTTPClientRequestDuration,
 		HTTPClientErrorsTotal,
+		HTTPClientRetriesTotal,
+		AnalysisTotal,
+		AnalysesInFlight,
+		AnalysisPageLinks,
+		AnalysisHTMLVersionTotal,
+		AnalysisLoginFormTotal,
+		AnalysisInaccessibleLinkRatio,
+		BatchJobsInFlight,
 		CPUCount,
 	)
 
 	return reg
 }
+
+// PageStats is what the page metrics record about a successful analysis
+type PageStats struct {
+	Links             int
+	HTMLVersion       string
+	HasLoginForm      bool
+	CheckedLinks      int
+	InaccessibleLinks int
+}
+
+// ObservePage records a successfully analyzed page. The inaccessible link
+// ratio is only observed when links were checked.
+func ObservePage(stats PageStats) {
+	AnalysisPageLinks.Observe(float64(stats.Links))
+	AnalysisHTMLVersionTotal.WithLabelValues(stats.HTMLVersion).Inc()
+	AnalysisLoginFormTotal.WithLabelValues(strconv.FormatBool(stats.HasLoginForm)).Inc()
+	if stats.CheckedLinks > 0 {
+		AnalysisInaccessibleLinkRatio.Observe(float64(stats.InaccessibleLinks) / float64(stats.CheckedLinks))
+	}
+}
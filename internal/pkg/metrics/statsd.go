@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"web_page_analyzer/internal/pkg/errors"
+)
+
+// statsdClient writes StatsD lines over UDP. Sends are fire-and-forget, so
+// an unreachable collector never slows a request down.
+type statsdClient struct {
+	conn   net.Conn
+	prefix string
+}
+
+var statsd atomic.Pointer[statsdClient]
+
+// InitStatsD mirrors the request and analysis metrics to the StatsD collector
+// at addr (host:port), with every metric name starting with prefix. With an
+// empty addr nothing is mirrored. The returned func stops mirroring.
+func InitStatsD(addr string, prefix string) (func() error, error) {
+	if addr == "" {
+		return func() error { return nil }, nil
+	}
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, errors.Wrap(err, `failed to dial statsd`)
+	}
+	statsd.Store(&statsdClient{conn: conn, prefix: strings.TrimSuffix(prefix, ".")})
+	return func() error {
+		statsd.Store(nil)
+		return conn.Close()
+	}, nil
+}
+
+// ObserveHTTPRequest records a served request: its count, its duration and,
+// for 4xx and 5xx responses, an error
+func ObserveHTTPRequest(method string, route string, code int, duration time.Duration) {
+	codeStr := strconv.Itoa(code)
+	HTTPRequestsTotal.WithLabelValues(method, route, codeStr).Inc()
+	HTTPRequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+	if code >= 400 {
+		HTTPRequestErrorsTotal.WithLabelValues(method, route, codeStr).Inc()
+	}
+
+	if c := statsd.Load(); c != nil {
+		c.send(statsdName(c.prefix, "http.requests", method, route, codeStr), "1|c")
+		c.send(statsdName(c.prefix, "http.request_duration", method, route), strconv.FormatInt(duration.Milliseconds(), 10)+"|ms")
+		if code >= 400 {
+			c.send(statsdName(c.prefix, "http.request_errors", method, route, codeStr), "1|c")
+		}
+	}
+}
+
+// CountAnalysis records the outcome of a page analysis
+func CountAnalysis(outcome string) {
+	AnalysisTotal.WithLabelValues(outcome).Inc()
+	if c := statsd.Load(); c != nil {
+		c.send(statsdName(c.prefix, "analysis", outcome), "1|c")
+	}
+}
+
+func (c *statsdClient) send(name string, value string) {
+	_, _ = c.conn.Write([]byte(name + ":" + value))
+}
+
+// statsdName joins the prefix, metric and label values into a dotted StatsD
+// name. Label values are lowercased and anything outside [a-z0-9_-] becomes
+// an underscore, so a route like /analyze yields "analyze".
+func statsdName(prefix string, metric string, labels ...string) string {
+	parts := make([]string, 0, len(labels)+2)
+	if prefix != "" {
+		parts = append(parts, prefix)
+	}
+	parts = append(parts, metric)
+	for _, label := range labels {
+		label = strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '-':
+				return r
+			case r >= 'A' && r <= 'Z':
+				return r + ('a' - 'A')
+			default:
+				return '_'
+			}
+		}, label)
+		if label = strings.Trim(label, "_"); label == "" {
+			label = "root"
+		}
+		parts = append(parts, label)
+	}
+	return strings.Join(parts, ".")
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestStatsDMirrorsMetrics(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	stop, err := InitStatsD(listener.LocalAddr().String(), "wpa.")
+	if err != nil {
+		t.Fatalf("InitStatsD: %v", err)
+	}
+	defer stop()
+
+	ObserveHTTPRequest(http.MethodPost, "/analyze", http.StatusBadGateway, 1500*time.Millisecond)
+	CountAnalysis("fetch_error")
+
+	want := []string{
+		"wpa.http.requests.post.analyze.502:1|c",
+		"wpa.http.request_duration.post.analyze:1500|ms",
+		"wpa.http.request_errors.post.analyze.502:1|c",
+		"wpa.analysis.fetch_error:1|c",
+	}
+	buf := make([]byte, 512)
+	for _, line := range want {
+		listener.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := listener.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("expected %q, read failed: %v", line, err)
+		}
+		if got := string(buf[:n]); got != line {
+			t.Errorf("got %q; want %q", got, line)
+		}
+	}
+}
+
+func TestStatsDDisabledByDefault(t *testing.T) {
+	stop, err := InitStatsD("", "wpa")
+	if err != nil {
+		t.Fatalf("InitStatsD: %v", err)
+	}
+	defer stop()
+	if statsd.Load() != nil {
+		t.Error("expected no statsd client without an address")
+	}
+	// must not panic without a collector
+	CountAnalysis("success")
+}
+
+func TestStatsDName(t *testing.T) {
+	cases := map[string]string{
+		statsdName("wpa", "http.requests", "GET", "/badge", "200"): "wpa.http.requests.get.badge.200",
+		statsdName("", "http.requests", "GET", "/", "200"):         "http.requests.get.root.200",
+		statsdName("wpa", "http.requests", "GET", "/api/{id}/x"):   "wpa.http.requests.get.api__id__x",
+	}
+	for got, want := range cases {
+		if got != want {
+			t.Errorf("got %q; want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package requestid
+
+import "context"
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx carrying the request ID id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx. ok is false when there
+// is none.
+func FromContext(ctx context.Context) (id string, ok bool) {
+	id, ok = ctx.Value(ctxKey{}).(string)
+	return id, ok && id != ""
+}
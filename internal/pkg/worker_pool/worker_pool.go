@@ -0,0 +1,191 @@
+package worker_pool
+
+import (
+	"context"
+	"sync"
+	"web_page_analyzer/internal/pkg/errors"
+)
+
+var ErrPoolStopped = errors.New("worker pool is stopped")
+
+// ErrQueueFull is returned by TrySubmit when no worker or queue slot is free
+var ErrQueueFull = errors.New("worker pool queue is full")
+
+// Task is a unit of work. ID is echoed back on the Result so callers can
+// correlate results that arrive out of order.
+type Task struct {
+	ID  int
+	Run func(ctx context.Context) (any, error)
+}
+
+type Result struct {
+	ID    int
+	Value any
+	Err   error
+}
+
+type WorkerPool struct {
+	numWorkers int
+	tasksCh    chan Task
+	ResultsCh  chan Result
+	wg         sync.WaitGroup
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+	// closing is closed by Drain to turn away new submissions. submitMu is
+	// held for reading while sending on tasksCh so Drain can close it safely.
+	closing      chan struct{}
+	submitMu     sync.RWMutex
+	closingOnce  sync.Once
+	shutdownOnce sync.Once
+}
+
+type Option func(*poolConfig)
+
+type poolConfig struct {
+	queueSize int
+}
+
+// WithQueueSize buffers up to n submitted tasks that no worker has picked up
+// yet. Without it a task is only accepted once a worker is free.
+func WithQueueSize(n int) Option {
+	return func(c *poolConfig) {
+		if n > 0 {
+			c.queueSize = n
+		}
+	}
+}
+
+func NewWorkerPool(ctx context.Context, numWorkers int, opts ...Option) *WorkerPool {
+	if numWorkers < 1 {
+		numWorkers = 1
+	}
+	var cfg poolConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &WorkerPool{
+		numWorkers: numWorkers,
+		tasksCh:    make(chan Task, cfg.queueSize),
+		ResultsCh:  make(chan Result, numWorkers),
+		ctx:        ctx,
+		cancelFunc: cancel,
+		closing:    make(chan struct{}),
+	}
+}
+
+func (wp *WorkerPool) Start() {
+	for i := 0; i < wp.numWorkers; i++ {
+		wp.wg.Add(1)
+		go wp.worker()
+	}
+}
+
+// Submit blocks until a worker accepts the task or the pool is stopped or
+// draining
+func (wp *WorkerPool) Submit(task Task) error {
+	wp.submitMu.RLock()
+	defer wp.submitMu.RUnlock()
+	if wp.isClosing() {
+		return ErrPoolStopped
+	}
+	select {
+	case <-wp.closing:
+		return ErrPoolStopped
+	case <-wp.ctx.Done():
+		return ErrPoolStopped
+	case wp.tasksCh <- task:
+		return nil
+	}
+}
+
+// TrySubmit hands the task to a free worker or queue slot, failing with
+// ErrQueueFull instead of blocking when there is none
+func (wp *WorkerPool) TrySubmit(task Task) error {
+	wp.submitMu.RLock()
+	defer wp.submitMu.RUnlock()
+	if wp.isClosing() || wp.ctx.Err() != nil {
+		return ErrPoolStopped
+	}
+	select {
+	case wp.tasksCh <- task:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// isClosing reports whether Drain has been called. While it is false and
+// submitMu is held, tasksCh is still open.
+func (wp *WorkerPool) isClosing() bool {
+	select {
+	case <-wp.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stop cancels the pool, waits for the workers to return and closes ResultsCh.
+// Tasks that have not been picked up yet, queued ones included, are dropped.
+func (wp *WorkerPool) Stop() {
+	wp.cancelFunc()
+	wp.shutdown()
+}
+
+// Drain turns away new submissions, lets the workers finish every task
+// already accepted, queued ones included, and then closes ResultsCh. Unlike
+// Stop it doesn't cancel the tasks' context. Callers must keep reading
+// ResultsCh until it is closed, and the pool must have been started.
+func (wp *WorkerPool) Drain() {
+	wp.closingOnce.Do(func() {
+		close(wp.closing)
+		// waits for submitters still sending on tasksCh
+		wp.submitMu.Lock()
+		close(wp.tasksCh)
+		wp.submitMu.Unlock()
+	})
+	wp.shutdown()
+	wp.cancelFunc()
+}
+
+// shutdown waits for the workers to return and closes ResultsCh once, so
+// Stop and Drain may both be called
+func (wp *WorkerPool) shutdown() {
+	wp.wg.Wait()
+	wp.shutdownOnce.Do(func() {
+		close(wp.ResultsCh)
+	})
+}
+
+// worker keeps pulling tasks until the pool is stopped or tasksCh is closed
+func (wp *WorkerPool) worker() {
+	defer wp.wg.Done()
+	for {
+		select {
+		case <-wp.ctx.Done():
+			return
+		case task, ok := <-wp.tasksCh:
+			if !ok {
+				return
+			}
+			value, err := wp.run(task)
+			select {
+			case wp.ResultsCh <- Result{ID: task.ID, Value: value, Err: err}:
+			case <-wp.ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+// run executes the task, reporting a panic as the task's error so a single
+// bad task doesn't kill the worker or the process
+func (wp *WorkerPool) run(task Task) (value any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			value, err = nil, errors.Errorf("task %d panicked: %v", task.ID, r)
+		}
+	}()
+	return task.Run(wp.ctx)
+}
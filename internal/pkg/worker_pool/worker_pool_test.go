@@ -0,0 +1,170 @@
+package worker_pool
+
+import (
+	"context"
+	"testing"
+	"time"
+	"web_page_analyzer/internal/pkg/errors"
+)
+
+func TestWorkerPoolProcessesMoreTasksThanWorkers(t *testing.T) {
+	const numTasks = 50
+	pool := NewWorkerPool(context.Background(), 4)
+	pool.Start()
+	defer pool.Stop()
+
+	go func() {
+		for i := 0; i < numTasks; i++ {
+			err := pool.Submit(Task{
+				ID: i,
+				Run: func(ctx context.Context) (any, error) {
+					return i * 2, nil
+				},
+			})
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	seen := make(map[int]bool, numTasks)
+	timeout := time.After(5 * time.Second)
+	for len(seen) < numTasks {
+		select {
+		case res := <-pool.ResultsCh:
+			if res.Err != nil {
+				t.Fatalf("task %d failed: %v", res.ID, res.Err)
+			}
+			if res.Value != res.ID*2 {
+				t.Fatalf("task %d returned %v, want %d", res.ID, res.Value, res.ID*2)
+			}
+			if seen[res.ID] {
+				t.Fatalf("task %d reported twice", res.ID)
+			}
+			seen[res.ID] = true
+		case <-timeout:
+			t.Fatalf("got %d of %d results before timing out", len(seen), numTasks)
+		}
+	}
+}
+
+func TestWorkerPoolTrySubmitRejectsWhenQueueFull(t *testing.T) {
+	pool := NewWorkerPool(context.Background(), 1, WithQueueSize(1))
+	pool.Start()
+	defer pool.Stop()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	blocking := Task{ID: 0, Run: func(ctx context.Context) (any, error) {
+		close(started)
+		<-release
+		return nil, nil
+	}}
+	// the worker may not be receiving yet, the queue slot takes it then
+	if err := pool.TrySubmit(blocking); err != nil {
+		t.Fatalf("TrySubmit with a free worker: %v", err)
+	}
+	<-started
+
+	queued := Task{ID: 1, Run: func(ctx context.Context) (any, error) { return nil, nil }}
+	if err := pool.TrySubmit(queued); err != nil {
+		t.Fatalf("TrySubmit with a free queue slot: %v", err)
+	}
+	rejected := Task{ID: 2, Run: func(ctx context.Context) (any, error) { return nil, nil }}
+	if err := pool.TrySubmit(rejected); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("TrySubmit on a full queue: got %v, want ErrQueueFull", err)
+	}
+
+	close(release)
+	for _, want := range []int{0, 1} {
+		select {
+		case res := <-pool.ResultsCh:
+			if res.ID != want {
+				t.Fatalf("got result %d, want %d", res.ID, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for result %d", want)
+		}
+	}
+}
+
+func TestWorkerPoolBufferedSubmit(t *testing.T) {
+	const queueSize = 3
+	pool := NewWorkerPool(context.Background(), 2, WithQueueSize(queueSize))
+	defer pool.Stop()
+
+	// nothing is running yet, so every task has to be queued
+	for i := 0; i < queueSize; i++ {
+		if err := pool.TrySubmit(Task{ID: i, Run: func(ctx context.Context) (any, error) { return i, nil }}); err != nil {
+			t.Fatalf("TrySubmit task %d: %v", i, err)
+		}
+	}
+	pool.Start()
+
+	seen := make(map[int]bool, queueSize)
+	for len(seen) < queueSize {
+		select {
+		case res := <-pool.ResultsCh:
+			if res.Value != res.ID {
+				t.Fatalf("task %d returned %v", res.ID, res.Value)
+			}
+			seen[res.ID] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got %d of %d results before timing out", len(seen), queueSize)
+		}
+	}
+}
+
+func TestWorkerPoolTrySubmitAfterStop(t *testing.T) {
+	pool := NewWorkerPool(context.Background(), 1, WithQueueSize(1))
+	pool.Start()
+	pool.Stop()
+
+	err := pool.TrySubmit(Task{ID: 0, Run: func(ctx context.Context) (any, error) { return nil, nil }})
+	if !errors.Is(err, ErrPoolStopped) {
+		t.Fatalf("got %v, want ErrPoolStopped", err)
+	}
+}
+
+func TestWorkerPoolDrainFinishesQueuedTasks(t *testing.T) {
+	const numTasks = 20
+	pool := NewWorkerPool(context.Background(), 3, WithQueueSize(numTasks))
+	pool.Start()
+
+	for i := 0; i < numTasks; i++ {
+		err := pool.Submit(Task{ID: i, Run: func(ctx context.Context) (any, error) {
+			time.Sleep(time.Millisecond)
+			return nil, ctx.Err()
+		}})
+		if err != nil {
+			t.Fatalf("Submit task %d: %v", i, err)
+		}
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		pool.Drain()
+		close(drained)
+	}()
+
+	seen := make(map[int]bool, numTasks)
+	for res := range pool.ResultsCh {
+		if res.Err != nil {
+			t.Fatalf("task %d failed: %v", res.ID, res.Err)
+		}
+		seen[res.ID] = true
+	}
+	<-drained
+	if len(seen) != numTasks {
+		t.Fatalf("got %d of %d results", len(seen), numTasks)
+	}
+
+	if err := pool.Submit(Task{ID: numTasks}); !errors.Is(err, ErrPoolStopped) {
+		t.Fatalf("Submit after Drain: got %v, want ErrPoolStopped", err)
+	}
+	if err := pool.TrySubmit(Task{ID: numTasks}); !errors.Is(err, ErrPoolStopped) {
+		t.Fatalf("TrySubmit after Drain: got %v, want ErrPoolStopped", err)
+	}
+	// Stop after Drain must not close ResultsCh again
+	pool.Stop()
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"net"
+	"web_page_analyzer/internal/domain/adaptors"
+	"web_page_analyzer/internal/pkg/errors"
+)
+
+// ErrorKind classifies why an analysis failed
+type ErrorKind int
+
+const (
+	// KindInvalidURL is a url, or base url, that isn't an absolute http(s) url
+	KindInvalidURL ErrorKind = iota + 1
+	// KindUnreachable is a page that could not be fetched at all
+	KindUnreachable
+	// KindUpstreamStatus is a page that answered with a status other than 200
+	KindUpstreamStatus
+	// KindParseFailed is a page whose body could not be parsed as HTML
+	KindParseFailed
+	// KindTimeout is a page that did not answer in time
+	KindTimeout
+	// KindResourceLimit is a page over the analyzer's resource limits
+	KindResourceLimit
+	// KindUnsupportedContentType is a page served as something other than HTML
+	KindUnsupportedContentType
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindInvalidURL:
+		return "invalid_url"
+	case KindUnreachable:
+		return "unreachable"
+	case KindUpstreamStatus:
+		return "upstream_status"
+	case KindParseFailed:
+		return "parse_failed"
+	case KindTimeout:
+		return "timeout"
+	case KindResourceLimit:
+		return "resource_limit"
+	case KindUnsupportedContentType:
+		return "unsupported_content_type"
+	default:
+		return "unknown"
+	}
+}
+
+// AnalyzeError is returned when an analysis fails for a reason the caller
+// can act on. Err keeps the cause, so sentinels like ErrInvalidURL and
+// ErrResourceLimit still match with errors.Is.
+type AnalyzeError struct {
+	Kind ErrorKind
+	// StatusCode is the page's status for KindUpstreamStatus
+	StatusCode int
+	Err        error
+}
+
+func (e *AnalyzeError) Error() string { return e.Err.Error() }
+
+func (e *AnalyzeError) Unwrap() error { return e.Err }
+
+// ErrorKindOf returns the kind of the AnalyzeError in err's chain. ok is
+// false when there is none, e.g. for a cancelled request.
+func ErrorKindOf(err error) (kind ErrorKind, ok bool) {
+	var analyzeErr *AnalyzeError
+	if errors.As(err, &analyzeErr) {
+		return analyzeErr.Kind, true
+	}
+	return 0, false
+}
+
+// IsTimeout reports whether err is a deadline running out, either the
+// context's or the transport's
+func IsTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// fetchErrorKind classifies a failed page fetch
+func fetchErrorKind(err error) ErrorKind {
+	switch {
+	case IsTimeout(err):
+		return KindTimeout
+	case errors.Is(err, adaptors.ErrResponseTooLarge):
+		return KindResourceLimit
+	default:
+		return KindUnreachable
+	}
+}
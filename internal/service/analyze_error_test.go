@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"web_page_analyzer/internal/domain/adaptors"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAnalyzeErrorKinds(t *testing.T) {
+	tests := []struct {
+		name           string
+		url            string
+		response       *adaptors.WebResponse
+		fetchErr       error
+		wantKind       ErrorKind
+		wantStatusCode int
+	}{
+		{name: "invalid url", url: "http:///path", response: htmlResponse(`<html></html>`), wantKind: KindInvalidURL},
+		{name: "unreachable", url: "http://example.com", fetchErr: errors.New("connection refused"), wantKind: KindUnreachable},
+		{name: "upstream status", url: "http://example.com", response: &adaptors.WebResponse{StatusCode: http.StatusNotFound},
+			wantKind: KindUpstreamStatus, wantStatusCode: http.StatusNotFound},
+		{name: "timeout", url: "http://example.com", fetchErr: context.DeadlineExceeded, wantKind: KindTimeout},
+		{name: "response too large", url: "http://example.com", fetchErr: adaptors.ErrResponseTooLarge, wantKind: KindResourceLimit},
+		{name: "resource limit", url: "http://example.com", response: htmlResponse(`<html><body>too big</body></html>`), wantKind: KindResourceLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockWebClient := new(MockWebClient)
+			mockWebClient.On("Do", mock.Anything, tt.url, http.MethodGet).Return(tt.response, tt.fetchErr)
+			analyzer := NewAnalyzer(log.New(), mockWebClient, WithResourceLimits(20, 0))
+
+			_, err := analyzer.Analyze(context.Background(), tt.url)
+
+			var analyzeErr *AnalyzeError
+			assert.ErrorAs(t, err, &analyzeErr)
+			assert.Equal(t, tt.wantKind, analyzeErr.Kind)
+			assert.Equal(t, tt.wantStatusCode, analyzeErr.StatusCode)
+			kind, ok := ErrorKindOf(err)
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantKind, kind)
+		})
+	}
+}
+
+func TestAnalyzeErrorKeepsCause(t *testing.T) {
+	_, err := ParseURL("https://")
+	assert.ErrorIs(t, err, ErrInvalidURL)
+
+	err = &AnalyzeError{Kind: KindResourceLimit, Err: ErrResourceLimit}
+	assert.ErrorIs(t, err, ErrResourceLimit)
+	assert.Equal(t, "resource_limit", KindResourceLimit.String())
+
+	_, ok := ErrorKindOf(context.Canceled)
+	assert.False(t, ok)
+}
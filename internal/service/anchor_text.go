@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"web_page_analyzer/internal/domain/models"
+
+	"golang.org/x/net/html"
+)
+
+// Anchor text is captured for the first defaultAnchorTextMaxLinks links and
+// cut at defaultAnchorTextMaxChars characters
+const (
+	defaultAnchorTextMaxChars = 100
+	defaultAnchorTextMaxLinks = 200
+)
+
+// WithAnchorTextLimits caps how many characters of each link's text are
+// captured and for how many links. Zero or less keeps the default.
+func WithAnchorTextLimits(maxChars, maxLinks int) AnalyzerOption {
+	return func(a *Analyzer) {
+		if maxChars > 0 {
+			a.anchorTextMaxChars = maxChars
+		}
+		if maxLinks > 0 {
+			a.anchorTextMaxLinks = maxLinks
+		}
+	}
+}
+
+func (a *Analyzer) analyzeAnchorTexts(ctx context.Context, result *models.AnalysisResult) error {
+	if facts, ok := ctx.Value(documentFactsKey{}).(*documentFacts); ok {
+		result.AnchorTexts = facts.anchorTexts
+		return nil
+	}
+	result.AnchorTexts = anchorTexts(ctx, result.HtmlNode, result.BaseUrl, a.anchorTextMaxChars, a.anchorTextMaxLinks)
+	return nil
+}
+
+// anchorTexts returns the text of the document's http(s) links in document
+// order, for at most maxLinks links. Text longer than maxChars characters is
+// cut and ends in an ellipsis.
+func anchorTexts(ctx context.Context, doc *html.Node, baseURL *url.URL, maxChars, maxLinks int) []models.AnchorText {
+	opts := walkOptions{anchorTextMaxChars: maxChars, anchorTextMaxLinks: maxLinks}
+	return walkDocument(ctx, doc, baseURL, opts).anchorTexts
+}
+
+// truncateText cuts s to maxChars characters, replacing the last with an
+// ellipsis when anything was cut
+func truncateText(s string, maxChars int) string {
+	runes := []rune(s)
+	if len(runes) <= maxChars {
+		return s
+	}
+	return string(runes[:maxChars-1]) + "…"
+}
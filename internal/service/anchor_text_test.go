@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"web_page_analyzer/internal/domain/models"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAnchorTexts(t *testing.T) {
+	baseURL, _ := url.Parse("https://example.com/")
+	doc := parseHTMLString(t, `<html><body>
+		<a href="/about">About <b>us</b></a>
+		<a href="mailto:me@example.com">Mail</a>
+		<a href="/long">`+strings.Repeat("é", 20)+`</a>
+		<a href="/exact">0123456789</a>
+		<a href="/past-the-cap">Never captured</a>
+	</body></html>`)
+
+	texts := anchorTexts(context.Background(), doc, baseURL, 10, 3)
+
+	assert.Equal(t, []models.AnchorText{
+		{URL: "https://example.com/about", Text: "About us"},
+		{URL: "https://example.com/long", Text: strings.Repeat("é", 9) + "…"},
+		{URL: "https://example.com/exact", Text: "0123456789"},
+	}, texts)
+}
+
+func TestAnalyzeTruncatesLongAnchorText(t *testing.T) {
+	htmlContent := `<html><body><a href="/essay">` + strings.Repeat("word ", 10000) + `</a></body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+	// keep link checks off the network
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithSkippedLinkHosts("example.com"), WithAnchorTextLimits(20, 0))
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), "http://example.com", models.AnalysisOptions{AnchorText: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.AnchorText{{URL: "http://example.com/essay", Text: "word word word word…"}}, result.AnchorTexts)
+
+	result, err = analyzer.Analyze(context.Background(), "http://example.com")
+	assert.NoError(t, err)
+	assert.Empty(t, result.AnchorTexts, "anchor text is only captured on request")
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"time"
+	"web_page_analyzer/internal/domain/models"
+	"web_page_analyzer/internal/pkg/errors"
+	"web_page_analyzer/internal/pkg/metrics"
+	"web_page_analyzer/internal/pkg/worker_pool"
+)
+
+// ErrTooManyBatches is returned when the concurrent batch ceiling is reached
+// and no slot frees up within the batch queue timeout
+var ErrTooManyBatches = errors.New(`too many concurrent batches`)
+
+// acquireBatchSlot claims one of the concurrent batch slots. The returned
+// release func must be called once the batch is done.
+func (a *Analyzer) acquireBatchSlot(ctx context.Context) (func(), error) {
+	if a.batchSlots != nil {
+		if err := a.waitBatchSlot(ctx); err != nil {
+			return nil, err
+		}
+	}
+	metrics.BatchJobsInFlight.Inc()
+	return func() {
+		metrics.BatchJobsInFlight.Dec()
+		if a.batchSlots != nil {
+			<-a.batchSlots
+		}
+	}, nil
+}
+
+// waitBatchSlot waits up to the batch queue timeout for a free slot
+func (a *Analyzer) waitBatchSlot(ctx context.Context) error {
+	select {
+	case a.batchSlots <- struct{}{}:
+		return nil
+	default:
+	}
+	if a.batchQueueTimeout <= 0 {
+		return ErrTooManyBatches
+	}
+
+	timer := time.NewTimer(a.batchQueueTimeout)
+	defer timer.Stop()
+	select {
+	case a.batchSlots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return ErrTooManyBatches
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// AnalyzeBatch analyzes the urls concurrently, bounded by the batch
+// concurrency, and returns one result per url in input order. Each page gets
+// the batch page timeout from when its analysis starts. It fails with
+// ErrTooManyBatches when the concurrent batch ceiling is reached.
+func (a *Analyzer) AnalyzeBatch(ctx context.Context, urls []string) ([]models.BatchResult, error) {
+	results := make([]models.BatchResult, len(urls))
+	if len(urls) == 0 {
+		return results, nil
+	}
+
+	release, err := a.acquireBatchSlot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	pool := worker_pool.NewWorkerPool(ctx, min(a.batchConcurrency, len(urls)))
+	pool.Start()
+	defer pool.Stop()
+
+	go func() {
+		for i, u := range urls {
+			// the task id carries the input index so results can be put back in order
+			err := pool.Submit(worker_pool.Task{
+				ID: i,
+				Run: func(ctx context.Context) (any, error) {
+					if a.batchPageTimeout > 0 {
+						var cancel context.CancelFunc
+						ctx, cancel = context.WithTimeout(ctx, a.batchPageTimeout)
+						defer cancel()
+					}
+					return a.Analyze(ctx, u)
+				},
+			})
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	done := make([]bool, len(urls))
+	for received := 0; received < len(urls); received++ {
+		select {
+		case <-ctx.Done():
+			for i := range results {
+				if !done[i] {
+					results[i] = models.BatchResult{URL: urls[i], Err: ctx.Err()}
+				}
+			}
+			return results, nil
+		case res := <-pool.ResultsCh:
+			analysis, _ := res.Value.(*models.AnalysisResult)
+			results[res.ID] = models.BatchResult{URL: urls[res.ID], Result: analysis, Err: res.Err}
+			done[res.ID] = true
+		}
+	}
+
+	return results, nil
+}
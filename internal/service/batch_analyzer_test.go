@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAnalyzeBatchPreservesInputOrder(t *testing.T) {
+	mockWebClient := new(MockWebClient)
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithBatchConcurrency(3))
+
+	pages := []struct {
+		url   string
+		title string
+		delay time.Duration
+	}{
+		{url: "http://slow.example.com", title: "Slow", delay: 80 * time.Millisecond},
+		{url: "http://fast.example.com", title: "Fast", delay: 0},
+		{url: "http://medium.example.com", title: "Medium", delay: 40 * time.Millisecond},
+		{url: "http://quick.example.com", title: "Quick", delay: 5 * time.Millisecond},
+	}
+
+	urls := make([]string, 0, len(pages))
+	for _, p := range pages {
+		urls = append(urls, p.url)
+		mockWebClient.On("Do", mock.Anything, p.url, http.MethodGet).
+			After(p.delay).
+			Return(htmlResponse("<html><head><title>"+p.title+"</title></head></html>"), nil)
+	}
+
+	results, err := analyzer.AnalyzeBatch(context.Background(), urls)
+
+	assert.NoError(t, err)
+	assert.Len(t, results, len(pages))
+	for i, p := range pages {
+		assert.Equal(t, p.url, results[i].URL)
+		assert.NoError(t, results[i].Err)
+		assert.Equal(t, p.title, results[i].Result.Title)
+	}
+}
+
+func TestAnalyzeBatchEmpty(t *testing.T) {
+	analyzer := NewAnalyzer(log.New(), new(MockWebClient))
+	results, err := analyzer.AnalyzeBatch(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestAnalyzeBatchRejectsOverCeiling(t *testing.T) {
+	mockWebClient := new(MockWebClient)
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithMaxConcurrentBatches(1, 20*time.Millisecond))
+
+	unblock := make(chan time.Time)
+	mockWebClient.On("Do", mock.Anything, "http://held.example.com", http.MethodGet).
+		WaitUntil(unblock).
+		Return(htmlResponse("<html></html>"), nil)
+	mockWebClient.On("Do", mock.Anything, "http://next.example.com", http.MethodGet).
+		Return(htmlResponse("<html></html>"), nil)
+
+	firstDone := make(chan error)
+	go func() {
+		_, err := analyzer.AnalyzeBatch(context.Background(), []string{"http://held.example.com"})
+		firstDone <- err
+	}()
+	assert.Eventually(t, func() bool { return len(analyzer.batchSlots) == 1 }, time.Second, time.Millisecond)
+
+	_, err := analyzer.AnalyzeBatch(context.Background(), []string{"http://next.example.com"})
+	assert.ErrorIs(t, err, ErrTooManyBatches)
+
+	close(unblock)
+	assert.NoError(t, <-firstDone)
+
+	// the slot is released once the first batch finishes
+	results, err := analyzer.AnalyzeBatch(context.Background(), []string{"http://next.example.com"})
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+}
+
+func TestAnalyzeBatchGivesEachPageItsOwnDeadline(t *testing.T) {
+	const pageTimeout = time.Second
+	mockWebClient := new(MockWebClient)
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithBatchConcurrency(1), WithBatchPageTimeout(pageTimeout))
+
+	var remaining []time.Duration
+	mockWebClient.On("Do", mock.Anything, mock.Anything, http.MethodGet).
+		Run(func(args mock.Arguments) {
+			deadline, ok := args.Get(0).(context.Context).Deadline()
+			assert.True(t, ok, "each page has a deadline")
+			remaining = append(remaining, time.Until(deadline))
+			time.Sleep(50 * time.Millisecond)
+		}).
+		Return(htmlResponse("<html></html>"), nil)
+
+	urls := []string{"http://one.example.com", "http://two.example.com", "http://three.example.com"}
+	results, err := analyzer.AnalyzeBatch(context.Background(), urls)
+	assert.NoError(t, err)
+	for _, result := range results {
+		assert.NoError(t, result.Err)
+	}
+	assert.Len(t, remaining, len(urls))
+	for i, left := range remaining {
+		// the pages before it don't eat into a page's deadline
+		assert.Greater(t, left, pageTimeout-40*time.Millisecond, "page %d", i)
+	}
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"strings"
+	"web_page_analyzer/internal/domain/models"
+)
+
+// FindBatchDuplicates flags the URLs of a batch that share an identical title
+// or meta description. Failed analyses and empty values are never grouped.
+func FindBatchDuplicates(results []models.BatchResult) models.BatchDuplicates {
+	return models.BatchDuplicates{
+		DuplicateTitleGroups: duplicateGroups(results, func(r *models.AnalysisResult) string {
+			return r.Title
+		}),
+		DuplicateDescriptionGroups: duplicateGroups(results, func(r *models.AnalysisResult) string {
+			return r.MetaDescription
+		}),
+	}
+}
+
+// duplicateGroups groups the URLs of results by the value key returns,
+// keeping only the groups with more than one URL. Groups are ordered by
+// where their first URL appears in results.
+func duplicateGroups(results []models.BatchResult, key func(*models.AnalysisResult) string) [][]string {
+	var order []string
+	byValue := make(map[string][]string)
+	for _, res := range results {
+		if res.Err != nil || res.Result == nil {
+			continue
+		}
+		value := strings.TrimSpace(key(res.Result))
+		if value == "" {
+			continue
+		}
+		if _, ok := byValue[value]; !ok {
+			order = append(order, value)
+		}
+		byValue[value] = append(byValue[value], res.URL)
+	}
+
+	var groups [][]string
+	for _, value := range order {
+		if len(byValue[value]) > 1 {
+			groups = append(groups, byValue[value])
+		}
+	}
+	return groups
+}
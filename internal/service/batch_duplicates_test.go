@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"web_page_analyzer/internal/domain/models"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFindBatchDuplicatesGroupsSharedTitles(t *testing.T) {
+	pages := map[string]string{
+		"http://example.com/a": `<html><head><title>Home</title><meta name="description" content="Welcome"></head></html>`,
+		"http://example.com/b": `<html><head><title>About</title><meta name="Description" content=" Welcome "></head></html>`,
+		"http://example.com/c": `<html><head><title>Home</title></head></html>`,
+		"http://example.com/d": `<html><head><title>Contact</title></head></html>`,
+	}
+	urls := []string{"http://example.com/a", "http://example.com/b", "http://example.com/c", "http://example.com/d"}
+	mockWebClient := new(MockWebClient)
+	for u, body := range pages {
+		mockWebClient.On("Do", mock.Anything, u, http.MethodGet).Return(htmlResponse(body), nil)
+	}
+
+	results, err := NewAnalyzer(log.New(), mockWebClient).AnalyzeBatch(context.Background(), urls)
+	assert.NoError(t, err)
+
+	duplicates := FindBatchDuplicates(results)
+	assert.Equal(t, [][]string{{"http://example.com/a", "http://example.com/c"}}, duplicates.DuplicateTitleGroups)
+	assert.Equal(t, [][]string{{"http://example.com/a", "http://example.com/b"}}, duplicates.DuplicateDescriptionGroups)
+}
+
+func TestFindBatchDuplicatesSkipsFailuresAndEmptyValues(t *testing.T) {
+	results := []models.BatchResult{
+		{URL: "http://example.com/a", Result: &models.AnalysisResult{Title: "Home"}},
+		{URL: "http://example.com/b", Result: &models.AnalysisResult{Title: "Home"}, Err: errors.New("fetch failed")},
+		{URL: "http://example.com/c", Result: &models.AnalysisResult{}},
+		{URL: "http://example.com/d", Result: &models.AnalysisResult{}},
+		{URL: "http://example.com/e"},
+	}
+
+	duplicates := FindBatchDuplicates(results)
+	assert.Empty(t, duplicates.DuplicateTitleGroups)
+	assert.Empty(t, duplicates.DuplicateDescriptionGroups)
+}
@@ -0,0 +1,21 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// countComments counts comment nodes and, among them, IE conditional comments
+func countComments(ctx context.Context, doc *html.Node) (comments int, conditional int) {
+	facts := walkDocument(ctx, doc, nil, walkOptions{})
+	return facts.comments, facts.conditionalComments
+}
+
+// isConditionalComment reports whether a comment is an IE conditional
+// comment. Both the downlevel-hidden (<!--[if IE]>) and downlevel-revealed
+// (<![if !IE]>) forms parse to comments whose data starts with "[if".
+func isConditionalComment(data string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(data)), "[if")
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountComments(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name            string
+		htmlStr         string
+		wantComments    int
+		wantConditional int
+	}{
+		{
+			name:    "no comments",
+			htmlStr: `<html><body><p>Hello</p></body></html>`,
+		},
+		{
+			name: "regular comments",
+			htmlStr: `<!-- top --><html><head><!-- meta --></head>
+				<body><!-- template: header --><p>Hi</p></body></html>`,
+			wantComments: 3,
+		},
+		{
+			name: "conditional comments",
+			htmlStr: `<html><head>
+				<!--[if IE]><link rel="stylesheet" href="ie.css"><![endif]-->
+				<!--[if lt IE 9]><script src="html5shiv.js"></script><![endif]-->
+				<![if !IE]><link rel="stylesheet" href="modern.css"><![endif]>
+				<!-- regular -->
+			</head><body></body></html>`,
+			wantComments:    5,
+			wantConditional: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			comments, conditional := countComments(ctx, parseHTMLString(t, tt.htmlStr))
+			assert.Equal(t, tt.wantComments, comments)
+			assert.Equal(t, tt.wantConditional, conditional)
+		})
+	}
+}
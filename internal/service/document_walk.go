@@ -0,0 +1,392 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"web_page_analyzer/internal/domain/models"
+
+	"golang.org/x/net/html"
+)
+
+// documentFactsKey carries the documentFacts of the page being analyzed to
+// the analysis steps
+type documentFactsKey struct{}
+
+// documentFacts is what a single walk of the document collects for the
+// steps that share it
+type documentFacts struct {
+	title    string
+	headings map[string]int
+	links    []linkInfo
+	// formsTotal counts the forms and loginForms describes the ones with a
+	// password field, in document order
+	formsTotal int
+	loginForms []models.FormInfo
+	// lang is the <html lang> attribute and metaCharset the charset declared
+	// by the first <meta> that declares one
+	lang        string
+	metaCharset string
+	// canonicalURL is the href of the first <link rel="canonical">, resolved
+	// against the base url when there is one
+	canonicalURL string
+	// images holds the http(s) <img src> URLs, each once, and imagesTotal
+	// how many images referenced them
+	images      []linkInfo
+	imagesTotal int
+	// linksByScheme tallies every anchor with an href by its scheme, see
+	// hrefScheme, whether or not it is an http(s) link
+	linksByScheme map[string]int
+	// metaTags holds the first <meta> elements in document order, up to the
+	// walk's maxMetaTags, and metaTagsTotal counts every one. Only the kept
+	// tags are inspected by the meta-based steps, so a page stuffed with meta
+	// tags can't make them collect without bound.
+	metaTags      []*html.Node
+	metaTagsTotal int
+	// comments counts the comment nodes and conditionalComments the IE
+	// conditional comments among them
+	comments            int
+	conditionalComments int
+	scripts             scriptCounts
+	landmarks           map[string]int
+	// inlineEventHandlers counts the elements with an on* attribute
+	inlineEventHandlers int
+	// headingTexts holds the headings in document order, leaving out those
+	// nested in another heading. sections holds the ones outside scripts,
+	// styles, noscript and templates with the words up to the next one.
+	headingTexts []headingText
+	sections     []section
+	// stylesheets holds the http(s) <link rel="stylesheet"> URLs, each once,
+	// and resourceHints the hints declared by <link> elements. Both are only
+	// collected when baseURL is set.
+	stylesheets   []linkInfo
+	resourceHints []models.ResourceHint
+	// anchorTexts holds the text of the first http(s) links, up to the walk's
+	// anchorTextMaxLinks
+	anchorTexts          []models.AnchorText
+	hasSkipNavLink       bool
+	likelyClientRendered bool
+	// normalizedLinks is links after the analyzer's link normalization, set
+	// by analyze so the link counts and link checks share one copy
+	normalizedLinks []linkInfo
+}
+
+// documentFactsFor returns the facts walked for result before the steps
+// started, walking the document itself when called outside an analysis
+func documentFactsFor(ctx context.Context, result *models.AnalysisResult) documentFacts {
+	if facts, ok := ctx.Value(documentFactsKey{}).(*documentFacts); ok {
+		return *facts
+	}
+	return walkDocument(ctx, result.HtmlNode, result.BaseUrl, walkOptions{})
+}
+
+// metaTagsFor returns the kept <meta> elements and how many the page has,
+// walking the document with the analyzer's cap when called outside an
+// analysis
+func (a *Analyzer) metaTagsFor(ctx context.Context, result *models.AnalysisResult) ([]*html.Node, int) {
+	if facts, ok := ctx.Value(documentFactsKey{}).(*documentFacts); ok {
+		return facts.metaTags, facts.metaTagsTotal
+	}
+	facts := walkDocument(ctx, result.HtmlNode, result.BaseUrl, a.walkOptions())
+	return facts.metaTags, facts.metaTagsTotal
+}
+
+// walkOptions bounds what walkDocument keeps
+type walkOptions struct {
+	// maxMetaTags caps the <meta> elements kept, zero or less keeps every one
+	maxMetaTags int
+	// anchorTextMaxLinks caps the links whose text is captured, cut at
+	// anchorTextMaxChars characters. Zero or less captures none.
+	anchorTextMaxChars int
+	anchorTextMaxLinks int
+}
+
+func (a *Analyzer) walkOptions() walkOptions {
+	return walkOptions{maxMetaTags: a.maxMetaTags}
+}
+
+// normalizedLinks returns the page's links after link normalization. Steps
+// must treat the slice as read-only, it is shared between them.
+func (a *Analyzer) normalizedLinks(ctx context.Context, result *models.AnalysisResult) []linkInfo {
+	if facts, ok := ctx.Value(documentFactsKey{}).(*documentFacts); ok {
+		return facts.normalizedLinks
+	}
+	return a.linkNormalization.apply(collectLinks(ctx, result.HtmlNode, result.BaseUrl))
+}
+
+// walkScope is what the walk knows about the ancestors of the node it visits
+type walkScope struct {
+	// skipLinks is set below anchors without a usable http(s) href, whose
+	// descendants have never been counted as links
+	skipLinks bool
+	// form is the innermost form around the node
+	form   *formFields
+	inHead bool
+	inBody bool
+	// inSection is set below the sectioningElements
+	inSection bool
+	inAnchor  bool
+	inHeading bool
+	// inRawText is set below script, style, noscript and template elements,
+	// whose content isn't visible page text
+	inRawText bool
+}
+
+// walkDocument descends root once, collecting everything the analysis steps
+// read from the tree: the title, headings and outline, links, images and
+// stylesheets, anchors by scheme, the forms, the document language, its
+// declared charset, its canonical url, its <meta> elements, scripts,
+// comments, landmarks and resource hints. Links, images and stylesheets are
+// only collected when baseURL is set. An input with a form attribute belongs
+// to the form it names rather than the one around it.
+func walkDocument(ctx context.Context, root *html.Node, baseURL *url.URL, opts walkOptions) documentFacts {
+	facts := documentFacts{
+		headings:      map[string]int{"h1": 0, "h2": 0, "h3": 0, "h4": 0, "h5": 0, "h6": 0},
+		linksByScheme: map[string]int{},
+		landmarks:     map[string]int{},
+	}
+	var titleFound bool
+	seenImages := map[string]bool{}
+	seenStylesheets := map[string]bool{}
+	var forms []*formFields
+	formsByID := map[string]*formFields{}
+	// inputs placed outside their form and linked back with the form
+	// attribute, which is also where error recovery can leave them
+	var ownedInputs []*html.Node
+	// the first <a href> and the ids and anchor names it may jump to
+	var firstLink *html.Node
+	fragmentTargets := map[string]bool{}
+	var (
+		bodyText        int
+		hasBundle       bool
+		emptyMountPoint bool
+	)
+
+	var traverse func(n *html.Node, scope walkScope)
+	traverse = func(n *html.Node, scope walkScope) {
+		switch n.Type {
+		case html.CommentNode:
+			facts.comments++
+			if isConditionalComment(n.Data) {
+				facts.conditionalComments++
+			}
+		case html.TextNode:
+			if scope.inRawText {
+				break
+			}
+			if scope.inBody {
+				bodyText += len(strings.TrimSpace(n.Data))
+			}
+			if !scope.inHeading && len(facts.sections) > 0 {
+				facts.sections[len(facts.sections)-1].words += len(strings.Fields(n.Data))
+			}
+		case html.ElementNode:
+			if hasInlineEventHandler(n) {
+				facts.inlineEventHandlers++
+			}
+			if role := landmarkRole(n, scope.inSection); role != "" {
+				facts.landmarks[role]++
+			}
+			if sectioningElements[n.Data] {
+				scope.inSection = true
+			}
+			if id := getAttr(n, "id"); id != "" {
+				fragmentTargets[id] = true
+			}
+
+			switch {
+			case n.Data == "html":
+				if facts.lang == "" {
+					facts.lang = strings.TrimSpace(getAttr(n, "lang"))
+				}
+			case n.Data == "head":
+				scope.inHead = true
+			case n.Data == "body":
+				scope.inBody = true
+			case n.Data == "meta":
+				if facts.metaCharset == "" {
+					facts.metaCharset = metaCharset(n)
+				}
+				facts.metaTagsTotal++
+				if opts.maxMetaTags <= 0 || len(facts.metaTags) < opts.maxMetaTags {
+					facts.metaTags = append(facts.metaTags, n)
+				}
+			case n.Data == "link":
+				rel := getAttr(n, "rel")
+				if facts.canonicalURL == "" && isCanonicalRel(rel) {
+					facts.canonicalURL = resolveHref(getAttr(n, "href"), baseURL)
+				}
+				facts.resourceHints = append(facts.resourceHints,
+					resourceHints(resolveHref(getAttr(n, "href"), baseURL), rel, getAttr(n, "as"), hintSourceHTML)...)
+				if baseURL == nil || !isStylesheetRel(rel) {
+					break
+				}
+				if stylesheet, ok := stylesheetFromLink(ctx, n, baseURL); ok && !seenStylesheets[stylesheet.url] {
+					seenStylesheets[stylesheet.url] = true
+					facts.stylesheets = append(facts.stylesheets, stylesheet)
+				}
+			case n.Data == "title":
+				if !titleFound && n.FirstChild != nil {
+					facts.title = n.FirstChild.Data
+					titleFound = true
+				}
+			case headingLevel(n.Data) > 0:
+				facts.headings[n.Data]++
+				if !scope.inHeading {
+					text := nodeText(n)
+					facts.headingTexts = append(facts.headingTexts, headingText{tag: n.Data, level: headingLevel(n.Data), text: text})
+					if !scope.inRawText {
+						facts.sections = append(facts.sections, section{heading: text})
+					}
+				}
+				scope.inHeading = true
+			case n.Data == "a":
+				if href := strings.TrimSpace(getHref(ctx, n)); href != "" {
+					if scheme, ok := hrefScheme(href); ok {
+						facts.linksByScheme[scheme]++
+					}
+				}
+				if firstLink == nil && getHref(ctx, n) != "" {
+					firstLink = n
+				}
+				if name := getAttr(n, "name"); name != "" {
+					fragmentTargets[name] = true
+				}
+				if baseURL != nil {
+					link, ok := linkFromAnchor(ctx, n, baseURL)
+					if !scope.skipLinks {
+						if ok {
+							facts.links = append(facts.links, link)
+						} else {
+							scope.skipLinks = true
+						}
+					}
+					// nested anchors are invalid and their text is already part of the outer one
+					if ok && !scope.inAnchor && len(facts.anchorTexts) < opts.anchorTextMaxLinks {
+						facts.anchorTexts = append(facts.anchorTexts, models.AnchorText{
+							URL:  link.url,
+							Text: truncateText(nodeText(n), opts.anchorTextMaxChars),
+						})
+					}
+				}
+				scope.inAnchor = true
+			case n.Data == "img":
+				if baseURL == nil {
+					break
+				}
+				if image, ok := imageFromSrc(ctx, n, baseURL); ok {
+					facts.imagesTotal++
+					if !seenImages[image.url] {
+						seenImages[image.url] = true
+						facts.images = append(facts.images, image)
+					}
+				}
+			case n.Data == "form":
+				scope.form = &formFields{node: n}
+				forms = append(forms, scope.form)
+				if id := getAttr(n, "id"); id != "" && formsByID[id] == nil {
+					formsByID[id] = scope.form
+				}
+			case n.Data == "input":
+				if getAttr(n, "form") != "" {
+					ownedInputs = append(ownedInputs, n)
+				} else if scope.form != nil {
+					scope.form.add(n)
+				}
+			case n.Data == "script":
+				classifyScript(n, scope.inHead, &facts.scripts)
+				hasBundle = hasBundle || (!scope.inRawText && isScriptBundle(n))
+				scope.inRawText = true
+			case n.Data == "style", n.Data == "noscript", n.Data == "template":
+				scope.inRawText = true
+			case n.Data == "app-root":
+				emptyMountPoint = emptyMountPoint || (!scope.inRawText && !hasElementChild(n))
+			case n.Data == "div":
+				if !scope.inRawText && spaMountPoints[getAttr(n, "id")] && !hasElementChild(n) {
+					emptyMountPoint = true
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			traverse(c, scope)
+		}
+	}
+	if root != nil {
+		traverse(root, walkScope{})
+	}
+
+	for _, input := range ownedInputs {
+		if form := formsByID[getAttr(input, "form")]; form != nil {
+			form.add(input)
+		}
+	}
+	facts.formsTotal = len(forms)
+	for _, form := range forms {
+		if form.passwords > 0 {
+			facts.loginForms = append(facts.loginForms, form.info(baseURL))
+		}
+	}
+	facts.hasSkipNavLink = jumpsToTarget(ctx, firstLink, fragmentTargets)
+	facts.likelyClientRendered = emptyMountPoint || (hasBundle && bodyText < clientRenderedMaxText)
+	return facts
+}
+
+func isCanonicalRel(rel string) bool {
+	for _, value := range strings.Fields(strings.ToLower(rel)) {
+		if value == "canonical" {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveHref resolves href against baseURL, keeping it as written when
+// there is no base url or it doesn't parse
+func resolveHref(href string, baseURL *url.URL) string {
+	href = strings.TrimSpace(href)
+	if href == "" || baseURL == nil {
+		return href
+	}
+	u, err := baseURL.Parse(href)
+	if err != nil {
+		return href
+	}
+	return u.String()
+}
+
+// linkFromAnchor resolves the href of anchor n against baseURL. ok is false
+// for a missing or unparsable href and for schemes other than http(s).
+func linkFromAnchor(ctx context.Context, n *html.Node, baseURL *url.URL) (linkInfo, bool) {
+	href := getHref(ctx, n)
+	if href == "" {
+		return linkInfo{}, false
+	}
+	absoluteURL, err := baseURL.Parse(href)
+	if err != nil {
+		return linkInfo{}, false
+	}
+	if absoluteURL.Scheme != "http" && absoluteURL.Scheme != "https" {
+		return linkInfo{}, false
+	}
+	isInternal := getCanonicalHost(ctx, absoluteURL) == getCanonicalHost(ctx, baseURL)
+	return linkInfo{
+		url:        absoluteURL.String(),
+		isInternal: isInternal,
+		samePage:   isSamePageHref(href),
+		anchor:     isAnchorHref(href),
+	}, true
+}
+
+// imageFromSrc resolves the src of image n against baseURL. ok is false for a
+// missing or unparsable src and for schemes other than http(s), like data:.
+func imageFromSrc(ctx context.Context, n *html.Node, baseURL *url.URL) (linkInfo, bool) {
+	src := strings.TrimSpace(getAttr(n, "src"))
+	if src == "" {
+		return linkInfo{}, false
+	}
+	u, err := baseURL.Parse(src)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return linkInfo{}, false
+	}
+	return linkInfo{url: u.String(), isInternal: getCanonicalHost(ctx, u) == getCanonicalHost(ctx, baseURL)}, true
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"web_page_analyzer/internal/domain/models"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWalkDocument(t *testing.T) {
+	doc := parseHTMLString(t, `<html><head><title>Docs</title></head><body>
+		<h1>Intro</h1><h2>Setup</h2><h2>Usage</h2>
+		<a href="/about">About</a>
+		<a href="mailto:team@example.com">Mail</a>
+		<a href="http://other.com/">Other</a>
+		<form id="login"></form>
+		<input type="password" form="login">
+	</body></html>`)
+	baseURL, _ := url.Parse("http://example.com/")
+
+	facts := walkDocument(context.Background(), doc, baseURL, walkOptions{})
+
+	assert.Equal(t, "Docs", facts.title)
+	assert.Equal(t, map[string]int{"h1": 1, "h2": 2, "h3": 0, "h4": 0, "h5": 0, "h6": 0}, facts.headings)
+	assert.Equal(t, []linkInfo{
+		{url: "http://example.com/about", isInternal: true},
+		{url: "http://other.com/", isInternal: false},
+	}, facts.links)
+	assert.Equal(t, 1, facts.formsTotal)
+	assert.Equal(t, []models.FormInfo{{Method: "get", Kind: models.FormKindLogin, HasPassword: true}},
+		facts.loginForms, "the password outside the form belongs to it")
+}
+
+func TestWalkDocumentCanonicalURL(t *testing.T) {
+	doc := parseHTMLString(t, `<html><head><link rel="alternate canonical" href="../guide?page=1"></head></html>`)
+	baseURL, _ := url.Parse("http://example.com/docs/intro")
+
+	assert.Equal(t, "http://example.com/guide?page=1", walkDocument(context.Background(), doc, baseURL, walkOptions{}).canonicalURL)
+	assert.Equal(t, "../guide?page=1", walkDocument(context.Background(), doc, nil, walkOptions{}).canonicalURL, "kept as written without a base url")
+}
+
+func TestWalkDocumentWithoutBaseURLSkipsLinks(t *testing.T) {
+	doc := parseHTMLString(t, `<html><body><a href="/about">About</a></body></html>`)
+
+	assert.Empty(t, walkDocument(context.Background(), doc, nil, walkOptions{}).links)
+}
+
+func TestWalkDocumentAnchorTexts(t *testing.T) {
+	doc := parseHTMLString(t, `<html><body>
+		<a href="/a">First</a>
+		<a href="/b">Second link</a>
+		<a href="/c">Third</a>
+	</body></html>`)
+	baseURL, _ := url.Parse("http://example.com/")
+
+	assert.Empty(t, walkDocument(context.Background(), doc, baseURL, walkOptions{}).anchorTexts, "not captured unless asked for")
+	assert.Equal(t, []models.AnchorText{
+		{URL: "http://example.com/a", Text: "First"},
+		{URL: "http://example.com/b", Text: "Seco…"},
+	}, walkDocument(context.Background(), doc, baseURL, walkOptions{anchorTextMaxChars: 5, anchorTextMaxLinks: 2}).anchorTexts)
+}
+
+func TestStepsReadPrecomputedFacts(t *testing.T) {
+	facts := &documentFacts{
+		title:                "Walked",
+		headings:             map[string]int{"h1": 3},
+		loginForms:           []models.FormInfo{{HasPassword: true}},
+		comments:             2,
+		conditionalComments:  1,
+		scripts:              scriptCounts{blocking: 1, async: 2, deferred: 3},
+		landmarks:            map[string]int{"main": 1},
+		inlineEventHandlers:  4,
+		headingTexts:         []headingText{{tag: "h1", level: 1, text: "Intro"}},
+		sections:             []section{{heading: "Intro", words: 1}},
+		resourceHints:        []models.ResourceHint{{URL: "http://example.com/app.js", Rel: "preload", Source: hintSourceHTML}},
+		anchorTexts:          []models.AnchorText{{URL: "http://example.com/", Text: "Home"}},
+		hasSkipNavLink:       true,
+		likelyClientRendered: true,
+	}
+	ctx := context.WithValue(context.Background(), documentFactsKey{}, facts)
+	// no document: the steps must not walk one themselves
+	result := &models.AnalysisResult{}
+	analyzer := NewAnalyzer(log.New(), nil, WithThinSectionWords(50))
+
+	for _, step := range []func(context.Context, *models.AnalysisResult) error{
+		analyzeTitle, analyzeHeadings, analyzeLoginForm, analyzeComments, analyzeScripts,
+		analyzeLandmarks, analyzeOutline, analyzeResourceHints, analyzeSkipNavLink, analyzeClientRendering,
+		analyzer.analyzeInlineEventHandlers, analyzer.analyzeThinSections, analyzer.analyzeAnchorTexts,
+	} {
+		assert.NoError(t, step(ctx, result))
+	}
+
+	assert.Equal(t, "Walked", result.Title)
+	assert.Equal(t, map[string]int{"h1": 3}, result.Headings)
+	assert.True(t, result.HasLoginForm)
+	assert.Equal(t, 2, result.CommentCount)
+	assert.Equal(t, 1, result.ConditionalComments)
+	assert.Equal(t, []int{1, 2, 3}, []int{result.BlockingScripts, result.AsyncScripts, result.DeferScripts})
+	assert.Equal(t, map[string]int{"main": 1}, result.Landmarks)
+	assert.Equal(t, 4, result.InlineEventHandlers)
+	assert.Equal(t, []models.OutlineNode{{Level: 1, Text: "Intro"}}, result.Outline)
+	assert.Equal(t, []string{"Intro"}, result.ThinSections)
+	assert.Equal(t, facts.resourceHints, result.ResourceHints)
+	assert.Equal(t, facts.anchorTexts, result.AnchorTexts)
+	assert.True(t, result.HasSkipNavLink)
+	assert.True(t, result.LikelyClientRendered)
+}
+
+func TestLinkStepsShareNormalizedLinks(t *testing.T) {
+	links := []linkInfo{{url: "http://example.com/", isInternal: true}, {url: "http://other.com/"}}
+	facts := &documentFacts{links: links, normalizedLinks: links}
+	ctx := context.WithValue(context.Background(), documentFactsKey{}, facts)
+	// no document: both steps must use the links walked before they started
+	result := &models.AnalysisResult{}
+	analyzer := NewAnalyzer(log.New(), nil, WithSkippedLinkHosts("example.com", "other.com"))
+
+	assert.NoError(t, analyzer.analyzeLinkCounts(ctx, result))
+	assert.NoError(t, analyzer.analyzeLinksAccessibility(ctx, result))
+
+	assert.Equal(t, 1, result.InternalLinks)
+	assert.Equal(t, 1, result.ExternalLinks)
+	assert.Equal(t, 2, result.SkippedLinks)
+}
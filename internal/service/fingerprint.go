@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"web_page_analyzer/internal/domain/models"
+)
+
+func (a *Analyzer) analyzeContentFingerprint(ctx context.Context, result *models.AnalysisResult) error {
+	facts := documentFactsFor(ctx, result)
+	result.ContentFingerprint = contentFingerprint(facts.title, facts.headingTexts, a.normalizedLinks(ctx, result))
+	return nil
+}
+
+// contentFingerprint hashes the title, the headings in document order and the
+// set of links. Text is whitespace-collapsed and links are sorted, so pages
+// that differ only in formatting or link order get the same fingerprint.
+func contentFingerprint(title string, headings []headingText, links []linkInfo) string {
+	var sb strings.Builder
+	sb.WriteString("title:")
+	sb.WriteString(strings.Join(strings.Fields(title), " "))
+	sb.WriteString("\n")
+	for _, heading := range headings {
+		sb.WriteString(heading.tag)
+		sb.WriteString(":")
+		sb.WriteString(heading.text)
+		sb.WriteString("\n")
+	}
+
+	urls := make([]string, 0, len(links))
+	for _, link := range links {
+		urls = append(urls, link.url)
+	}
+	slices.Sort(urls)
+	for _, u := range slices.Compact(urls) {
+		sb.WriteString("link:")
+		sb.WriteString(u)
+		sb.WriteString("\n")
+	}
+
+	sum := sha256.Sum256([]byte(sb.String()))
+	return hex.EncodeToString(sum[:])
+}
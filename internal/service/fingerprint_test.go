@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAnalyzeContentFingerprint(t *testing.T) {
+	pages := map[string]string{
+		"http://example.com/a": `<html><head><title>Release notes</title></head><body>
+			<h1>Version 2</h1><p>Changes</p><h2>Fixes</h2>
+			<a href="/docs">Docs</a><a href="https://other.com/">Other</a>
+		</body></html>`,
+		"http://example.com/b": `<html>
+			<head>
+				<title>  Release
+					notes </title>
+			</head>
+			<body>
+				<h1>
+					Version   2
+				</h1>
+				<p>Reworded changes</p>
+				<h2>Fixes</h2>
+				<a href="https://other.com/">Elsewhere</a>
+				<a href="/docs">Documentation</a>
+			</body>
+		</html>`,
+		"http://example.com/c": `<html><head><title>Release notes</title></head><body>
+			<h1>Version 3</h1><h2>Fixes</h2>
+			<a href="/docs">Docs</a><a href="https://other.com/">Other</a>
+		</body></html>`,
+	}
+	mockWebClient := new(MockWebClient)
+	for u, body := range pages {
+		mockWebClient.On("Do", mock.Anything, u, http.MethodGet).Return(htmlResponse(body), nil)
+	}
+	// keep link checks off the network
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithContentFingerprint(), WithSkippedLinkHosts("example.com", "other.com"))
+
+	fingerprints := make(map[string]string)
+	for u := range pages {
+		result, err := analyzer.Analyze(context.Background(), u)
+		assert.NoError(t, err)
+		assert.Len(t, result.ContentFingerprint, 64)
+		fingerprints[u] = result.ContentFingerprint
+	}
+
+	assert.Equal(t, fingerprints["http://example.com/a"], fingerprints["http://example.com/b"],
+		"whitespace and link order don't change the fingerprint")
+	assert.NotEqual(t, fingerprints["http://example.com/a"], fingerprints["http://example.com/c"])
+
+	result, err := NewAnalyzer(log.New(), mockWebClient, WithSkippedLinkHosts("example.com", "other.com")).
+		Analyze(context.Background(), "http://example.com/a")
+	assert.NoError(t, err)
+	assert.Empty(t, result.ContentFingerprint, "fingerprints are opt-in")
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"web_page_analyzer/internal/domain/models"
+
+	"golang.org/x/net/html"
+)
+
+func analyzeForms(ctx context.Context, result *models.AnalysisResult) error {
+	facts := documentFactsFor(ctx, result)
+	result.FormsTotal, result.LoginForms = facts.formsTotal, facts.loginForms
+	return nil
+}
+
+// formFields tallies the fields of one form
+type formFields struct {
+	node      *html.Node
+	email     bool
+	username  bool
+	passwords int
+}
+
+// classifyForms counts the document's forms and describes the ones with a
+// password field, in document order
+func classifyForms(ctx context.Context, doc *html.Node, baseURL *url.URL) (int, []models.FormInfo) {
+	facts := walkDocument(ctx, doc, baseURL, walkOptions{})
+	return facts.formsTotal, facts.loginForms
+}
+
+// add counts input towards the form's fields. Email and username fields are
+// recognized by their type, autocomplete hint, or name and id.
+func (f *formFields) add(input *html.Node) {
+	inputType := strings.ToLower(strings.TrimSpace(getAttr(input, "type")))
+	autocomplete := strings.ToLower(getAttr(input, "autocomplete"))
+	names := strings.ToLower(getAttr(input, "name") + " " + getAttr(input, "id"))
+	isText := inputType == "" || inputType == "text"
+	switch {
+	case inputType == "password":
+		f.passwords++
+	case inputType == "email" || strings.Contains(autocomplete, "email") || (isText && strings.Contains(names, "email")):
+		f.email = true
+	case isText && (strings.Contains(autocomplete, "username") || strings.Contains(names, "user") || strings.Contains(names, "login")):
+		f.username = true
+	}
+}
+
+func (f *formFields) info(baseURL *url.URL) models.FormInfo {
+	method := strings.ToLower(strings.TrimSpace(getAttr(f.node, "method")))
+	if method == "" {
+		method = "get"
+	}
+	info := models.FormInfo{
+		Action:             resolveHref(getAttr(f.node, "action"), baseURL),
+		Method:             method,
+		Kind:               models.FormKindLogin,
+		HasEmail:           f.email,
+		HasUsername:        f.username,
+		HasPassword:        f.passwords > 0,
+		HasConfirmPassword: f.passwords > 1,
+	}
+	if info.HasConfirmPassword || (info.HasEmail && info.HasUsername) {
+		info.Kind = models.FormKindRegistration
+	}
+	return info
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"web_page_analyzer/internal/domain/models"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestClassifyForms(t *testing.T) {
+	ctx := context.Background()
+	baseURL, _ := url.Parse("https://example.com/account/")
+
+	tests := []struct {
+		name      string
+		htmlStr   string
+		wantTotal int
+		wantForms []models.FormInfo
+	}{
+		{
+			name: "login form",
+			htmlStr: `<html><body><form action="/session" method="POST">
+				<input type="text" name="username">
+				<input type="password" name="password">
+			</form></body></html>`,
+			wantTotal: 1,
+			wantForms: []models.FormInfo{{Action: "https://example.com/session", Method: "post", Kind: models.FormKindLogin,
+				HasUsername: true, HasPassword: true}},
+		},
+		{
+			name: "registration form",
+			htmlStr: `<html><body><form action="signup" method="post">
+				<input type="email" name="email">
+				<input type="password" name="password" autocomplete="new-password">
+				<input type="password" name="password_confirmation">
+			</form></body></html>`,
+			wantTotal: 1,
+			wantForms: []models.FormInfo{{Action: "https://example.com/account/signup", Method: "post", Kind: models.FormKindRegistration,
+				HasEmail: true, HasPassword: true, HasConfirmPassword: true}},
+		},
+		{
+			name: "email and username register",
+			htmlStr: `<html><body><form>
+				<input name="user_login"><input type="text" id="signup-email"><input type="password">
+			</form></body></html>`,
+			wantTotal: 1,
+			wantForms: []models.FormInfo{{Method: "get", Kind: models.FormKindRegistration,
+				HasEmail: true, HasUsername: true, HasPassword: true}},
+		},
+		{
+			name:      "search form",
+			htmlStr:   `<html><body><form action="/search"><input type="search" name="q"><button>Go</button></form></body></html>`,
+			wantTotal: 1,
+		},
+		{
+			name: "login, registration and search",
+			htmlStr: `<html><body>
+				<form action="/search"><input type="search" name="q"></form>
+				<form id="login" action="/login" method="post"><input type="email" name="email"></form>
+				<input type="password" form="login">
+				<form action="/register" method="post">
+					<input type="text" name="username"><input type="password"><input type="password">
+				</form>
+			</body></html>`,
+			wantTotal: 3,
+			wantForms: []models.FormInfo{
+				{Action: "https://example.com/login", Method: "post", Kind: models.FormKindLogin, HasEmail: true, HasPassword: true},
+				{Action: "https://example.com/register", Method: "post", Kind: models.FormKindRegistration,
+					HasUsername: true, HasPassword: true, HasConfirmPassword: true},
+			},
+		},
+		{
+			name: "password owned by another form",
+			htmlStr: `<html><body>
+				<form id="other"></form>
+				<form><input type="password" form="other"></form>
+			</body></html>`,
+			wantTotal: 2,
+			wantForms: []models.FormInfo{{Method: "get", Kind: models.FormKindLogin, HasPassword: true}},
+		},
+		{
+			name:    "no forms",
+			htmlStr: `<html><body><input type="password"></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, forms := classifyForms(ctx, parseHTMLString(t, tt.htmlStr), baseURL)
+			assert.Equal(t, tt.wantTotal, total)
+			assert.Equal(t, tt.wantForms, forms)
+		})
+	}
+}
+
+func TestAnalyzeForms(t *testing.T) {
+	htmlContent := `<html><body>
+		<form action="/search"><input type="search" name="q"></form>
+		<form id="login" action="/login" method="post"><input name="username"></form>
+		<input type="password" name="password" form="login">
+		<form action="/join" method="post"><input type="email"><input type="password"><input type="password"></form>
+	</body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com/", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+
+	result, err := NewAnalyzer(log.New(), mockWebClient).Analyze(context.Background(), "http://example.com/")
+
+	assert.NoError(t, err)
+	assert.True(t, result.HasLoginForm, "agrees with LoginForms, even for a password outside its form")
+	assert.Equal(t, 3, result.FormsTotal)
+	if assert.Len(t, result.LoginForms, 2) {
+		assert.Equal(t, models.FormKindLogin, result.LoginForms[0].Kind)
+		assert.Equal(t, "http://example.com/login", result.LoginForms[0].Action)
+		assert.Equal(t, models.FormKindRegistration, result.LoginForms[1].Kind)
+	}
+}
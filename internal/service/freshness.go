@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"web_page_analyzer/internal/domain/models"
+)
+
+func analyzeFreshness(ctx context.Context, result *models.AnalysisResult) error {
+	result.LastModified, result.PageAge = pageFreshness(result.ResponseHeader, time.Now())
+	return nil
+}
+
+// pageFreshness reads the Last-Modified time and the age of the response from
+// header. The age is the larger of the Age header and how long ago the Date
+// header says the response was generated, as in RFC 9111 section 4.2.3.
+// Missing or malformed headers leave the zero value.
+func pageFreshness(header http.Header, now time.Time) (time.Time, time.Duration) {
+	var lastModified time.Time
+	if value := header.Get("Last-Modified"); value != "" {
+		if t, err := http.ParseTime(value); err == nil {
+			lastModified = t
+		}
+	}
+
+	var age time.Duration
+	if value := header.Get("Date"); value != "" {
+		if date, err := http.ParseTime(value); err == nil && now.After(date) {
+			age = now.Sub(date).Truncate(time.Second)
+		}
+	}
+	if value := strings.TrimSpace(header.Get("Age")); value != "" {
+		if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds >= 0 {
+			age = max(age, time.Duration(seconds)*time.Second)
+		}
+	}
+	return lastModified, age
+}
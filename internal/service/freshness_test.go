@@ -0,0 +1,57 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPageFreshness(t *testing.T) {
+	now := time.Date(2024, time.March, 10, 12, 0, 0, 0, time.UTC)
+	lastModified := time.Date(2024, time.March, 1, 8, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		header           http.Header
+		wantLastModified time.Time
+		wantAge          time.Duration
+	}{
+		{name: "no headers", header: http.Header{}},
+		{
+			name:             "last modified in RFC 1123",
+			header:           http.Header{"Last-Modified": {"Fri, 01 Mar 2024 08:30:00 GMT"}},
+			wantLastModified: lastModified,
+		},
+		{
+			name:             "last modified in RFC 850",
+			header:           http.Header{"Last-Modified": {"Friday, 01-Mar-24 08:30:00 GMT"}},
+			wantLastModified: lastModified,
+		},
+		{
+			name:             "last modified in ANSI C asctime",
+			header:           http.Header{"Last-Modified": {"Fri Mar  1 08:30:00 2024"}},
+			wantLastModified: lastModified,
+		},
+		{name: "age header", header: http.Header{"Age": {"120"}}, wantAge: 2 * time.Minute},
+		{name: "date header", header: http.Header{"Date": {"Sun, 10 Mar 2024 11:55:00 GMT"}}, wantAge: 5 * time.Minute},
+		{
+			name:    "larger of age and date",
+			header:  http.Header{"Date": {"Sun, 10 Mar 2024 11:59:00 GMT"}, "Age": {"300"}},
+			wantAge: 5 * time.Minute,
+		},
+		{
+			name:   "malformed headers",
+			header: http.Header{"Last-Modified": {"yesterday"}, "Age": {"-5"}, "Date": {"soon"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotLastModified, gotAge := pageFreshness(tt.header, now)
+			assert.True(t, tt.wantLastModified.Equal(gotLastModified), "LastModified = %v; want %v", gotLastModified, tt.wantLastModified)
+			assert.Equal(t, tt.wantAge, gotAge)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+)
+
+// hostPatterns matches hostnames against exact names or "*.example.com"
+// wildcards, which match any subdomain but not example.com itself
+type hostPatterns []string
+
+func newHostPatterns(patterns []string) hostPatterns {
+	var hp hostPatterns
+	for _, p := range patterns {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			hp = append(hp, p)
+		}
+	}
+	return hp
+}
+
+func (hp hostPatterns) match(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, p := range hp {
+		if suffix, ok := strings.CutPrefix(p, "*"); ok {
+			if strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+				return true
+			}
+		} else if host == p {
+			return true
+		}
+	}
+	return false
+}
+
+// matchURL reports whether the host of rawURL matches any pattern
+func (hp hostPatterns) matchURL(rawURL string) bool {
+	if len(hp) == 0 {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return hp.match(u.Hostname())
+}
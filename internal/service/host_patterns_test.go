@@ -0,0 +1,30 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostPatternsMatch(t *testing.T) {
+	patterns := newHostPatterns([]string{"*.googleapis.com", "Fonts.gstatic.com", " "})
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "fonts.googleapis.com", want: true},
+		{host: "a.b.googleapis.com", want: true},
+		{host: "googleapis.com", want: false},
+		{host: "notgoogleapis.com", want: false},
+		{host: "fonts.gstatic.com", want: true},
+		{host: "FONTS.GSTATIC.COM.", want: true},
+		{host: "cdn.gstatic.com", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			assert.Equal(t, tt.want, patterns.match(tt.host))
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"web_page_analyzer/internal/domain/models"
+
+	"golang.org/x/net/html"
+)
+
+// analyzeImages probes the page's images. Images on hosts the operator skips,
+// or internal ones robots.txt disallows, are counted but never contacted.
+func (a *Analyzer) analyzeImages(ctx context.Context, result *models.AnalysisResult) error {
+	facts := documentFactsFor(ctx, result)
+	result.ImagesTotal = facts.imagesTotal
+
+	robots := a.robotsFor(ctx)
+	toCheck := make([]linkInfo, 0, len(facts.images))
+	for _, image := range facts.images {
+		if a.skipReason(ctx, image, robots) == "" {
+			toCheck = append(toCheck, image)
+		}
+	}
+	for check := range a.probeLinks(ctx, toCheck) {
+		if !check.Accessible {
+			result.InaccessibleImages++
+		}
+	}
+	return nil
+}
+
+// collectImages returns the absolute http(s) URLs of the document's <img src>
+// elements, each once, and how many images referenced them. data: URIs and
+// other schemes are left out of both.
+func collectImages(ctx context.Context, doc *html.Node, baseURL *url.URL) ([]linkInfo, int) {
+	facts := walkDocument(ctx, doc, baseURL, walkOptions{})
+	return facts.images, facts.imagesTotal
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCollectImages(t *testing.T) {
+	baseURL, _ := url.Parse("https://example.com/docs/")
+	doc := parseHTMLString(t, `<html><body>
+		<img src="logo.png">
+		<img src="https://cdn.example.net/hero.jpg">
+		<img src="/docs/logo.png">
+		<img src="">
+		<img alt="no source">
+		<img src="data:image/png;base64,iVBORw0KGgo=">
+	</body></html>`)
+
+	images, total := collectImages(context.Background(), doc, baseURL)
+
+	assert.Equal(t, []linkInfo{
+		{url: "https://example.com/docs/logo.png", isInternal: true},
+		{url: "https://cdn.example.net/hero.jpg", isInternal: false},
+	}, images)
+	assert.Equal(t, 3, total)
+}
+
+func TestAnalyzeReportsBrokenImages(t *testing.T) {
+	assets := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.png" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer assets.Close()
+
+	htmlContent := `<html><body>
+		<img src="` + assets.URL + `/logo.png">
+		<img src="` + assets.URL + `/missing.png">
+		<img src="data:image/gif;base64,R0lGODlhAQABAAAAACw=">
+	</body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+
+	result, err := NewAnalyzer(log.New(), mockWebClient).Analyze(context.Background(), "http://example.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.ImagesTotal)
+	assert.Equal(t, 1, result.InaccessibleImages)
+}
+
+func TestAnalyzeImagesHonoursLinkCheckSkips(t *testing.T) {
+	htmlContent := `<html><body>
+		<a href="/private/page">Private page</a>
+		<img src="/public/logo.png">
+		<img src="/private/photo.png">
+		<img src="https://fonts.googleapis.com/icon.png">
+	</body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com/", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+
+	var mu sync.Mutex
+	requested := map[string]int{}
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		requested[req.URL.String()]++
+		mu.Unlock()
+		body := ""
+		if req.URL.Path == "/robots.txt" {
+			body = "User-agent: *\nDisallow: /private\n"
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})
+
+	analyzer := NewAnalyzer(log.New(), mockWebClient,
+		WithRobotsTxt(), WithSkippedLinkHosts("*.googleapis.com"), WithLinkCheckTransport(transport))
+	result, err := analyzer.Analyze(context.Background(), "http://example.com/")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.ImagesTotal)
+	assert.Zero(t, result.InaccessibleImages)
+	assert.Equal(t, 1, requested["http://example.com/public/logo.png"])
+	assert.Zero(t, requested["http://example.com/private/photo.png"], "robots.txt disallows it")
+	assert.Zero(t, requested["https://fonts.googleapis.com/icon.png"], "its host is skipped")
+	assert.Equal(t, 1, requested["http://example.com/robots.txt"], "links and images share one robots.txt fetch")
+}
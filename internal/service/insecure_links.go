@@ -0,0 +1,26 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+)
+
+// insecureInternalLinks returns the internal links of an https page that
+// still point at http://, each once, in document order. Pages served over
+// http have none.
+func insecureInternalLinks(links []linkInfo, baseURL *url.URL) []string {
+	if baseURL == nil || !strings.EqualFold(baseURL.Scheme, "https") {
+		return nil
+	}
+
+	var insecure []string
+	seen := make(map[string]bool)
+	for _, link := range links {
+		if !link.isInternal || !strings.HasPrefix(strings.ToLower(link.url), "http:") || seen[link.url] {
+			continue
+		}
+		seen[link.url] = true
+		insecure = append(insecure, link.url)
+	}
+	return insecure
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInsecureInternalLinks(t *testing.T) {
+	doc := parseHTMLString(t, `<html><body>
+		<a href="/secure">Relative</a>
+		<a href="https://example.com/also-secure">Secure</a>
+		<a href="http://example.com/old">Old</a>
+		<a href="http://example.com:80/older">Default port</a>
+		<a href="http://example.com/old">Duplicate</a>
+		<a href="http://other.com/">External</a>
+	</body></html>`)
+
+	tests := []struct {
+		name     string
+		base     string
+		expected []string
+	}{
+		{name: "https page", base: "https://example.com/", expected: []string{"http://example.com/old", "http://example.com:80/older"}},
+		{name: "http page", base: "http://example.com/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseURL, _ := url.Parse(tt.base)
+			links := collectLinks(context.Background(), doc, baseURL)
+			assert.Equal(t, tt.expected, insecureInternalLinks(links, baseURL))
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// landmarkRoles are the ARIA landmark roles reported by countLandmarks
+var landmarkRoles = map[string]bool{
+	"banner":        true,
+	"navigation":    true,
+	"main":          true,
+	"contentinfo":   true,
+	"complementary": true,
+	"search":        true,
+	"form":          true,
+	"region":        true,
+}
+
+// implicitLandmarkRoles maps semantic elements to the landmark role they expose
+var implicitLandmarkRoles = map[string]string{
+	"header": "banner",
+	"nav":    "navigation",
+	"main":   "main",
+	"footer": "contentinfo",
+	"aside":  "complementary",
+	"search": "search",
+}
+
+// sectioningElements scope header and footer so they no longer act as the
+// page banner/contentinfo
+var sectioningElements = map[string]bool{
+	"article": true,
+	"aside":   true,
+	"main":    true,
+	"nav":     true,
+	"section": true,
+}
+
+// countLandmarks counts landmarks by ARIA role, taking explicit role
+// attributes first and falling back to the element's implicit role
+func countLandmarks(ctx context.Context, doc *html.Node) map[string]int {
+	return walkDocument(ctx, doc, nil, walkOptions{}).landmarks
+}
+
+func landmarkRole(n *html.Node, inSection bool) string {
+	for _, attr := range n.Attr {
+		if attr.Key == "role" {
+			// role may list fallbacks; the first recognised token wins
+			for _, role := range strings.Fields(strings.ToLower(attr.Val)) {
+				if landmarkRoles[role] {
+					return role
+				}
+			}
+			return ""
+		}
+	}
+
+	role := implicitLandmarkRoles[n.Data]
+	if (n.Data == "header" || n.Data == "footer") && inSection {
+		return ""
+	}
+	return role
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountLandmarks(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		htmlStr  string
+		expected map[string]int
+	}{
+		{
+			name: "semantic elements",
+			htmlStr: `<html><body>
+				<header>Site</header>
+				<nav><a href="/">Home</a></nav>
+				<main><article><header>Post title</header><p>Body</p><footer>Post meta</footer></article></main>
+				<nav>Secondary</nav>
+				<footer>Copyright</footer>
+			</body></html>`,
+			expected: map[string]int{"banner": 1, "navigation": 2, "main": 1, "contentinfo": 1},
+		},
+		{
+			name: "explicit roles",
+			htmlStr: `<html><body>
+				<div role="banner">Site</div>
+				<div role="navigation">Menu</div>
+				<div role="main">Content</div>
+				<div role="presentation">Ignored</div>
+				<nav role="search">Search</nav>
+			</body></html>`,
+			expected: map[string]int{"banner": 1, "navigation": 1, "main": 1, "search": 1},
+		},
+		{
+			name:     "no landmarks",
+			htmlStr:  `<html><body><div>Plain</div></body></html>`,
+			expected: map[string]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseHTMLString(t, tt.htmlStr)
+			assert.Equal(t, tt.expected, countLandmarks(ctx, doc))
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"web_page_analyzer/internal/domain/models"
+
+	"golang.org/x/net/html"
+)
+
+// analyzeLanguage reports the document language from <html lang>, falling
+// back to the Content-Language header. The charset from the Content-Type
+// header is kept when there is one, as it is what browsers decode with;
+// otherwise the charset declared in a <meta> is reported.
+func analyzeLanguage(ctx context.Context, result *models.AnalysisResult) error {
+	facts := documentFactsFor(ctx, result)
+	result.Language = facts.lang
+	if result.Language == "" {
+		result.Language = contentLanguage(result.ResponseHeader.Get("Content-Language"))
+	}
+	if result.Charset == "" {
+		result.Charset = facts.metaCharset
+	}
+	return nil
+}
+
+// contentLanguage returns the first language of a Content-Language header,
+// which may list several, e.g. "de-DE, en-CA"
+func contentLanguage(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.TrimSpace(first)
+}
+
+// metaCharset returns the charset a <meta> element declares, either with
+// <meta charset> or with <meta http-equiv="Content-Type" content="...">
+func metaCharset(n *html.Node) string {
+	if charset := getAttr(n, "charset"); charset != "" {
+		return normalizeHeaderToken(charset)
+	}
+	if strings.EqualFold(getAttr(n, "http-equiv"), "content-type") {
+		_, charset := normalizeContentType(getAttr(n, "content"))
+		return charset
+	}
+	return ""
+}
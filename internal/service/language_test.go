@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAnalyzeLanguageAndCharset(t *testing.T) {
+	tests := []struct {
+		name         string
+		page         string
+		header       http.Header
+		wantLanguage string
+		wantCharset  string
+	}{
+		{
+			name:         "html lang",
+			page:         `<html lang="en-US"><head><title>Page</title></head></html>`,
+			header:       http.Header{"Content-Type": {"text/html"}, "Content-Language": {"de"}},
+			wantLanguage: "en-US",
+		},
+		{
+			name:        "meta charset",
+			page:        `<html><head><meta charset="UTF-8"><title>Page</title></head></html>`,
+			header:      http.Header{"Content-Type": {"text/html"}},
+			wantCharset: "utf-8",
+		},
+		{
+			name:        "meta http-equiv content type",
+			page:        `<html><head><meta http-equiv="Content-Type" content="text/html; charset=ISO-8859-1"></head></html>`,
+			header:      http.Header{"Content-Type": {"text/html"}},
+			wantCharset: "iso-8859-1",
+		},
+		{
+			name:         "headers only",
+			page:         `<html><head><meta charset="utf-8"></head></html>`,
+			header:       http.Header{"Content-Type": {"text/html; charset=windows-1252"}, "Content-Language": {"de-DE, en-CA"}},
+			wantLanguage: "de-DE",
+			wantCharset:  "windows-1252",
+		},
+		{
+			name:   "neither",
+			page:   `<html><head><title>Page</title></head></html>`,
+			header: http.Header{"Content-Type": {"text/html"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := htmlResponse(tt.page)
+			response.Header = tt.header
+			mockWebClient := new(MockWebClient)
+			mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(response, nil)
+
+			result, err := NewAnalyzer(log.New(), mockWebClient).Analyze(context.Background(), "http://example.com")
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantLanguage, result.Language)
+			assert.Equal(t, tt.wantCharset, result.Charset)
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"net/url"
+	"strings"
+)
+
+// linkNormalization controls how collected links are compared. The zero
+// value keeps every URL as collected and only drops exact duplicates.
+type linkNormalization struct {
+	stripQuery    bool
+	ignoredParams []string
+}
+
+func (n linkNormalization) enabled() bool {
+	return n.stripQuery || len(n.ignoredParams) > 0
+}
+
+// apply normalizes the query string of each link and drops links that are, or
+// become, duplicates of an earlier one
+func (n linkNormalization) apply(links []linkInfo) []linkInfo {
+	seen := make(map[string]bool, len(links))
+	deduped := make([]linkInfo, 0, len(links))
+	for _, link := range links {
+		if n.enabled() {
+			link.url = n.normalize(link.url)
+		}
+		if seen[link.url] {
+			continue
+		}
+		seen[link.url] = true
+		deduped = append(deduped, link)
+	}
+	return deduped
+}
+
+// countUniqueLinks returns how many distinct links remain once links are
+// normalized and duplicates dropped
+func countUniqueLinks(links []linkInfo, n linkNormalization) int {
+	seen := make(map[string]bool, len(links))
+	for _, link := range links {
+		seen[n.normalize(link.url)] = true
+	}
+	return len(seen)
+}
+
+func (n linkNormalization) normalize(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	if n.stripQuery {
+		u.RawQuery = ""
+		return u.String()
+	}
+
+	query := u.Query()
+	for key := range query {
+		if n.isIgnored(key) {
+			query.Del(key)
+		}
+	}
+	// Encode sorts the keys so parameter order doesn't create distinct links
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// isIgnored matches a query parameter against the ignored list; entries
+// ending in "*" match by prefix, e.g. "utm_*"
+func (n linkNormalization) isIgnored(key string) bool {
+	key = strings.ToLower(key)
+	for _, param := range n.ignoredParams {
+		param = strings.ToLower(param)
+		if prefix, ok := strings.CutSuffix(param, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+			continue
+		}
+		if key == param {
+			return true
+		}
+	}
+	return false
+}
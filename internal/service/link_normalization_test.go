@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLinkNormalizationApply(t *testing.T) {
+	links := []linkInfo{
+		{url: "http://example.com/page?utm_source=newsletter", isInternal: true},
+		{url: "http://example.com/page?utm_source=twitter", isInternal: true},
+		{url: "http://example.com/page?id=1&utm_medium=social", isInternal: true},
+		{url: "http://example.com/page?utm_medium=email&id=1", isInternal: true},
+		{url: "http://example.com/page?id=2", isInternal: true},
+	}
+
+	tests := []struct {
+		name     string
+		norm     linkNormalization
+		expected []string
+	}{
+		{
+			name: "default keeps query strings",
+			norm: linkNormalization{},
+			expected: []string{
+				"http://example.com/page?utm_source=newsletter",
+				"http://example.com/page?utm_source=twitter",
+				"http://example.com/page?id=1&utm_medium=social",
+				"http://example.com/page?utm_medium=email&id=1",
+				"http://example.com/page?id=2",
+			},
+		},
+		{
+			name: "ignored tracking params",
+			norm: linkNormalization{ignoredParams: []string{"utm_*"}},
+			expected: []string{
+				"http://example.com/page",
+				"http://example.com/page?id=1",
+				"http://example.com/page?id=2",
+			},
+		},
+		{
+			name: "ignore a single param",
+			norm: linkNormalization{ignoredParams: []string{"utm_source"}},
+			expected: []string{
+				"http://example.com/page",
+				"http://example.com/page?id=1&utm_medium=social",
+				"http://example.com/page?id=1&utm_medium=email",
+				"http://example.com/page?id=2",
+			},
+		},
+		{
+			name:     "strip query strings",
+			norm:     linkNormalization{stripQuery: true},
+			expected: []string{"http://example.com/page"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, link := range tt.norm.apply(links) {
+				got = append(got, link.url)
+			}
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestCountLinksWithIgnoredQueryParams(t *testing.T) {
+	ctx := context.Background()
+	baseURL, _ := url.Parse("http://example.com")
+	doc := parseHTMLString(t, `<html><body>
+		<a href="/pricing?utm_source=newsletter">Pricing</a>
+		<a href="/pricing?utm_source=twitter">Pricing</a>
+		<a href="http://other.com/?utm_source=newsletter">Other</a>
+	</body></html>`)
+
+	internal, external := countLinks(ctx, doc, baseURL, linkNormalization{})
+	assert.Equal(t, 2, internal)
+	assert.Equal(t, 1, external)
+
+	internal, external = countLinks(ctx, doc, baseURL, linkNormalization{ignoredParams: []string{"utm_source"}})
+	assert.Equal(t, 1, internal)
+	assert.Equal(t, 1, external)
+}
+
+func TestAnalyzeTotalAndUniqueLinks(t *testing.T) {
+	htmlContent := `<html><body>
+		<nav><a href="/">Home</a><a href="/about">About</a></nav>
+		<main><a href="/about">About us</a><a href="/?utm_source=footer">Home</a></main>
+		<footer><a href="/">Home</a><a href="/about">About</a></footer>
+	</body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+
+	tests := []struct {
+		name       string
+		opts       []AnalyzerOption
+		wantUnique int
+	}{
+		{name: "exact duplicates", wantUnique: 3},
+		{name: "normalized duplicates", opts: []AnalyzerOption{WithIgnoredQueryParams("utm_*")}, wantUnique: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// keep link checks off the network
+			opts := append(tt.opts, WithSkippedLinkHosts("example.com"))
+			result, err := NewAnalyzer(log.New(), mockWebClient, opts...).Analyze(context.Background(), "http://example.com")
+			assert.NoError(t, err)
+			assert.Equal(t, 6, result.TotalLinks)
+			assert.Equal(t, tt.wantUnique, result.UniqueLinks)
+		})
+	}
+}
+
+func TestAnalyzeProbesRepeatedLinksOnce(t *testing.T) {
+	htmlContent := `<html><body>` + strings.Repeat(`<a href="/missing">Missing</a>`, 5) + `</body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+
+	var probes atomic.Int32
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithLinkCheckTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		probes.Add(1)
+		return &http.Response{StatusCode: http.StatusNotFound, Body: http.NoBody, Request: req}, nil
+	})))
+
+	result, err := analyzer.Analyze(context.Background(), "http://example.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), probes.Load())
+	assert.Equal(t, 5, result.TotalLinks)
+	assert.Equal(t, 1, result.UniqueLinks)
+	assert.Equal(t, 1, result.InternalLinks)
+	assert.Equal(t, 1, result.InaccessibleLinks)
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"net/http"
+	"web_page_analyzer/internal/domain/models"
+)
+
+// followOneRedirect is the link check CheckRedirect: it follows the first
+// redirect and stops at the response after it
+func followOneRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > 1 {
+		return http.ErrUseLastResponse
+	}
+	return nil
+}
+
+// linkRedirect describes the redirect that led to resp, or returns nil when
+// the link answered directly
+func linkRedirect(sourceURL string, resp *http.Response) *models.LinkRedirect {
+	if resp.Request == nil || resp.Request.Response == nil {
+		return nil
+	}
+	return &models.LinkRedirect{
+		SourceURL:  sourceURL,
+		FinalURL:   resp.Request.URL.String(),
+		StatusCode: resp.Request.Response.StatusCode,
+	}
+}
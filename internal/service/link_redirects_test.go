@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"web_page_analyzer/internal/domain/models"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAnalyzeReportsRedirectingLinks(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case "/loop":
+			http.Redirect(w, r, "/loop-again", http.StatusFound)
+		case "/loop-again":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer srv.Close()
+
+	htmlContent := `<html><body>
+		<a href="` + srv.URL + `/old">Old</a>
+		<a href="` + srv.URL + `/loop">Loop</a>
+		<a href="` + srv.URL + `/direct">Direct</a>
+	</body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+
+	tests := []struct {
+		name             string
+		opts             []AnalyzerOption
+		want             []models.LinkRedirect
+		wantInaccessible int
+	}{
+		// redirects are followed until the client gives up on the loop
+		{name: "not reported by default", wantInaccessible: 1},
+		{name: "reported", opts: []AnalyzerOption{WithLinkRedirects()}, want: []models.LinkRedirect{
+			{SourceURL: srv.URL + "/old", FinalURL: srv.URL + "/new", StatusCode: http.StatusMovedPermanently},
+			// only the first hop is followed
+			{SourceURL: srv.URL + "/loop", FinalURL: srv.URL + "/loop-again", StatusCode: http.StatusFound},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := NewAnalyzer(log.New(), mockWebClient, tt.opts...).Analyze(context.Background(), "http://example.com")
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, result.RedirectingLinks)
+			assert.Equal(t, tt.wantInaccessible, result.InaccessibleLinks)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"web_page_analyzer/internal/domain/models"
+
+	"golang.org/x/net/html"
+)
+
+func (a *Analyzer) analyzeMetaTags(ctx context.Context, result *models.AnalysisResult) error {
+	var tags []*html.Node
+	tags, result.MetaTagCount = a.metaTagsFor(ctx, result)
+	result.MetaDescription = metaDescription(tags)
+	result.MetaRobots = metaRobots(tags)
+	return nil
+}
+
+func analyzeCanonicalURL(ctx context.Context, result *models.AnalysisResult) error {
+	result.CanonicalURL = documentFactsFor(ctx, result).canonicalURL
+	return nil
+}
+
+// metaDescription returns the content of the first <meta name="description">
+// among tags
+func metaDescription(tags []*html.Node) string {
+	for _, tag := range tags {
+		if strings.EqualFold(getAttr(tag, "name"), "description") {
+			return strings.TrimSpace(getAttr(tag, "content"))
+		}
+	}
+	return ""
+}
+
+// metaRobots returns the lower cased directives of every <meta name="robots">
+// among tags, e.g. ["noindex", "nofollow"], each once and in document order
+func metaRobots(tags []*html.Node) []string {
+	var directives []string
+	for _, tag := range tags {
+		if !strings.EqualFold(strings.TrimSpace(getAttr(tag, "name")), "robots") {
+			continue
+		}
+		for _, directive := range strings.Split(getAttr(tag, "content"), ",") {
+			directive = strings.ToLower(strings.TrimSpace(directive))
+			if directive != "" && !slices.Contains(directives, directive) {
+				directives = append(directives, directive)
+			}
+		}
+	}
+	return directives
+}
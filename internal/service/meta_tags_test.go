@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMetaTags(t *testing.T) {
+	doc := parseHTMLString(t, `<html><head>`+strings.Repeat(`<meta name="keywords" content="spam">`, 1000)+`</head></html>`)
+
+	facts := walkDocument(context.Background(), doc, nil, walkOptions{maxMetaTags: 50})
+	assert.Len(t, facts.metaTags, 50)
+	assert.Equal(t, 1000, facts.metaTagsTotal)
+
+	facts = walkDocument(context.Background(), doc, nil, walkOptions{})
+	assert.Len(t, facts.metaTags, 1000, "zero disables the cap")
+	assert.Equal(t, 1000, facts.metaTagsTotal)
+}
+
+func TestAnalyzeCapsMetaTags(t *testing.T) {
+	// the refresh tag sits past the cap, so it is counted but not inspected
+	htmlContent := `<html><head>` + strings.Repeat(`<meta name="keywords" content="spam">`, 5000) +
+		`<meta http-equiv="refresh" content="0;url=/elsewhere"></head></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithMaxMetaTags(100))
+
+	result, err := analyzer.Analyze(context.Background(), "http://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, 5001, result.MetaTagCount)
+	assert.Empty(t, result.MetaRefreshURL)
+}
+
+func TestMetaRobots(t *testing.T) {
+	doc := parseHTMLString(t, `<html><head>
+		<meta name="ROBOTS" content="NoIndex, nofollow">
+		<meta name="googlebot" content="noarchive">
+		<meta name="robots" content="nofollow,, max-snippet:50">
+	</head></html>`)
+	tags := walkDocument(context.Background(), doc, nil, walkOptions{}).metaTags
+
+	assert.Equal(t, []string{"noindex", "nofollow", "max-snippet:50"}, metaRobots(tags))
+	assert.Nil(t, metaRobots(nil))
+}
+
+func TestAnalyzeSEOTags(t *testing.T) {
+	tests := []struct {
+		name            string
+		head            string
+		wantDescription string
+		wantRobots      []string
+		wantCanonical   string
+	}{
+		{
+			name: "all tags",
+			head: `<meta name="description" content=" Docs for the analyzer ">
+				<meta name="robots" content="noindex, nofollow">
+				<link rel="canonical" href="/docs/">`,
+			wantDescription: "Docs for the analyzer",
+			wantRobots:      []string{"noindex", "nofollow"},
+			wantCanonical:   "http://example.com/docs/",
+		},
+		{
+			name:          "absolute canonical",
+			head:          `<link rel="Canonical" href="https://www.example.com/page"><link rel="canonical" href="/second">`,
+			wantCanonical: "https://www.example.com/page",
+		},
+		{
+			name: "no tags",
+			head: `<meta name="keywords" content="analyzer"><link rel="stylesheet" href="/site.css">`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			htmlContent := `<html><head>` + tt.head + `</head><body></body></html>`
+			mockWebClient := new(MockWebClient)
+			mockWebClient.On("Do", mock.Anything, "http://example.com/guide", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+
+			result, err := NewAnalyzer(log.New(), mockWebClient).Analyze(context.Background(), "http://example.com/guide")
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantDescription, result.MetaDescription)
+			assert.Equal(t, tt.wantRobots, result.MetaRobots)
+			assert.Equal(t, tt.wantCanonical, result.CanonicalURL)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"web_page_analyzer/internal/domain/models"
+	"web_page_analyzer/internal/pkg/errors"
+	"web_page_analyzer/internal/pkg/metrics"
+)
+
+const (
+	outcomeSuccess            = "success"
+	outcomeInvalidRequest     = "invalid_request"
+	outcomeFetchError         = "fetch_error"
+	outcomeUnsupportedContent = "unsupported_content_type"
+	outcomeParseError         = "parse_error"
+	outcomeTimeout            = "timeout"
+	outcomeResourceLimit      = "resource_limit"
+	outcomeCanceled           = "canceled"
+	outcomeInternalError      = "internal_error"
+)
+
+// analysisOutcome labels the terminal result of an analysis for the
+// analysis_total metric. Failures without a kind, like a panicking step, are
+// internal errors.
+func analysisOutcome(err error) string {
+	if err == nil {
+		return outcomeSuccess
+	}
+	kind, _ := ErrorKindOf(err)
+	switch {
+	// the caller went away, whatever step was running when it did
+	case errors.Is(err, context.Canceled):
+		return outcomeCanceled
+	case kind == KindTimeout || IsTimeout(err):
+		return outcomeTimeout
+	case kind == KindResourceLimit:
+		return outcomeResourceLimit
+	case kind == KindInvalidURL:
+		return outcomeInvalidRequest
+	case kind == KindUnreachable || kind == KindUpstreamStatus:
+		return outcomeFetchError
+	case kind == KindUnsupportedContentType:
+		return outcomeUnsupportedContent
+	case kind == KindParseFailed:
+		return outcomeParseError
+	default:
+		return outcomeInternalError
+	}
+}
+
+// pageStats picks what the page metrics record from a successful analysis.
+// Links that were skipped instead of probed don't count as checked.
+func pageStats(result *models.AnalysisResult) metrics.PageStats {
+	return metrics.PageStats{
+		Links:             result.TotalLinks,
+		HTMLVersion:       htmlVersionLabel(result.HTMLVersion),
+		HasLoginForm:      result.HasLoginForm,
+		CheckedLinks:      max(result.UniqueLinks-result.SkippedLinks, 0),
+		InaccessibleLinks: result.InaccessibleLinks,
+	}
+}
+
+// htmlVersionLabel maps a detected HTML version to the bounded label set of
+// analysis_html_version_total: HTML5, the known legacy versions, "other" for
+// an unrecognized doctype and "unknown" for a page without one. Unrecognized
+// doctypes are reported verbatim and would otherwise let pages mint labels.
+func htmlVersionLabel(version string) string {
+	switch version {
+	case "HTML5":
+		return version
+	case "", "Unknown/Quirks":
+		return "unknown"
+	}
+	for _, known := range doctypeVersions {
+		if version == known {
+			return version
+		}
+	}
+	return "other"
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"web_page_analyzer/internal/domain/models"
+	"web_page_analyzer/internal/pkg/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAnalysisOutcome(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "success", err: nil, want: outcomeSuccess},
+		{name: "invalid url", err: &AnalyzeError{Kind: KindInvalidURL, Err: errors.New("url is invalid")}, want: outcomeInvalidRequest},
+		{name: "unreachable", err: &AnalyzeError{Kind: KindUnreachable, Err: errors.New("connection refused")}, want: outcomeFetchError},
+		{name: "upstream status", err: &AnalyzeError{Kind: KindUpstreamStatus, StatusCode: http.StatusNotFound, Err: errors.New("not found")}, want: outcomeFetchError},
+		{name: "parse failed", err: &AnalyzeError{Kind: KindParseFailed, Err: errors.New("bad markup")}, want: outcomeParseError},
+		{name: "timeout", err: &AnalyzeError{Kind: KindTimeout, Err: context.DeadlineExceeded}, want: outcomeTimeout},
+		{name: "deadline without a kind", err: context.DeadlineExceeded, want: outcomeTimeout},
+		{name: "resource limit", err: &AnalyzeError{Kind: KindResourceLimit, Err: ErrResourceLimit}, want: outcomeResourceLimit},
+		{name: "unsupported content type", err: &AnalyzeError{Kind: KindUnsupportedContentType, Err: ErrNotHTML}, want: outcomeUnsupportedContent},
+		{name: "canceled", err: context.Canceled, want: outcomeCanceled},
+		{name: "canceled mid fetch", err: &AnalyzeError{Kind: KindUnreachable, Err: context.Canceled}, want: outcomeCanceled},
+		{name: "no kind", err: errors.New("step panicked"), want: outcomeInternalError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, analysisOutcome(tt.err))
+		})
+	}
+}
+
+func TestHTMLVersionLabel(t *testing.T) {
+	assert.Equal(t, "HTML5", htmlVersionLabel("HTML5"))
+	assert.Equal(t, "HTML 4.01 Strict", htmlVersionLabel("HTML 4.01 Strict"))
+	assert.Equal(t, "XHTML 1.1", htmlVersionLabel("XHTML 1.1"))
+	assert.Equal(t, "unknown", htmlVersionLabel("Unknown/Quirks"))
+	assert.Equal(t, "unknown", htmlVersionLabel(""))
+	assert.Equal(t, "other", htmlVersionLabel("-//Example//DTD Made Up 1.0//EN"))
+}
+
+func TestAnalyzeCountsOutcomes(t *testing.T) {
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(`<html></html>`), nil)
+	mockWebClient.On("Do", mock.Anything, "http://down.example.com", http.MethodGet).Return(nil, errors.New("connection refused"))
+	analyzer := NewAnalyzer(log.New(), mockWebClient)
+
+	count := func(outcome string) float64 {
+		return testutil.ToFloat64(metrics.AnalysisTotal.WithLabelValues(outcome))
+	}
+	successBefore, fetchErrorBefore := count(outcomeSuccess), count(outcomeFetchError)
+
+	_, err := analyzer.Analyze(context.Background(), "http://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, count(outcomeSuccess)-successBefore)
+
+	_, err = analyzer.Analyze(context.Background(), "http://down.example.com")
+	assert.Error(t, err)
+	assert.Equal(t, 1.0, count(outcomeFetchError)-fetchErrorBefore)
+	assert.Equal(t, 1.0, count(outcomeSuccess)-successBefore)
+}
+
+func TestAnalyzeTracksAnalysesInFlight(t *testing.T) {
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(`<html></html>`), nil)
+	mockWebClient.On("Do", mock.Anything, "http://down.example.com", http.MethodGet).Return(nil, errors.New("connection refused"))
+	analyzer := NewAnalyzer(log.New(), mockWebClient)
+
+	var during float64
+	_, err := analyzer.AnalyzeWithOptions(context.Background(), "http://example.com", models.AnalysisOptions{
+		OnPageFetched: func(*models.AnalysisResult) { during = testutil.ToFloat64(metrics.AnalysesInFlight) },
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1.0, during)
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.AnalysesInFlight))
+
+	_, err = analyzer.Analyze(context.Background(), "http://down.example.com")
+	assert.Error(t, err)
+	assert.Equal(t, 0.0, testutil.ToFloat64(metrics.AnalysesInFlight), "the error path must release the gauge too")
+}
+
+// scrapeMetric sums the counter values, or histogram sample counts, of the
+// series of name whose labels include labels
+func scrapeMetric(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := metrics.MetricsRegister().Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	total := 0.0
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	series:
+		for _, m := range family.GetMetric() {
+			for _, pair := range m.GetLabel() {
+				if want, ok := labels[pair.GetName()]; ok && want != pair.GetValue() {
+					continue series
+				}
+			}
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				total += m.GetCounter().GetValue()
+			case dto.MetricType_HISTOGRAM:
+				total += float64(m.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return total
+}
+
+func TestAnalyzeRecordsPageMetrics(t *testing.T) {
+	htmlContent := `<!DOCTYPE html><html><body>
+		<form><input type="password" name="pw"></form>
+		<a href="http://example.com/ok">Fine</a>
+		<a href="http://example.com/broken">Broken</a>
+	</body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+	mockWebClient.On("Do", mock.Anything, "http://down.example.com", http.MethodGet).Return(nil, errors.New("connection refused"))
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithLinkCheckTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		status := http.StatusOK
+		if strings.HasSuffix(req.URL.Path, "/broken") {
+			status = http.StatusNotFound
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+	})))
+
+	scrape := func() map[string]float64 {
+		return map[string]float64{
+			"links":    scrapeMetric(t, "analysis_page_links", nil),
+			"html5":    scrapeMetric(t, "analysis_html_version_total", map[string]string{"version": "HTML5"}),
+			"login":    scrapeMetric(t, "analysis_login_form_total", map[string]string{"has_login_form": "true"}),
+			"no_login": scrapeMetric(t, "analysis_login_form_total", map[string]string{"has_login_form": "false"}),
+			"broken":   scrapeMetric(t, "analysis_inaccessible_link_ratio", nil),
+		}
+	}
+	before := scrape()
+
+	_, err := analyzer.Analyze(context.Background(), "http://example.com")
+	assert.NoError(t, err)
+	_, err = analyzer.Analyze(context.Background(), "http://down.example.com")
+	assert.Error(t, err)
+
+	after := scrape()
+	assert.Equal(t, 1.0, after["links"]-before["links"], "failed analyses are not observed")
+	assert.Equal(t, 1.0, after["html5"]-before["html5"])
+	assert.Equal(t, 1.0, after["login"]-before["login"])
+	assert.Equal(t, 0.0, after["no_login"]-before["no_login"])
+	assert.Equal(t, 1.0, after["broken"]-before["broken"])
+}
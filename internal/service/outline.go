@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"web_page_analyzer/internal/domain/models"
+
+	"golang.org/x/net/html"
+)
+
+type outlineEntry struct {
+	level    int
+	text     string
+	children []*outlineEntry
+}
+
+// headingText is a heading element with its whitespace-collapsed text
+type headingText struct {
+	tag   string
+	level int
+	text  string
+}
+
+func buildOutline(ctx context.Context, doc *html.Node) []models.OutlineNode {
+	return nestHeadings(walkDocument(ctx, doc, nil, walkOptions{}).headingTexts)
+}
+
+// nestHeadings takes the headings in document order and nests each one under
+// the closest preceding heading of a higher level
+func nestHeadings(headings []headingText) []models.OutlineNode {
+	var roots []*outlineEntry
+	var stack []*outlineEntry
+	for _, heading := range headings {
+		entry := &outlineEntry{level: heading.level, text: heading.text}
+		for len(stack) > 0 && stack[len(stack)-1].level >= heading.level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, entry)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, entry)
+		}
+		stack = append(stack, entry)
+	}
+	return toOutlineNodes(roots)
+}
+
+func toOutlineNodes(entries []*outlineEntry) []models.OutlineNode {
+	if len(entries) == 0 {
+		return nil
+	}
+	nodes := make([]models.OutlineNode, 0, len(entries))
+	for _, e := range entries {
+		nodes = append(nodes, models.OutlineNode{
+			Level:    e.level,
+			Text:     e.text,
+			Children: toOutlineNodes(e.children),
+		})
+	}
+	return nodes
+}
+
+// headingLevel returns 1-6 for h1-h6 tags and 0 otherwise
+func headingLevel(tag string) int {
+	if len(tag) == 2 && tag[0] == 'h' && tag[1] >= '1' && tag[1] <= '6' {
+		return int(tag[1] - '0')
+	}
+	return 0
+}
+
+// nodeText returns the text content of n with whitespace collapsed
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var traverse func(*html.Node)
+	traverse = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteString(" ")
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			traverse(c)
+		}
+	}
+	traverse(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"web_page_analyzer/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildOutline(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		htmlStr  string
+		expected []models.OutlineNode
+	}{
+		{
+			name: "h1 > h2 > h2 > h3",
+			htmlStr: `<html><body>
+				<h1>Guide</h1>
+				<h2>Install</h2>
+				<h2>Usage <em>basics</em></h2>
+				<h3>Flags</h3>
+			</body></html>`,
+			expected: []models.OutlineNode{
+				{Level: 1, Text: "Guide", Children: []models.OutlineNode{
+					{Level: 2, Text: "Install"},
+					{Level: 2, Text: "Usage basics", Children: []models.OutlineNode{
+						{Level: 3, Text: "Flags"},
+					}},
+				}},
+			},
+		},
+		{
+			name: "skipped level and second root",
+			htmlStr: `<html><body>
+				<h2>Intro</h2>
+				<h4>Detail</h4>
+				<h3>Aside</h3>
+				<h1>Main</h1>
+			</body></html>`,
+			expected: []models.OutlineNode{
+				{Level: 2, Text: "Intro", Children: []models.OutlineNode{
+					{Level: 4, Text: "Detail"},
+					{Level: 3, Text: "Aside"},
+				}},
+				{Level: 1, Text: "Main"},
+			},
+		},
+		{
+			name:     "no headings",
+			htmlStr:  `<html><body><p>Nothing here</p></body></html>`,
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseHTMLString(t, tt.htmlStr)
+			assert.Equal(t, tt.expected, buildOutline(ctx, doc))
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"compress/gzip"
+	"context"
+	"web_page_analyzer/internal/domain/models"
+	"web_page_analyzer/internal/pkg/errors"
+)
+
+func analyzePageSize(ctx context.Context, result *models.AnalysisResult) error {
+	gzipped, err := gzippedSize(result.BodyByte)
+	if err != nil {
+		return err
+	}
+	result.PageSizeBytes = len(result.BodyByte)
+	result.GzippedSizeBytes = gzipped
+	return nil
+}
+
+// gzippedSize returns how many bytes body takes gzipped at the default level,
+// an estimate of its transfer size when the server compresses
+func gzippedSize(body []byte) (int, error) {
+	var counter byteCounter
+	zw := gzip.NewWriter(&counter)
+	if _, err := zw.Write(body); err != nil {
+		return 0, errors.Wrap(err, `failed to gzip body`)
+	}
+	if err := zw.Close(); err != nil {
+		return 0, errors.Wrap(err, `failed to gzip body`)
+	}
+	return counter.n, nil
+}
+
+// byteCounter counts what is written to it and discards it
+type byteCounter struct {
+	n int
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	c.n += len(p)
+	return len(p), nil
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"web_page_analyzer/internal/domain/models"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAnalyzePageSize(t *testing.T) {
+	htmlContent := `<html><body>` + strings.Repeat(`<p>The same paragraph, over and over.</p>`, 200) + `</body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+	analyzer := NewAnalyzer(log.New(), mockWebClient)
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), "http://example.com", models.AnalysisOptions{PageSize: true})
+	assert.NoError(t, err)
+	assert.Equal(t, len(htmlContent), result.PageSizeBytes)
+	assert.Positive(t, result.GzippedSizeBytes)
+	assert.Less(t, result.GzippedSizeBytes, result.PageSizeBytes)
+
+	result, err = analyzer.Analyze(context.Background(), "http://example.com")
+	assert.NoError(t, err)
+	assert.Zero(t, result.PageSizeBytes, "sizes are only measured on request")
+	assert.Zero(t, result.GzippedSizeBytes)
+}
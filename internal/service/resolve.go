@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+	"web_page_analyzer/internal/domain/models"
+)
+
+// Reverse lookups are best effort: at most maxReverseLookups addresses are
+// looked up, and together they get reverseLookupTimeout
+const (
+	maxReverseLookups    = 4
+	reverseLookupTimeout = 2 * time.Second
+)
+
+// Resolver looks up host addresses and reverse DNS names. *net.Resolver
+// satisfies it.
+type Resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupAddr(ctx context.Context, addr string) ([]string, error)
+}
+
+// resolveTarget picks the host to report addresses for: the address the page
+// was fetched from when the fetch reported it, otherwise the analyzed url's
+// host
+func resolveTarget(remoteAddr string, u *url.URL) string {
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		return host
+	}
+	if u == nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// resolveHost fills in the addresses of host and their reverse DNS names. A
+// failure is reported as a warning, it doesn't fail the analysis.
+func (a *Analyzer) resolveHost(ctx context.Context, result *models.AnalysisResult, timings *stepTimings, host string) {
+	funcStartTime := time.Now()
+	defer func() {
+		a.logger(ctx).Debugf("resolveHost took %v", timings.record("resolveHost", funcStartTime))
+	}()
+	ips, names, err := resolveHost(ctx, a.resolver, host)
+	if err != nil {
+		a.logger(ctx).WithError(err).Warn(`failed to resolve host`)
+		result.Warnings = append(result.Warnings, `failed to resolve host addresses`)
+		return
+	}
+	result.ResolvedIPs, result.ReverseDNS = ips, names
+}
+
+// resolveHost returns the addresses of host and the reverse DNS names of
+// those addresses. Reverse lookups are best effort, since most addresses have
+// no PTR record.
+func resolveHost(ctx context.Context, resolver Resolver, host string) (ips []string, names []string, err error) {
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []string{ip.String()}
+	} else {
+		ips, err = resolver.LookupHost(ctx, host)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, reverseLookupTimeout)
+	defer cancel()
+	seen := map[string]bool{}
+	for _, ip := range ips[:min(len(ips), maxReverseLookups)] {
+		ptrs, err := resolver.LookupAddr(ctx, ip)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		for _, name := range ptrs {
+			name = strings.TrimSuffix(name, ".")
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return ips, names, nil
+}
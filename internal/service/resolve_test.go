@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"web_page_analyzer/internal/domain/models"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// stubResolver answers from fixed tables
+type stubResolver struct {
+	hosts map[string][]string
+	addrs map[string][]string
+	// reverseLookups counts LookupAddr calls
+	reverseLookups int
+}
+
+func (r *stubResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	ips, ok := r.hosts[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return ips, nil
+}
+
+func (r *stubResolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	r.reverseLookups++
+	names, ok := r.addrs[addr]
+	if !ok {
+		return nil, errors.New("no PTR record")
+	}
+	return names, nil
+}
+
+func TestAnalyzeResolveHost(t *testing.T) {
+	resolver := &stubResolver{
+		hosts: map[string][]string{"example.com": {"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"}},
+		addrs: map[string][]string{"93.184.216.34": {"edge-1.example.net."}},
+	}
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "https://example.com/page", http.MethodGet).
+		Return(htmlResponse(`<html><body><main></main></body></html>`), nil)
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithResolver(resolver))
+
+	t.Run("requested", func(t *testing.T) {
+		result, err := analyzer.AnalyzeWithOptions(context.Background(), "https://example.com/page", models.AnalysisOptions{ResolveHost: true})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"}, result.ResolvedIPs)
+		assert.Equal(t, []string{"edge-1.example.net"}, result.ReverseDNS)
+	})
+
+	t.Run("reports the dialed address", func(t *testing.T) {
+		resp := htmlResponse(`<html><body><main></main></body></html>`)
+		resp.RemoteAddr = "93.184.216.34:443"
+		client := new(MockWebClient)
+		client.On("Do", mock.Anything, "https://www.example.com/page", http.MethodGet).Return(resp, nil)
+		// the resolver knows no hosts, so only the dialed address can be reported
+		analyzer := NewAnalyzer(log.New(), client, WithResolver(&stubResolver{addrs: resolver.addrs}))
+
+		result, err := analyzer.AnalyzeWithOptions(context.Background(), "https://www.example.com/page", models.AnalysisOptions{ResolveHost: true})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"93.184.216.34"}, result.ResolvedIPs)
+		assert.Equal(t, []string{"edge-1.example.net"}, result.ReverseDNS)
+	})
+
+	t.Run("not requested", func(t *testing.T) {
+		result, err := analyzer.Analyze(context.Background(), "https://example.com/page")
+
+		assert.NoError(t, err)
+		assert.Empty(t, result.ResolvedIPs)
+		assert.Empty(t, result.ReverseDNS)
+	})
+}
+
+func TestResolveHostFailure(t *testing.T) {
+	_, _, err := resolveHost(context.Background(), &stubResolver{}, "missing.example.com")
+	assert.Error(t, err)
+
+	ips, names, err := resolveHost(context.Background(), &stubResolver{}, "127.0.0.1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"127.0.0.1"}, ips)
+	assert.Empty(t, names)
+}
+
+func TestResolveHostCapsReverseLookups(t *testing.T) {
+	resolver := &stubResolver{hosts: map[string][]string{
+		"example.com": {"192.0.2.1", "192.0.2.2", "192.0.2.3", "192.0.2.4", "192.0.2.5", "192.0.2.6"},
+	}}
+
+	ips, _, err := resolveHost(context.Background(), resolver, "example.com")
+
+	assert.NoError(t, err)
+	assert.Len(t, ips, 6)
+	assert.Equal(t, maxReverseLookups, resolver.reverseLookups)
+}
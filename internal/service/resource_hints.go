@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"strings"
+	"web_page_analyzer/internal/domain/models"
+
+	"golang.org/x/net/html"
+)
+
+// resourceHintRels are the link relations reported as resource hints
+var resourceHintRels = map[string]bool{
+	"preload":       true,
+	"modulepreload": true,
+	"preconnect":    true,
+	"dns-prefetch":  true,
+	"prefetch":      true,
+	"prerender":     true,
+}
+
+const (
+	hintSourceHTML   = "html"
+	hintSourceHeader = "header"
+)
+
+// linkValue is one entry of a Link header: the target and its parameters,
+// with parameter names lowercased
+type linkValue struct {
+	target string
+	params map[string]string
+}
+
+// collectResourceHints gathers resource hints from <link> elements and from
+// the Link response header values, resolving targets against baseURL
+func collectResourceHints(ctx context.Context, doc *html.Node, baseURL *url.URL, linkHeader []string) []models.ResourceHint {
+	return withHeaderHints(walkDocument(ctx, doc, baseURL, walkOptions{}).resourceHints, baseURL, linkHeader)
+}
+
+// withHeaderHints returns the hints from <link> elements followed by the
+// ones from the Link response header values. htmlHints is left untouched.
+func withHeaderHints(htmlHints []models.ResourceHint, baseURL *url.URL, linkHeader []string) []models.ResourceHint {
+	hints := slices.Clip(htmlHints)
+	for _, link := range parseLinkHeader(linkHeader) {
+		hints = append(hints, resourceHints(resolveHref(link.target, baseURL), link.params["rel"], link.params["as"], hintSourceHeader)...)
+	}
+	return hints
+}
+
+// resourceHints returns a hint to target for each resource hint relation
+// among rels
+func resourceHints(target, rels, as, source string) []models.ResourceHint {
+	if target == "" {
+		return nil
+	}
+	var hints []models.ResourceHint
+	for _, rel := range strings.Fields(strings.ToLower(rels)) {
+		if resourceHintRels[rel] {
+			hints = append(hints, models.ResourceHint{URL: target, Rel: rel, As: strings.ToLower(as), Source: source})
+		}
+	}
+	return hints
+}
+
+// parseLinkHeader parses RFC 8288 Link header values such as
+// `</app.css>; rel=preload; as=style, <https://cdn.example.com>; rel="preconnect"`.
+// Entries without a <target> are skipped.
+func parseLinkHeader(values []string) []linkValue {
+	var links []linkValue
+	for _, value := range values {
+		for _, entry := range splitOutsideQuotes(value, ',') {
+			entry = strings.TrimSpace(entry)
+			if !strings.HasPrefix(entry, "<") {
+				continue
+			}
+			end := strings.Index(entry, ">")
+			if end < 0 {
+				continue
+			}
+			link := linkValue{target: entry[1:end], params: map[string]string{}}
+			for _, param := range splitOutsideQuotes(entry[end+1:], ';') {
+				key, val, _ := strings.Cut(param, "=")
+				key = strings.ToLower(strings.TrimSpace(key))
+				if key == "" {
+					continue
+				}
+				link.params[key] = strings.Trim(strings.TrimSpace(val), `"`)
+			}
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// splitOutsideQuotes splits s on sep, ignoring separators inside quoted
+// strings or <targets>
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	inQuotes, inTarget := false, false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' && !inTarget:
+			inQuotes = !inQuotes
+		case c == '<' && !inQuotes:
+			inTarget = true
+		case c == '>' && !inQuotes:
+			inTarget = false
+		case c == sep && !inQuotes && !inTarget:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
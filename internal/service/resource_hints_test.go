@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"testing"
+	"web_page_analyzer/internal/domain/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLinkHeader(t *testing.T) {
+	links := parseLinkHeader([]string{
+		`</static/app.css>; rel=preload; as=style, <https://fonts.example.com>; rel="preconnect"; crossorigin`,
+		`</next,page>; rel="prefetch dns-prefetch"; title="a; b, c"`,
+		`malformed; rel=preload`,
+	})
+
+	assert.Equal(t, []linkValue{
+		{target: "/static/app.css", params: map[string]string{"rel": "preload", "as": "style"}},
+		{target: "https://fonts.example.com", params: map[string]string{"rel": "preconnect", "crossorigin": ""}},
+		{target: "/next,page", params: map[string]string{"rel": "prefetch dns-prefetch", "title": "a; b, c"}},
+	}, links)
+}
+
+func TestCollectResourceHints(t *testing.T) {
+	baseURL, _ := url.Parse("https://example.com/blog/")
+	doc := parseHTMLString(t, `<html><head>
+		<link rel="preload" href="hero.jpg" as="image">
+		<link rel="stylesheet" href="/main.css">
+		<link rel="dns-prefetch preconnect" href="https://cdn.example.net">
+	</head><body></body></html>`)
+	header := []string{`</static/app.js>; rel=modulepreload, </robots.txt>; rel=alternate`}
+
+	hints := collectResourceHints(context.Background(), doc, baseURL, header)
+
+	assert.Equal(t, []models.ResourceHint{
+		{URL: "https://example.com/blog/hero.jpg", Rel: "preload", As: "image", Source: "html"},
+		{URL: "https://cdn.example.net", Rel: "dns-prefetch", Source: "html"},
+		{URL: "https://cdn.example.net", Rel: "preconnect", Source: "html"},
+		{URL: "https://example.com/static/app.js", Rel: "modulepreload", Source: "header"},
+	}, hints)
+}
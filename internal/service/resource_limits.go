@@ -0,0 +1,61 @@
+package service
+
+import (
+	"fmt"
+	"web_page_analyzer/internal/pkg/errors"
+
+	"golang.org/x/net/html"
+)
+
+// ErrResourceLimit is returned when a page is larger than the analyzer's
+// resource limits allow, see WithResourceLimits
+var ErrResourceLimit = errors.New(`analysis resource limit exceeded`)
+
+// resourceLimits caps how much of a page one analysis holds in memory. Zero
+// fields are unlimited.
+type resourceLimits struct {
+	maxBodyBytes int
+	maxDOMNodes  int
+}
+
+// checkBody fails with ErrResourceLimit when body is over the size limit, so
+// an oversized page is never parsed
+func (l resourceLimits) checkBody(body []byte) error {
+	if l.maxBodyBytes > 0 && len(body) > l.maxBodyBytes {
+		return errors.Wrap(ErrResourceLimit, fmt.Sprintf(`body is %d bytes, limit is %d`, len(body), l.maxBodyBytes))
+	}
+	return nil
+}
+
+// checkDocument fails with ErrResourceLimit when doc has more nodes than the
+// limit, before any analyzer walks it
+func (l resourceLimits) checkDocument(doc *html.Node) error {
+	if l.maxDOMNodes > 0 && exceedsNodeCount(doc, l.maxDOMNodes) {
+		return errors.Wrap(ErrResourceLimit, fmt.Sprintf(`document has more than %d nodes`, l.maxDOMNodes))
+	}
+	return nil
+}
+
+// exceedsNodeCount reports whether the tree under root, root included, has
+// more than limit nodes. It stops counting as soon as the limit is passed.
+func exceedsNodeCount(root *html.Node, limit int) bool {
+	count := 0
+	n := root
+	for {
+		count++
+		if count > limit {
+			return true
+		}
+		if n.FirstChild != nil {
+			n = n.FirstChild
+			continue
+		}
+		for n != root && n.NextSibling == nil {
+			n = n.Parent
+		}
+		if n == root {
+			return false
+		}
+		n = n.NextSibling
+	}
+}
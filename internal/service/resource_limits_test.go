@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAnalyzeResourceLimits(t *testing.T) {
+	// document, html, head, body, p and its text node: 6 nodes
+	htmlContent := `<html><body><p>hello</p></body></html>`
+
+	tests := []struct {
+		name    string
+		opts    []AnalyzerOption
+		wantErr bool
+	}{
+		{name: "no limits"},
+		{name: "within limits", opts: []AnalyzerOption{WithResourceLimits(len(htmlContent), 6)}},
+		{name: "body over limit", opts: []AnalyzerOption{WithResourceLimits(len(htmlContent)-1, 0)}, wantErr: true},
+		{name: "nodes over limit", opts: []AnalyzerOption{WithResourceLimits(0, 5)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockWebClient := new(MockWebClient)
+			mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+
+			_, err := NewAnalyzer(log.New(), mockWebClient, tt.opts...).Analyze(context.Background(), "http://example.com")
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrResourceLimit)
+				assert.Equal(t, outcomeResourceLimit, analysisOutcome(err))
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestExceedsNodeCount(t *testing.T) {
+	doc := parseHTMLString(t, `<html><body>`+strings.Repeat(`<p>x</p>`, 10)+`</body></html>`)
+
+	// document, html, head, body and 10 paragraphs with a text node each
+	assert.False(t, exceedsNodeCount(doc, 24))
+	assert.True(t, exceedsNodeCount(doc, 23))
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// maxRobotsBytes is how much of a robots.txt is read, matching the limit
+// crawlers commonly apply
+const maxRobotsBytes = 500 << 10
+
+type robotsRule struct {
+	pattern string
+	allow   bool
+}
+
+// robotsRules are the Allow and Disallow rules of a robots.txt that apply to
+// every user agent, i.e. the "User-agent: *" groups
+type robotsRules []robotsRule
+
+// parseRobots reads the rules of the "*" groups from a robots.txt body
+func parseRobots(body []byte) robotsRules {
+	var rules robotsRules
+	applies, inAgents := false, false
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			// consecutive User-agent lines share the group that follows them
+			if !inAgents {
+				applies = false
+			}
+			if value == "*" {
+				applies = true
+			}
+			inAgents = true
+		case "allow", "disallow":
+			inAgents = false
+			if applies && value != "" {
+				rules = append(rules, robotsRule{pattern: value, allow: key == "allow"})
+			}
+		default:
+			inAgents = false
+		}
+	}
+	return rules
+}
+
+// allows reports whether path may be fetched. The longest matching rule
+// wins, Allow winning a tie, and a path no rule matches is allowed.
+func (r robotsRules) allows(path string) bool {
+	allowed, longest := true, -1
+	for _, rule := range r {
+		if !robotsPatternMatches(rule.pattern, path) {
+			continue
+		}
+		if len(rule.pattern) > longest || (len(rule.pattern) == longest && rule.allow) {
+			allowed, longest = rule.allow, len(rule.pattern)
+		}
+	}
+	return allowed
+}
+
+// robotsPatternMatches matches path against a robots.txt path pattern, where
+// "*" matches any run of characters and a trailing "$" anchors the end
+func robotsPatternMatches(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	if len(parts) == 1 {
+		return !anchored || path == parts[0]
+	}
+	rest := path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(rest, part)
+		}
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+	return true
+}
+
+// robotsCache fetches each origin's robots.txt at most once during an
+// analysis. It is shared by the link, image and stylesheet checks, which run
+// concurrently; checks against an origin whose robots.txt is still being
+// fetched wait for it, checks against other origins don't.
+type robotsCache struct {
+	client *http.Client
+	mu     sync.Mutex
+	rules  map[string]*robotsEntry
+}
+
+// robotsEntry is one origin's robots.txt, fetched once
+type robotsEntry struct {
+	once  sync.Once
+	rules robotsRules
+}
+
+// robotsCacheKey carries the analysis's robotsCache to the network steps
+type robotsCacheKey struct{}
+
+func newRobotsCache(client *http.Client) *robotsCache {
+	return &robotsCache{client: client, rules: map[string]*robotsEntry{}}
+}
+
+// allows reports whether the robots.txt of rawURL's origin lets it be fetched.
+// URLs that don't parse are left to the link check.
+func (c *robotsCache) allows(ctx context.Context, rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return true
+	}
+	origin := u.Scheme + "://" + u.Host
+	c.mu.Lock()
+	entry, ok := c.rules[origin]
+	if !ok {
+		entry = &robotsEntry{}
+		c.rules[origin] = entry
+	}
+	c.mu.Unlock()
+	entry.once.Do(func() {
+		entry.rules = c.fetch(ctx, origin)
+	})
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return entry.rules.allows(path)
+}
+
+// fetch reads origin's robots.txt. A missing or unreadable robots.txt
+// disallows nothing. The request takes a slot from the analysis's probe
+// semaphore, like any other probe.
+func (c *robotsCache) fetch(ctx context.Context, origin string) robotsRules {
+	if sem, ok := ctx.Value(probeSlotsKey{}).(chan struct{}); ok {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	resp, err := sendProbe(ctx, c.client, http.MethodGet, origin+"/robots.txt")
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsBytes))
+	if err != nil {
+		return nil
+	}
+	return parseRobots(body)
+}
@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"web_page_analyzer/internal/domain/models"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRobotsRulesAllows(t *testing.T) {
+	rules := parseRobots([]byte(`# comment
+User-agent: Googlebot
+Disallow: /
+
+User-agent: Bingbot
+User-agent: *
+Disallow: /private  # trailing comment
+Allow: /private/open
+Disallow: /*.pdf$
+Disallow: /search?
+Disallow:
+Crawl-delay: 2
+`))
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/", want: true},
+		{path: "/about", want: true},
+		{path: "/private", want: false},
+		{path: "/private/docs", want: false},
+		{path: "/private/open/page", want: true},
+		{path: "/files/report.pdf", want: false},
+		{path: "/files/report.pdf.html", want: true},
+		{path: "/search?q=go", want: false},
+		{path: "/search", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.want, rules.allows(tt.path))
+		})
+	}
+}
+
+func TestParseRobotsIgnoresOtherAgents(t *testing.T) {
+	rules := parseRobots([]byte("User-agent: Googlebot\nDisallow: /\n"))
+	assert.Empty(t, rules)
+	assert.True(t, rules.allows("/anything"))
+}
+
+func TestAnalyzeSkipsLinksDisallowedByRobots(t *testing.T) {
+	htmlContent := `<html><body>
+		<a href="/private/one">Private</a>
+		<a href="/private/two">Private</a>
+		<a href="/public">Public</a>
+		<a href="http://other.example/private/three">Elsewhere</a>
+	</body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com/", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+
+	var mu sync.Mutex
+	requested := map[string]int{}
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		requested[req.URL.String()]++
+		mu.Unlock()
+		body := ""
+		if req.URL.Path == "/robots.txt" {
+			body = "User-agent: *\nDisallow: /private\n"
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})
+
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithRobotsTxt(), WithLinkCheckTransport(transport))
+	skipped := map[string]string{}
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), "http://example.com/", models.AnalysisOptions{
+		OnLinkCheck: func(check models.LinkCheck) {
+			if check.Skipped {
+				skipped[check.URL] = check.SkipReason
+			}
+		},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.RobotsSkippedLinks)
+	assert.Equal(t, 2, result.SkippedLinks)
+	assert.Equal(t, 0, result.InaccessibleLinks)
+	assert.Equal(t, map[string]string{
+		"http://example.com/private/one": models.SkipReasonRobots,
+		"http://example.com/private/two": models.SkipReasonRobots,
+	}, skipped)
+
+	assert.Equal(t, 1, requested["http://example.com/robots.txt"], "robots.txt is fetched once per origin")
+	assert.Zero(t, requested["http://example.com/private/one"])
+	assert.Zero(t, requested["http://example.com/private/two"])
+	assert.Equal(t, 1, requested["http://example.com/public"])
+	assert.Equal(t, 1, requested["http://other.example/private/three"], "only the target site's robots.txt applies")
+	assert.Zero(t, requested["http://other.example/robots.txt"])
+}
+
+func TestAnalyzeIgnoresRobotsByDefault(t *testing.T) {
+	htmlContent := `<html><body><a href="/private/one">Private</a></body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com/", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+
+	var mu sync.Mutex
+	var requested []string
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		requested = append(requested, req.URL.Path)
+		mu.Unlock()
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+	})
+
+	result, err := NewAnalyzer(log.New(), mockWebClient, WithLinkCheckTransport(transport)).Analyze(context.Background(), "http://example.com/")
+	assert.NoError(t, err)
+	assert.Zero(t, result.RobotsSkippedLinks)
+	assert.Equal(t, []string{"/private/one"}, requested)
+}
+
+func TestRobotsCacheDoesNotBlockOtherOrigins(t *testing.T) {
+	release := make(chan struct{})
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == "slow.example" {
+			<-release
+		}
+		body := "User-agent: *\nDisallow: /private\n"
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})
+	robots := newRobotsCache(&http.Client{Transport: transport})
+
+	slowDone := make(chan bool)
+	go func() { slowDone <- robots.allows(context.Background(), "http://slow.example/private") }()
+
+	fastDone := make(chan bool)
+	go func() { fastDone <- robots.allows(context.Background(), "http://fast.example/private") }()
+	select {
+	case allowed := <-fastDone:
+		assert.False(t, allowed)
+	case <-time.After(time.Second):
+		t.Fatal("a slow robots.txt blocked the checks of another origin")
+	}
+
+	close(release)
+	assert.False(t, <-slowDone)
+}
+
+func TestRobotsCacheTakesAProbeSlot(t *testing.T) {
+	var requests atomic.Int32
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		requests.Add(1)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+	})
+	robots := newRobotsCache(&http.Client{Transport: transport})
+
+	// every slot is taken, so the fetch waits until the analysis gives up
+	sem := make(chan struct{}, 1)
+	sem <- struct{}{}
+	ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), probeSlotsKey{}, sem), 20*time.Millisecond)
+	defer cancel()
+
+	assert.True(t, robots.allows(ctx, "http://example.com/private"))
+	assert.Zero(t, requests.Load())
+}
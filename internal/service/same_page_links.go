@@ -0,0 +1,43 @@
+package service
+
+import (
+	"strings"
+)
+
+// isSamePageHref reports whether href only changes the fragment or query of
+// the current page, e.g. "#section" or "?page=2"
+func isSamePageHref(href string) bool {
+	href = strings.TrimSpace(href)
+	return strings.HasPrefix(href, "#") || strings.HasPrefix(href, "?")
+}
+
+// isAnchorHref reports whether href is fragment-only, like "#top", or empty,
+// and so only moves within the current page. Other hrefs that resolve to the
+// page, like "/" on a homepage, are ordinary internal links.
+func isAnchorHref(href string) bool {
+	href = strings.TrimSpace(href)
+	return href == "" || strings.HasPrefix(href, "#")
+}
+
+// splitAnchorLinks counts the anchor links and returns the others
+func splitAnchorLinks(links []linkInfo) (int, []linkInfo) {
+	return splitLinks(links, func(link linkInfo) bool { return link.anchor })
+}
+
+// splitSamePageLinks counts the same-page links and returns the others
+func splitSamePageLinks(links []linkInfo) (int, []linkInfo) {
+	return splitLinks(links, func(link linkInfo) bool { return link.samePage })
+}
+
+func splitLinks(links []linkInfo, match func(linkInfo) bool) (int, []linkInfo) {
+	matched := 0
+	rest := make([]linkInfo, 0, len(links))
+	for _, link := range links {
+		if match(link) {
+			matched++
+			continue
+		}
+		rest = append(rest, link)
+	}
+	return matched, rest
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIsSamePageHref(t *testing.T) {
+	tests := []struct {
+		href     string
+		expected bool
+	}{
+		{href: "#frag", expected: true},
+		{href: " #frag", expected: true},
+		{href: "?q=1", expected: true},
+		{href: "./relative", expected: false},
+		{href: "/about#team", expected: false},
+		{href: "http://example.com/#frag", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.href, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isSamePageHref(tt.href))
+		})
+	}
+}
+
+func TestAnalyzeSamePageLinks(t *testing.T) {
+	htmlContent := `<html><body>
+		<a href="#frag">Jump</a>
+		<a href="?q=1">Next page</a>
+		<a href="./relative">Relative</a>
+		<a href="http://other.com/">External</a>
+	</body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com/docs/", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+
+	tests := []struct {
+		name         string
+		opts         []AnalyzerOption
+		wantSamePage int
+		wantInternal int
+	}{
+		{name: "disabled", wantSamePage: 0, wantInternal: 2},
+		{name: "enabled", opts: []AnalyzerOption{WithSamePageLinks()}, wantSamePage: 1, wantInternal: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// keep link checks off the network
+			opts := append(tt.opts, WithSkippedLinkHosts("example.com", "other.com"))
+			result, err := NewAnalyzer(log.New(), mockWebClient, opts...).Analyze(context.Background(), "http://example.com/docs/")
+			assert.NoError(t, err)
+			assert.Equal(t, 4, result.TotalLinks)
+			assert.Equal(t, tt.wantSamePage, result.SamePageLinks)
+			assert.Equal(t, 1, result.AnchorLinks)
+			assert.Equal(t, tt.wantInternal, result.InternalLinks)
+			assert.Equal(t, 1, result.ExternalLinks)
+		})
+	}
+}
+
+func TestLinkFromAnchorClassification(t *testing.T) {
+	baseURL, _ := url.Parse("https://example.com/docs/")
+	tests := []struct {
+		href         string
+		wantURL      string
+		wantInternal bool
+		wantAnchor   bool
+	}{
+		{href: "#top", wantURL: "https://example.com/docs/#top", wantInternal: true, wantAnchor: true},
+		{href: "https://example.com/docs/#intro", wantURL: "https://example.com/docs/#intro", wantInternal: true},
+		{href: "./", wantURL: "https://example.com/docs/", wantInternal: true},
+		{href: "?q=1", wantURL: "https://example.com/docs/?q=1", wantInternal: true},
+		{href: "//cdn.example.net/lib.js", wantURL: "https://cdn.example.net/lib.js"},
+		{href: "//example.com/about", wantURL: "https://example.com/about", wantInternal: true},
+		{href: "/about#team", wantURL: "https://example.com/about#team", wantInternal: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.href, func(t *testing.T) {
+			doc := parseHTMLString(t, `<a href="`+tt.href+`">link</a>`)
+			links := collectLinks(context.Background(), doc, baseURL)
+			if assert.Len(t, links, 1) {
+				assert.Equal(t, tt.wantURL, links[0].url)
+				assert.Equal(t, tt.wantInternal, links[0].isInternal)
+				assert.Equal(t, tt.wantAnchor, links[0].anchor)
+			}
+		})
+	}
+}
+
+func TestAnalyzeAnchorLinks(t *testing.T) {
+	htmlContent := `<html><body>
+		<a href="#top">Top</a>
+		<a href="#top">Top again</a>
+		<a href="?q=1">Search</a>
+		<a href="//cdn.example.net/path">CDN</a>
+		<a href="/about">About</a>
+	</body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com/", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithSkippedLinkHosts("example.com", "cdn.example.net"))
+	result, err := analyzer.Analyze(context.Background(), "http://example.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, 5, result.TotalLinks)
+	assert.Equal(t, 1, result.AnchorLinks)
+	assert.Equal(t, 2, result.InternalLinks)
+	assert.Equal(t, 1, result.ExternalLinks)
+}
+
+func TestAnalyzeCountsSelfLinksAsInternal(t *testing.T) {
+	htmlContent := `<html><body>
+		<a href="/">Home</a>
+		<a href="http://example.com/">Home again</a>
+		<a href="#top">Top</a>
+	</body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com/", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithSkippedLinkHosts("example.com"))
+	result, err := analyzer.Analyze(context.Background(), "http://example.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.AnchorLinks)
+	assert.Equal(t, 1, result.InternalLinks, "a link to the homepage from the homepage is internal")
+	assert.Equal(t, 1, result.SkippedLinks, "and goes through the link checks")
+}
+
+func TestAnalyzeDoesNotProbeAnchorLinks(t *testing.T) {
+	htmlContent := `<html><body>
+		<a href="#top">Top</a>
+		<a href=" #section">Section</a>
+		<a href="/about">About</a>
+	</body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com/", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+	var probed []string
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		probed = append(probed, req.URL.String())
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithLinkCheckTransport(transport), WithLinkCheckConcurrency(1))
+	result, err := analyzer.Analyze(context.Background(), "http://example.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.AnchorLinks)
+	assert.Equal(t, []string{"http://example.com/about"}, probed)
+	assert.Zero(t, result.SkippedLinks)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// scriptCounts classifies executable scripts by how they affect rendering
+type scriptCounts struct {
+	blocking int
+	async    int
+	deferred int
+}
+
+// countScripts classifies the page's scripts. External classic scripts block
+// the parser unless marked async or defer, and module scripts are deferred
+// unless marked async. Inline classic scripts ignore async/defer and are
+// counted as blocking only in <head>, where they hold up first render.
+// Data blocks such as application/ld+json are not scripts and are skipped.
+func countScripts(ctx context.Context, doc *html.Node) scriptCounts {
+	return walkDocument(ctx, doc, nil, walkOptions{}).scripts
+}
+
+func classifyScript(n *html.Node, inHead bool, counts *scriptCounts) {
+	var hasSrc, isAsync, isDefer bool
+	scriptType := ""
+	for _, attr := range n.Attr {
+		switch strings.ToLower(attr.Key) {
+		case "src":
+			hasSrc = strings.TrimSpace(attr.Val) != ""
+		case "async":
+			isAsync = true
+		case "defer":
+			isDefer = true
+		case "type":
+			scriptType = strings.ToLower(strings.TrimSpace(attr.Val))
+		}
+	}
+
+	isModule := scriptType == "module"
+	if !isModule && !isClassicScriptType(scriptType) {
+		return
+	}
+
+	switch {
+	case isAsync && (hasSrc || isModule):
+		counts.async++
+	case isModule || (hasSrc && isDefer):
+		counts.deferred++
+	case hasSrc || inHead:
+		counts.blocking++
+	}
+}
+
+// isClassicScriptType reports whether a script type attribute denotes a
+// classic JavaScript script
+func isClassicScriptType(scriptType string) bool {
+	switch scriptType {
+	case "", "text/javascript", "application/javascript", "text/ecmascript", "application/ecmascript":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountScripts(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		htmlStr  string
+		expected scriptCounts
+	}{
+		{
+			name:    "no scripts",
+			htmlStr: `<html><head></head><body><p>Hi</p></body></html>`,
+		},
+		{
+			name: "async defer and plain",
+			htmlStr: `<html><head>
+				<script src="/analytics.js" async></script>
+				<script src="/app.js" defer></script>
+				<script src="/vendor.js"></script>
+				<script src="/both.js" async defer></script>
+			</head><body><script src="/footer.js"></script></body></html>`,
+			expected: scriptCounts{blocking: 2, async: 2, deferred: 1},
+		},
+		{
+			name: "inline scripts",
+			htmlStr: `<html><head>
+				<script>window.config = {}</script>
+				<script defer>console.log("defer has no effect inline")</script>
+			</head><body><script>console.log("late")</script></body></html>`,
+			expected: scriptCounts{blocking: 2},
+		},
+		{
+			name: "modules and data blocks",
+			htmlStr: `<html><head>
+				<script type="module" src="/main.mjs"></script>
+				<script type="module" async src="/widget.mjs"></script>
+				<script type="application/ld+json">{"@type": "Organization"}</script>
+				<script type="text/template"><p>{{name}}</p></script>
+			</head><body></body></html>`,
+			expected: scriptCounts{async: 1, deferred: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, countScripts(ctx, parseHTMLString(t, tt.htmlStr)))
+		})
+	}
+}
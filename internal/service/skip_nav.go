@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"web_page_analyzer/internal/domain/models"
+
+	"golang.org/x/net/html"
+)
+
+func analyzeSkipNavLink(ctx context.Context, result *models.AnalysisResult) error {
+	result.HasSkipNavLink = documentFactsFor(ctx, result).hasSkipNavLink
+	return nil
+}
+
+// hasSkipNavLink reports whether the first link in the document jumps to a
+// fragment that exists on the page, e.g. <a href="#main">Skip to content</a>
+func hasSkipNavLink(ctx context.Context, doc *html.Node) bool {
+	return walkDocument(ctx, doc, nil, walkOptions{}).hasSkipNavLink
+}
+
+// jumpsToTarget reports whether link's href is a fragment naming one of
+// targets, the ids and anchor names of the document
+func jumpsToTarget(ctx context.Context, link *html.Node, targets map[string]bool) bool {
+	if link == nil {
+		return false
+	}
+	fragment, ok := strings.CutPrefix(strings.TrimSpace(getHref(ctx, link)), "#")
+	if !ok || fragment == "" {
+		return false
+	}
+	if unescaped, err := url.PathUnescape(fragment); err == nil {
+		fragment = unescaped
+	}
+	return targets[fragment]
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasSkipNavLink(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		htmlStr  string
+		expected bool
+	}{
+		{
+			name: "valid skip link",
+			htmlStr: `<html><body><a href="#main" class="skip">Skip to content</a>
+				<nav><a href="/">Home</a></nav><main id="main"><h1>Hello</h1></main></body></html>`,
+			expected: true,
+		},
+		{
+			name:     "named anchor target",
+			htmlStr:  `<html><body><a href="#content">Skip</a><nav></nav><a name="content"></a></body></html>`,
+			expected: true,
+		},
+		{
+			name:    "no skip link",
+			htmlStr: `<html><body><nav><a href="/">Home</a><a href="#main">Skip</a></nav><main id="main"></main></body></html>`,
+		},
+		{
+			name:    "broken skip link",
+			htmlStr: `<html><body><a href="#main">Skip to content</a><main id="content"></main></body></html>`,
+		},
+		{
+			name:    "bare hash",
+			htmlStr: `<html><body><a href="#">Top</a><main id=""></main></body></html>`,
+		},
+		{
+			name:    "no links",
+			htmlStr: `<html><body><main id="main"></main></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseHTMLString(t, tt.htmlStr)
+			assert.Equal(t, tt.expected, hasSkipNavLink(ctx, doc))
+		})
+	}
+}
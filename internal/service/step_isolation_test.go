@@ -0,0 +1,122 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+	"web_page_analyzer/internal/domain/models"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/net/html"
+)
+
+// fullFeaturedPage exercises every analyzer
+func fullFeaturedPage(assetsURL string) string {
+	return `<!DOCTYPE html><html lang="en"><head><title>Everything</title>
+		<meta http-equiv="Content-Security-Policy" content="script-src 'self'">
+		<meta http-equiv="refresh" content="30;url=/next">
+		<link rel="stylesheet" href="` + assetsURL + `/site.css">
+		<link rel="preload" href="/font.woff2" as="font">
+		<script src="/app.js"></script><script async src="/a.js"></script>
+		<!--[if IE]><p>old</p><![endif]--><!-- note -->
+	</head><body>
+		<header><nav><a href="/">Home</a></nav></header>
+		<main><h1>Title</h1><h2 onclick="go()">Sub</h2>
+			<a href="http://example.com/old">Old</a>
+			<a href="` + assetsURL + `/missing">External</a>
+			<a href="mailto:me@example.com">Mail</a>
+			<form><input type="password"></form>
+		</main>
+	</body></html>`
+}
+
+func allOptions() models.AnalysisOptions {
+	return models.AnalysisOptions{StrictHTML: true, CheckStylesheets: true, PageSize: true, Freshness: true, AnchorText: true}
+}
+
+func newAssetsServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestAnalysisStepsWriteDistinctFields guards the analysisStep contract: steps
+// run concurrently on one result, so no two of them may write the same field.
+func TestAnalysisStepsWriteDistinctFields(t *testing.T) {
+	assets := newAssetsServer(t)
+	body := []byte(fullFeaturedPage(assets.URL))
+	doc, err := html.Parse(bytes.NewReader(body))
+	assert.NoError(t, err)
+	baseURL, _ := url.Parse("https://example.com/")
+	input := models.AnalysisResult{
+		BaseUrl:        baseURL,
+		HtmlNode:       doc,
+		BodyByte:       body,
+		ResponseHeader: http.Header{"Content-Type": {"text/html"}},
+	}
+
+	// keep link checks off the network
+	analyzer := NewAnalyzer(log.New(), new(MockWebClient), WithContentFingerprint(), WithThinSectionWords(50), WithSkippedLinkHosts("example.com"))
+	network, dom := analyzer.analysisSteps(allOptions())
+
+	writers := map[string]string{}
+	for _, step := range append(network, dom...) {
+		result := input
+		assert.NoError(t, step.run(context.Background(), &result), step.name)
+
+		before, after := reflect.ValueOf(input), reflect.ValueOf(result)
+		for i := 0; i < before.NumField(); i++ {
+			if reflect.DeepEqual(before.Field(i).Interface(), after.Field(i).Interface()) {
+				continue
+			}
+			field := before.Type().Field(i).Name
+			if other, ok := writers[field]; ok {
+				t.Errorf("%s and %s both write %s", other, step.name, field)
+			}
+			writers[field] = step.name
+		}
+	}
+	assert.NotEmpty(t, writers)
+}
+
+// TestAnalyzeConcurrentAnalysesAreRaceFree runs full analyses side by side.
+// It is most useful under go test -race.
+func TestAnalyzeConcurrentAnalysesAreRaceFree(t *testing.T) {
+	assets := newAssetsServer(t)
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "https://example.com/", http.MethodGet).Return(htmlResponse(fullFeaturedPage(assets.URL)), nil)
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithContentFingerprint(), WithSkippedLinkHosts("example.com"), WithResolver(&stubResolver{
+		hosts: map[string][]string{"example.com": {"93.184.216.34"}},
+	}))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			opts := allOptions()
+			opts.ResolveHost = true
+			checks := 0
+			opts.OnLinkCheck = func(models.LinkCheck) { checks++ }
+			result, err := analyzer.AnalyzeWithOptions(context.Background(), "https://example.com/", opts)
+			assert.NoError(t, err)
+			assert.Equal(t, "Everything", result.Title)
+			assert.Empty(t, result.BrokenStylesheets)
+			assert.Equal(t, []string{"93.184.216.34"}, result.ResolvedIPs)
+			assert.Positive(t, checks)
+		}()
+	}
+	wg.Wait()
+}
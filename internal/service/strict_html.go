@@ -0,0 +1,88 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"golang.org/x/net/html"
+)
+
+// maxMalformedDetails caps how many problems validateHTML reports
+const maxMalformedDetails = 20
+
+// voidElements never have content or an end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// optionalEndTagElements may legally be left unclosed
+var optionalEndTagElements = map[string]bool{
+	"html": true, "head": true, "body": true, "p": true, "li": true, "dt": true, "dd": true,
+	"option": true, "optgroup": true, "tr": true, "td": true, "th": true, "thead": true,
+	"tbody": true, "tfoot": true, "colgroup": true, "caption": true, "rb": true, "rt": true,
+	"rtc": true, "rp": true,
+}
+
+// validateHTML tokenizes body and reports markup the lenient parser silently
+// repairs: stray end tags, elements closed out of order or never closed, and
+// tokenizer errors other than EOF. An empty result means the markup is well
+// formed.
+func validateHTML(ctx context.Context, body []byte) []string {
+	var problems []string
+	report := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	var open []string
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+loop:
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				report(`tokenizer error: %v`, err)
+			}
+			break loop
+		case html.StartTagToken:
+			name, _ := tokenizer.TagName()
+			if !voidElements[string(name)] {
+				open = append(open, string(name))
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			tag := string(name)
+			if voidElements[tag] {
+				report(`end tag </%s> for void element`, tag)
+				continue
+			}
+			i := len(open) - 1
+			for i >= 0 && open[i] != tag {
+				i--
+			}
+			if i < 0 {
+				report(`stray end tag </%s>`, tag)
+				continue
+			}
+			for _, unclosed := range open[i+1:] {
+				if !optionalEndTagElements[unclosed] {
+					report(`<%s> not closed before </%s>`, unclosed, tag)
+				}
+			}
+			open = open[:i]
+		}
+	}
+
+	for _, unclosed := range open {
+		if !optionalEndTagElements[unclosed] {
+			report(`<%s> never closed`, unclosed)
+		}
+	}
+
+	if len(problems) > maxMalformedDetails {
+		problems = append(problems[:maxMalformedDetails], fmt.Sprintf(`and %d more`, len(problems)-maxMalformedDetails))
+	}
+	return problems
+}
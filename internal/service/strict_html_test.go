@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"web_page_analyzer/internal/domain/models"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestValidateHTML(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		htmlStr  string
+		expected []string
+	}{
+		{
+			name: "well formed",
+			htmlStr: `<!DOCTYPE html><html><head><title>Ok</title><meta charset="utf-8"></head>
+				<body><div><p>Text<br>more</div><ul><li>One<li>Two</ul><script>if (a < b) {}</script></body></html>`,
+		},
+		{
+			name:     "misnested",
+			htmlStr:  `<html><body><div><span><b>bold</span></b></div></body></html>`,
+			expected: []string{`<b> not closed before </span>`, `stray end tag </b>`},
+		},
+		{
+			name:     "unclosed and void end tag",
+			htmlStr:  `<html><body><section><div>open</body></html><img></img>`,
+			expected: []string{`<section> not closed before </body>`, `<div> not closed before </body>`, `end tag </img> for void element`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, validateHTML(ctx, []byte(tt.htmlStr)))
+		})
+	}
+}
+
+func TestValidateHTMLCapsDetails(t *testing.T) {
+	problems := validateHTML(context.Background(), []byte(strings.Repeat("</span>", 30)))
+	assert.Len(t, problems, maxMalformedDetails+1)
+	assert.Equal(t, "and 10 more", problems[maxMalformedDetails])
+}
+
+func TestAnalyzeStrictHTML(t *testing.T) {
+	malformed := `<html><body><main><div><span>unclosed</div></main></body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "https://example.com", http.MethodGet).Return(htmlResponse(malformed), nil)
+	analyzer := NewAnalyzer(log.New(), mockWebClient)
+
+	lenient, err := analyzer.Analyze(context.Background(), "https://example.com")
+	assert.NoError(t, err)
+	assert.False(t, lenient.MalformedHTML)
+	assert.Empty(t, lenient.MalformedHTMLDetails)
+
+	strict, err := analyzer.AnalyzeWithOptions(context.Background(), "https://example.com", models.AnalysisOptions{StrictHTML: true})
+	assert.NoError(t, err)
+	assert.True(t, strict.MalformedHTML)
+	assert.Equal(t, []string{`<span> not closed before </div>`}, strict.MalformedHTMLDetails)
+	assert.Equal(t, lenient.Title, strict.Title, "lenient analysis still runs")
+}
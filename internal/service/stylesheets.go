@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"web_page_analyzer/internal/domain/models"
+
+	"golang.org/x/net/html"
+)
+
+func (a *Analyzer) analyzeStylesheets(ctx context.Context, result *models.AnalysisResult) error {
+	robots := a.robotsFor(ctx)
+	var toCheck []linkInfo
+	for _, stylesheet := range documentFactsFor(ctx, result).stylesheets {
+		if a.skipReason(ctx, stylesheet, robots) == "" {
+			toCheck = append(toCheck, stylesheet)
+		}
+	}
+	for check := range a.probeLinks(ctx, toCheck) {
+		if !check.Accessible {
+			result.BrokenStylesheets = append(result.BrokenStylesheets, check.URL)
+		}
+	}
+	return nil
+}
+
+// collectStylesheets returns the absolute http(s) URLs of the document's
+// <link rel="stylesheet"> elements, each once
+func collectStylesheets(ctx context.Context, doc *html.Node, baseURL *url.URL) []linkInfo {
+	return walkDocument(ctx, doc, baseURL, walkOptions{}).stylesheets
+}
+
+// stylesheetFromLink resolves the href of <link> n against baseURL. ok is
+// false for a missing or unparsable href and for schemes other than http(s).
+func stylesheetFromLink(ctx context.Context, n *html.Node, baseURL *url.URL) (linkInfo, bool) {
+	href := strings.TrimSpace(getAttr(n, "href"))
+	if href == "" {
+		return linkInfo{}, false
+	}
+	u, err := baseURL.Parse(href)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return linkInfo{}, false
+	}
+	return linkInfo{url: u.String(), isInternal: getCanonicalHost(ctx, u) == getCanonicalHost(ctx, baseURL)}, true
+}
+
+func isStylesheetRel(rel string) bool {
+	for _, value := range strings.Fields(strings.ToLower(rel)) {
+		if value == "stylesheet" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"web_page_analyzer/internal/domain/models"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCollectStylesheets(t *testing.T) {
+	baseURL, _ := url.Parse("https://example.com/docs/")
+	doc := parseHTMLString(t, `<html><head>
+		<link rel="stylesheet" href="site.css">
+		<link rel="Alternate Stylesheet" href="https://cdn.example.net/dark.css">
+		<link rel="stylesheet" href="/docs/site.css">
+		<link rel="preload" href="font.woff2">
+		<link rel="stylesheet" href="">
+		<link rel="stylesheet" href="data:text/css,body{}">
+		<link rel="stylesheet" href="https://example.com:443/print.css">
+	</head></html>`)
+
+	assert.Equal(t, []linkInfo{
+		{url: "https://example.com/docs/site.css", isInternal: true},
+		{url: "https://cdn.example.net/dark.css", isInternal: false},
+		{url: "https://example.com:443/print.css", isInternal: true},
+	}, collectStylesheets(context.Background(), doc, baseURL))
+}
+
+func TestAnalyzeReportsBrokenStylesheets(t *testing.T) {
+	assets := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.css" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer assets.Close()
+
+	htmlContent := `<html><head>
+		<link rel="stylesheet" href="` + assets.URL + `/site.css">
+		<link rel="stylesheet" href="` + assets.URL + `/missing.css">
+	</head></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+	analyzer := NewAnalyzer(log.New(), mockWebClient)
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), "http://example.com", models.AnalysisOptions{CheckStylesheets: true})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{assets.URL + "/missing.css"}, result.BrokenStylesheets)
+
+	result, err = analyzer.Analyze(context.Background(), "http://example.com")
+	assert.NoError(t, err)
+	assert.Empty(t, result.BrokenStylesheets, "stylesheets are only checked on request")
+}
+
+func TestAnalyzeSkipsStylesheetsOnSkippedHosts(t *testing.T) {
+	var probed atomic.Int32
+	assets := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probed.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer assets.Close()
+	assetsURL, _ := url.Parse(assets.URL)
+
+	htmlContent := `<html><head>
+		<link rel="stylesheet" href="` + assets.URL + `/missing.css">
+	</head></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithSkippedLinkHosts(assetsURL.Hostname()))
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), "http://example.com", models.AnalysisOptions{CheckStylesheets: true})
+	assert.NoError(t, err)
+	assert.Empty(t, result.BrokenStylesheets)
+	assert.Zero(t, probed.Load(), "stylesheets on skipped hosts are not probed")
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+	"web_page_analyzer/internal/domain/models"
+
+	"golang.org/x/net/html"
+)
+
+func (a *Analyzer) analyzeThinSections(ctx context.Context, result *models.AnalysisResult) error {
+	result.ThinSections = thinHeadings(documentFactsFor(ctx, result).sections, a.thinSectionWords)
+	return nil
+}
+
+type section struct {
+	heading string
+	words   int
+}
+
+// thinSections counts the words between each heading and the next and
+// returns the text of the headings whose section has fewer than minWords
+// words. Text before the first heading and inside scripts or styles is not
+// counted.
+func thinSections(ctx context.Context, doc *html.Node, minWords int) []string {
+	return thinHeadings(walkDocument(ctx, doc, nil, walkOptions{}).sections, minWords)
+}
+
+func thinHeadings(sections []section, minWords int) []string {
+	var thin []string
+	for _, s := range sections {
+		if s.words < minWords {
+			thin = append(thin, s.heading)
+		}
+	}
+	return thin
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThinSections(t *testing.T) {
+	ctx := context.Background()
+	longText := strings.Repeat("word ", 60)
+
+	tests := []struct {
+		name     string
+		htmlStr  string
+		expected []string
+	}{
+		{
+			name: "thin section flagged",
+			htmlStr: `<html><body><p>Intro without a heading.</p>
+				<h1>Overview</h1><p>` + longText + `</p>
+				<h2>Pricing</h2><p>Call us.</p>
+				<h2>Details</h2><section><p>` + longText + `</p></section>
+			</body></html>`,
+			expected: []string{"Pricing"},
+		},
+		{
+			name: "nested text and scripts",
+			htmlStr: `<html><body>
+				<h2>Scripts only</h2><script>` + longText + `</script><style>p { color: red }</style>
+				<h2>Nested</h2><div><ul><li>` + longText + `</li></ul></div>
+				<h3>Empty at the end</h3>
+			</body></html>`,
+			expected: []string{"Scripts only", "Empty at the end"},
+		},
+		{
+			name:    "no headings",
+			htmlStr: `<html><body><p>Just text.</p></body></html>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseHTMLString(t, tt.htmlStr)
+			assert.Equal(t, tt.expected, thinSections(ctx, doc, 50))
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// stepTimings collects how long each part of an analysis took. The parts run
+// concurrently, so every access is locked.
+type stepTimings struct {
+	mu        sync.Mutex
+	durations map[string]time.Duration
+}
+
+func newStepTimings() *stepTimings {
+	return &stepTimings{durations: map[string]time.Duration{}}
+}
+
+// record stores the time since start under name
+func (t *stepTimings) record(name string, start time.Time) time.Duration {
+	took := time.Since(start)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.durations[name] = took
+	return took
+}
+
+// snapshot returns a copy of the durations recorded so far
+func (t *stepTimings) snapshot() map[string]time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	durations := make(map[string]time.Duration, len(t.durations))
+	for name, took := range t.durations {
+		durations[name] = took
+	}
+	return durations
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+	"web_page_analyzer/internal/domain/models"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestStepTimingsConcurrentRecords(t *testing.T) {
+	timings := newStepTimings()
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b", "c", "d"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			timings.record(name, time.Now().Add(-time.Millisecond))
+		}()
+	}
+	wg.Wait()
+
+	snapshot := timings.snapshot()
+	assert.Len(t, snapshot, 4)
+	for _, took := range snapshot {
+		assert.GreaterOrEqual(t, took, time.Millisecond)
+	}
+}
+
+func TestAnalyzeRecordsTimingsForEachStep(t *testing.T) {
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(`<html><head><title>Timed</title></head></html>`), nil)
+	analyzer := NewAnalyzer(log.New(), mockWebClient)
+	opts := models.AnalysisOptions{PageSize: true}
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), "http://example.com", opts)
+	assert.NoError(t, err)
+
+	network, dom := analyzer.analysisSteps(opts)
+	wantKeys := []string{"parseUrl", "getWebPage"}
+	for _, step := range append(network, dom...) {
+		wantKeys = append(wantKeys, step.name)
+	}
+	assert.Len(t, result.Timings, len(wantKeys))
+	for _, key := range wantKeys {
+		assert.Contains(t, result.Timings, key)
+	}
+}
@@ -3,16 +3,30 @@ package service
 import (
 	"bytes"
 	"context"
+	"crypto/x509"
 	"fmt"
+	"io"
+	"mime"
+	"net"
 	"net/http"
 	"net/url"
+	"runtime/debug"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 	"web_page_analyzer/internal/domain/adaptors"
 	"web_page_analyzer/internal/domain/models"
 	"web_page_analyzer/internal/pkg/errors"
+	"web_page_analyzer/internal/pkg/metrics"
+	"web_page_analyzer/internal/pkg/requestid"
+	"web_page_analyzer/internal/pkg/tracing"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/sync/errgroup"
 
 	log "github.com/sirupsen/logrus"
@@ -23,62 +37,649 @@ type WebPageAnalyzer interface {
 	Analyze(url string) (string, error)
 }
 
+// linkCheckObserverKey carries AnalysisOptions.OnLinkCheck to the link checker
+type linkCheckObserverKey struct{}
+
+// probeSlotsKey carries the analysis's probe semaphore, shared by the link,
+// image and stylesheet checks so together they stay within
+// linkCheckConcurrency
+type probeSlotsKey struct{}
+
 type linkInfo struct {
 	url        string
 	isInternal bool
+	// samePage is set for fragment-only and query-only hrefs like "#top" or
+	// "?page=2", which navigate within the current page
+	samePage bool
+	// anchor is set for fragment-only hrefs like "#top", which are never
+	// counted as internal or external links
+	anchor bool
 }
 
 type webPageInfo struct {
 	responseCode int
 	bodyByte     []byte
 	htmlNode     *html.Node
+	contentType  string
+	charset      string
+	ttfb         time.Duration
+	proto        string
+	retries      int
+	header       http.Header
+	certificate  *x509.Certificate
+	truncated    bool
+	finalURL     string
+	redirects    int
+	remoteAddr   string
+}
+
+// defaultMaxDOMDepth is far beyond real-world pages but well within what the
+// recursive traversals can handle
+const defaultMaxDOMDepth = 2000
+
+// defaultMaxMetaTags is far more meta tags than a real page carries
+const defaultMaxMetaTags = 200
+
+// Link checks run defaultLinkCheckConcurrency at a time and give each link
+// defaultLinkCheckTimeout to answer
+const (
+	defaultLinkCheckConcurrency = 20
+	defaultLinkCheckTimeout     = 1 * time.Second
+)
+
+// maxProbeDrainBytes is the most of a link check's response body read before
+// closing it
+const maxProbeDrainBytes = 64 << 10
+
+// defaultCertExpiryWarning is how close to expiry a certificate gets flagged
+const defaultCertExpiryWarning = 30 * 24 * time.Hour
+
+// analysisStep fills in part of the result. DOM steps only read the parsed
+// document; network steps reach out over the network and may fail without
+// invalidating the rest of the result.
+//
+// All steps of an analysis run concurrently on the same result, so a step may
+// read only what is filled in before the steps start (the page, its headers,
+// the base URL and the documentFacts on ctx) and may write only fields no
+// other step writes. Anything shared, like Warnings, is filled in after every
+// step has finished.
+type analysisStep struct {
+	name string
+	run  func(ctx context.Context, result *models.AnalysisResult) error
 }
 
 type Analyzer struct {
-	log       *log.Logger
-	webClient adaptors.WebClient
+	log                  *log.Logger
+	webClient            adaptors.WebClient
+	domAnalyzers         []analysisStep
+	networkAnalyzers     []analysisStep
+	batchConcurrency     int
+	batchPageTimeout     time.Duration
+	analyzerConcurrency  int
+	maxDOMDepth          int
+	linkNormalization    linkNormalization
+	batchSlots           chan struct{}
+	batchQueueTimeout    time.Duration
+	resolver             Resolver
+	certExpiryWarning    time.Duration
+	skipLinkHosts        hostPatterns
+	linkCheckTransport   http.RoundTripper
+	contentFingerprint   bool
+	maxMetaTags          int
+	thinSectionWords     int
+	separateSamePage     bool
+	resourceLimits       resourceLimits
+	linkRedirects        bool
+	respectRobots        bool
+	linkCheckConcurrency int
+	linkCheckTimeout     time.Duration
+	anchorTextMaxChars   int
+	anchorTextMaxLinks   int
+	forwardRequestID     bool
+}
+
+type AnalyzerOption func(*Analyzer)
+
+// WithBatchConcurrency sets how many pages of a batch are analyzed at once
+func WithBatchConcurrency(n int) AnalyzerOption {
+	return func(a *Analyzer) {
+		if n > 0 {
+			a.batchConcurrency = n
+		}
+	}
+}
+
+// WithBatchPageTimeout gives each page of a batch its own deadline, so the
+// pages analyzed last get as long as the first. Zero or less leaves them
+// bounded only by the batch request.
+func WithBatchPageTimeout(timeout time.Duration) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.batchPageTimeout = timeout
+	}
+}
+
+// WithStripQueryStrings ignores the whole query string when comparing links,
+// so links differing only by query count once
+func WithStripQueryStrings() AnalyzerOption {
+	return func(a *Analyzer) {
+		a.linkNormalization.stripQuery = true
+	}
+}
+
+// WithIgnoredQueryParams ignores the given query parameters (e.g. "utm_*",
+// "sessionid") when comparing links
+func WithIgnoredQueryParams(params ...string) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.linkNormalization.ignoredParams = append(a.linkNormalization.ignoredParams, params...)
+	}
+}
+
+// WithSamePageLinks counts query-only links like "?page=2" as SamePageLinks
+// instead of internal links. Fragment-only links like "#top" are always
+// counted as AnchorLinks.
+func WithSamePageLinks() AnalyzerOption {
+	return func(a *Analyzer) {
+		a.separateSamePage = true
+	}
+}
+
+// WithResourceLimits fails analyses with ErrResourceLimit when the page body
+// is over maxBodyBytes or its parsed document has more than maxDOMNodes nodes.
+// Zero or less leaves that limit off.
+func WithResourceLimits(maxBodyBytes int, maxDOMNodes int) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.resourceLimits = resourceLimits{maxBodyBytes: max(maxBodyBytes, 0), maxDOMNodes: max(maxDOMNodes, 0)}
+	}
+}
+
+// WithRequestIDForwarding sends the inbound request ID as X-Request-ID with
+// link, image and robots.txt checks. Without it third-party sites never see it.
+func WithRequestIDForwarding() AnalyzerOption {
+	return func(a *Analyzer) {
+		a.forwardRequestID = true
+	}
+}
+
+// WithLinkRedirects makes link checks follow at most one redirect and report
+// the links that redirected as RedirectingLinks. Without it link checks
+// follow redirects silently.
+func WithLinkRedirects() AnalyzerOption {
+	return func(a *Analyzer) {
+		a.linkRedirects = true
+	}
+}
+
+// WithRobotsTxt skips the accessibility check for internal links and images
+// the site's robots.txt disallows. Each origin's robots.txt is fetched once per analysis.
+func WithRobotsTxt() AnalyzerOption {
+	return func(a *Analyzer) {
+		a.respectRobots = true
+	}
+}
+
+// WithLinkCheckConcurrency sets how many links are checked at once. Zero or
+// less keeps the default.
+func WithLinkCheckConcurrency(n int) AnalyzerOption {
+	return func(a *Analyzer) {
+		if n > 0 {
+			a.linkCheckConcurrency = n
+		}
+	}
+}
+
+// WithLinkCheckTimeout sets how long a link has to answer its check. Zero or
+// less keeps the default.
+func WithLinkCheckTimeout(d time.Duration) AnalyzerOption {
+	return func(a *Analyzer) {
+		if d > 0 {
+			a.linkCheckTimeout = d
+		}
+	}
+}
+
+// WithAnalyzerConcurrency caps how many DOM analyzers of a single analysis run
+// at once. Zero or less leaves them unbounded.
+func WithAnalyzerConcurrency(n int) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.analyzerConcurrency = n
+	}
+}
+
+// WithMaxDOMDepth sets the nesting depth beyond which the document is not
+// traversed. Zero or less disables the guard.
+func WithMaxDOMDepth(n int) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.maxDOMDepth = n
+	}
 }
 
-func NewAnalyzer(log *log.Logger, webClient adaptors.WebClient) *Analyzer {
-	return &Analyzer{
-		log:       log,
-		webClient: webClient,
+// WithMaxConcurrentBatches caps how many batches run at once. A batch arriving
+// at the ceiling waits up to queueTimeout for a slot before failing with
+// ErrTooManyBatches. Zero or less leaves batches unbounded.
+func WithMaxConcurrentBatches(n int, queueTimeout time.Duration) AnalyzerOption {
+	return func(a *Analyzer) {
+		if n > 0 {
+			a.batchSlots = make(chan struct{}, n)
+			a.batchQueueTimeout = queueTimeout
+		}
+	}
+}
+
+// WithResolver sets the resolver used when an analysis asks for the host's
+// addresses
+func WithResolver(resolver Resolver) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.resolver = resolver
+	}
+}
+
+// WithCertExpiryWarning warns when the page's TLS certificate expires within
+// window. Zero or less disables the warning.
+func WithCertExpiryWarning(window time.Duration) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.certExpiryWarning = window
 	}
 }
 
+// WithSkippedLinkHosts skips the accessibility check for links, images and
+// stylesheets whose host matches one of the patterns, e.g. "*.googleapis.com".
+// Skipped links still count as internal or external.
+func WithSkippedLinkHosts(patterns ...string) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.skipLinkHosts = append(a.skipLinkHosts, newHostPatterns(patterns)...)
+	}
+}
+
+// WithLinkCheckTransport sets the transport used to probe links. Nil uses
+// http.DefaultTransport.
+func WithLinkCheckTransport(rt http.RoundTripper) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.linkCheckTransport = rt
+	}
+}
+
+// WithContentFingerprint adds a hash of the page's title, headings and links
+// to each result, for spotting content changes between analyses
+func WithContentFingerprint() AnalyzerOption {
+	return func(a *Analyzer) {
+		a.contentFingerprint = true
+	}
+}
+
+// WithMaxMetaTags caps how many <meta> tags the analyzers inspect. Tags past
+// the cap are still counted. Zero or less inspects every tag.
+func WithMaxMetaTags(n int) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.maxMetaTags = n
+	}
+}
+
+// WithThinSectionWords reports headings followed by fewer than n words before
+// the next heading. Zero or less disables the check.
+func WithThinSectionWords(n int) AnalyzerOption {
+	return func(a *Analyzer) {
+		a.thinSectionWords = n
+	}
+}
+
+func NewAnalyzer(log *log.Logger, webClient adaptors.WebClient, opts ...AnalyzerOption) *Analyzer {
+	a := &Analyzer{
+		log:                  log,
+		webClient:            webClient,
+		batchConcurrency:     5,
+		maxDOMDepth:          defaultMaxDOMDepth,
+		resolver:             net.DefaultResolver,
+		certExpiryWarning:    defaultCertExpiryWarning,
+		maxMetaTags:          defaultMaxMetaTags,
+		linkCheckConcurrency: defaultLinkCheckConcurrency,
+		linkCheckTimeout:     defaultLinkCheckTimeout,
+		anchorTextMaxChars:   defaultAnchorTextMaxChars,
+		anchorTextMaxLinks:   defaultAnchorTextMaxLinks,
+	}
+	a.domAnalyzers = []analysisStep{
+		{name: "countLinks", run: a.analyzeLinkCounts},
+		{name: "countSchemeLinks", run: analyzeSchemeLinks},
+		{name: "countHeadings", run: analyzeHeadings},
+		{name: "getTitle", run: analyzeTitle},
+		{name: "getHTMLVersion", run: analyzeHTMLVersion},
+		{name: "checkLoginForm", run: analyzeLoginForm},
+		{name: "classifyForms", run: analyzeForms},
+		{name: "detectLanguage", run: analyzeLanguage},
+		{name: "countInlineEventHandlers", run: a.analyzeInlineEventHandlers},
+		{name: "isLikelyClientRendered", run: analyzeClientRendering},
+		{name: "getMetaRefresh", run: a.analyzeMetaRefresh},
+		{name: "countMetaTags", run: a.analyzeMetaTags},
+		{name: "getCanonicalURL", run: analyzeCanonicalURL},
+		{name: "buildOutline", run: analyzeOutline},
+		{name: "countLandmarks", run: analyzeLandmarks},
+		{name: "detectSkipNavLink", run: analyzeSkipNavLink},
+		{name: "countComments", run: analyzeComments},
+		{name: "countScripts", run: analyzeScripts},
+		{name: "collectResourceHints", run: analyzeResourceHints},
+	}
+	a.networkAnalyzers = []analysisStep{
+		{name: "checkLinksAccessibility", run: a.analyzeLinksAccessibility},
+		{name: "checkImages", run: a.analyzeImages},
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	if a.contentFingerprint {
+		a.domAnalyzers = append(a.domAnalyzers, analysisStep{name: "contentFingerprint", run: a.analyzeContentFingerprint})
+	}
+	if a.thinSectionWords > 0 {
+		a.domAnalyzers = append(a.domAnalyzers, analysisStep{name: "findThinSections", run: a.analyzeThinSections})
+	}
+	return a
+}
+
+// analysisSteps returns the network and DOM steps to run for opts. The
+// returned slices never alias the analyzer's own lists.
+func (a *Analyzer) analysisSteps(opts models.AnalysisOptions) (network []analysisStep, dom []analysisStep) {
+	if !opts.SkipNetworkChecks {
+		network = slices.Clone(a.networkAnalyzers)
+	}
+	if opts.CheckStylesheets {
+		network = append(network, analysisStep{name: "checkStylesheets", run: a.analyzeStylesheets})
+	}
+	dom = slices.Clone(a.domAnalyzers)
+	if opts.StrictHTML {
+		dom = append(dom, analysisStep{name: "validateHTML", run: analyzeStrictHTML})
+	}
+	if opts.PageSize {
+		dom = append(dom, analysisStep{name: "measurePageSize", run: analyzePageSize})
+	}
+	if opts.Freshness {
+		dom = append(dom, analysisStep{name: "readFreshness", run: analyzeFreshness})
+	}
+	if opts.AnchorText {
+		dom = append(dom, analysisStep{name: "captureAnchorText", run: a.analyzeAnchorTexts})
+	}
+	return network, dom
+}
+
+func (a *Analyzer) analyzeLinksAccessibility(ctx context.Context, result *models.AnalysisResult) error {
+	// links back to the page itself were fetched with it, there is nothing to probe
+	_, links := splitAnchorLinks(a.normalizedLinks(ctx, result))
+	robots := a.robotsFor(ctx)
+
+	observe := linkCheckObserver(ctx)
+	toCheck := make([]linkInfo, 0, len(links))
+	for _, link := range links {
+		reason := a.skipReason(ctx, link, robots)
+		switch reason {
+		case "":
+			toCheck = append(toCheck, link)
+			continue
+		case models.SkipReasonRobots:
+			result.RobotsSkippedLinks++
+		}
+		result.SkippedLinks++
+		if observe != nil {
+			observe(models.LinkCheck{URL: link.url, Internal: link.isInternal, Skipped: true, SkipReason: reason})
+		}
+	}
+
+	result.InaccessibleLinks, result.RedirectingLinks = a.checkLinksAccessibility(ctx, toCheck)
+	return nil
+}
+
+// skipReason returns why link is not probed, or "" when it is: its host is
+// one the operator skips, or it is internal and robots disallows it
+func (a *Analyzer) skipReason(ctx context.Context, link linkInfo, robots *robotsCache) string {
+	switch {
+	case a.skipLinkHosts.matchURL(link.url):
+		return models.SkipReasonHost
+	case robots != nil && link.isInternal && !robots.allows(ctx, link.url):
+		return models.SkipReasonRobots
+	}
+	return ""
+}
+
+// robotsFor returns the robots.txt cache shared by the analysis on ctx, or a
+// new one outside an analysis. It is nil unless WithRobotsTxt is set.
+func (a *Analyzer) robotsFor(ctx context.Context) *robotsCache {
+	if !a.respectRobots {
+		return nil
+	}
+	if robots, ok := ctx.Value(robotsCacheKey{}).(*robotsCache); ok {
+		return robots
+	}
+	return newRobotsCache(a.probeClient())
+}
+
+// analyzeLinkCounts counts every anchor in TotalLinks. All other link counts,
+// like InternalLinks and ExternalLinks, count unique URLs after normalization,
+// matching the links that are probed.
+func (a *Analyzer) analyzeLinkCounts(ctx context.Context, result *models.AnalysisResult) error {
+	links := a.normalizedLinks(ctx, result)
+	result.TotalLinks = len(documentFactsFor(ctx, result).links)
+	result.UniqueLinks = countUniqueLinks(links, a.linkNormalization)
+	result.InsecureInternalLinks = insecureInternalLinks(links, result.BaseUrl)
+	result.AnchorLinks, links = splitAnchorLinks(links)
+	if a.separateSamePage {
+		result.SamePageLinks, links = splitSamePageLinks(links)
+	}
+	result.InternalLinks, result.ExternalLinks = tallyLinks(links)
+	return nil
+}
+
+func analyzeSchemeLinks(ctx context.Context, result *models.AnalysisResult) error {
+	counts := documentFactsFor(ctx, result).linksByScheme
+	if len(counts) > 0 {
+		result.LinksByScheme = counts
+	}
+	result.MailtoLinks = counts["mailto"]
+	result.TelLinks = counts["tel"]
+	other := map[string]int{}
+	for scheme, n := range counts {
+		switch scheme {
+		case "http", "https", "mailto", "tel", "fragment", "relative":
+		default:
+			other[scheme] = n
+		}
+	}
+	if len(other) > 0 {
+		result.OtherSchemeLinks = other
+	}
+	return nil
+}
+
+func analyzeHeadings(ctx context.Context, result *models.AnalysisResult) error {
+	result.Headings = documentFactsFor(ctx, result).headings
+	return nil
+}
+
+func analyzeTitle(ctx context.Context, result *models.AnalysisResult) error {
+	result.Title = documentFactsFor(ctx, result).title
+	return nil
+}
+
+func analyzeHTMLVersion(ctx context.Context, result *models.AnalysisResult) error {
+	result.HTMLVersion = getHTMLVersion(ctx, result.HtmlNode)
+	result.Doctype = readDoctype(result.BodyByte)
+	return nil
+}
+
+func analyzeLoginForm(ctx context.Context, result *models.AnalysisResult) error {
+	// derived from the same forms as LoginForms, so the two always agree
+	result.HasLoginForm = len(documentFactsFor(ctx, result).loginForms) > 0
+	return nil
+}
+
+func (a *Analyzer) analyzeInlineEventHandlers(ctx context.Context, result *models.AnalysisResult) error {
+	result.InlineEventHandlers = documentFactsFor(ctx, result).inlineEventHandlers
+	tags, _ := a.metaTagsFor(ctx, result)
+	result.ContentSecurityPolicy = getMetaCSP(ctx, tags)
+	return nil
+}
+
+func analyzeLandmarks(ctx context.Context, result *models.AnalysisResult) error {
+	result.Landmarks = documentFactsFor(ctx, result).landmarks
+	return nil
+}
+
+func analyzeComments(ctx context.Context, result *models.AnalysisResult) error {
+	facts := documentFactsFor(ctx, result)
+	result.CommentCount, result.ConditionalComments = facts.comments, facts.conditionalComments
+	return nil
+}
+
+func analyzeScripts(ctx context.Context, result *models.AnalysisResult) error {
+	counts := documentFactsFor(ctx, result).scripts
+	result.BlockingScripts = counts.blocking
+	result.AsyncScripts = counts.async
+	result.DeferScripts = counts.deferred
+	return nil
+}
+
+func analyzeResourceHints(ctx context.Context, result *models.AnalysisResult) error {
+	result.ResourceHints = withHeaderHints(documentFactsFor(ctx, result).resourceHints, result.BaseUrl, result.ResponseHeader.Values("Link"))
+	return nil
+}
+
+func analyzeStrictHTML(ctx context.Context, result *models.AnalysisResult) error {
+	result.MalformedHTMLDetails = validateHTML(ctx, result.BodyByte)
+	result.MalformedHTML = len(result.MalformedHTMLDetails) > 0
+	return nil
+}
+
+func analyzeOutline(ctx context.Context, result *models.AnalysisResult) error {
+	result.Outline = nestHeadings(documentFactsFor(ctx, result).headingTexts)
+	return nil
+}
+
+func (a *Analyzer) analyzeMetaRefresh(ctx context.Context, result *models.AnalysisResult) error {
+	tags, _ := a.metaTagsFor(ctx, result)
+	result.MetaRefreshURL, result.MetaRefreshDelay = getMetaRefresh(ctx, tags, result.BaseUrl)
+	return nil
+}
+
+func analyzeClientRendering(ctx context.Context, result *models.AnalysisResult) error {
+	result.LikelyClientRendered = documentFactsFor(ctx, result).likelyClientRendered
+	return nil
+}
+
 func (a *Analyzer) Analyze(ctx context.Context, userURL string) (*models.AnalysisResult, error) {
-	a.log.Debug(`analyze web page started...`)
+	return a.AnalyzeWithOptions(ctx, userURL, models.AnalysisOptions{})
+}
+
+func (a *Analyzer) AnalyzeWithOptions(ctx context.Context, userURL string, opts models.AnalysisOptions) (*models.AnalysisResult, error) {
+	return a.observedAnalyze(ctx, userURL, opts, a.fetchedPage(userURL, opts))
+}
+
+// AnalyzeHTML analyzes the given HTML instead of fetching a page. Relative
+// links resolve against baseURL, which must be an absolute http(s) url.
+func (a *Analyzer) AnalyzeHTML(ctx context.Context, body []byte, baseURL string) (*models.AnalysisResult, error) {
+	return a.AnalyzeHTMLWithOptions(ctx, body, baseURL, models.AnalysisOptions{})
+}
+
+func (a *Analyzer) AnalyzeHTMLWithOptions(ctx context.Context, body []byte, baseURL string, opts models.AnalysisOptions) (*models.AnalysisResult, error) {
+	return a.observedAnalyze(ctx, baseURL, opts, a.suppliedPage(body))
+}
+
+func (a *Analyzer) observedAnalyze(ctx context.Context, userURL string, opts models.AnalysisOptions, page pageSource) (*models.AnalysisResult, error) {
+	metrics.AnalysesInFlight.Inc()
+	defer metrics.AnalysesInFlight.Dec()
+	result, err := a.analyze(ctx, userURL, opts, page)
+	metrics.CountAnalysis(analysisOutcome(err))
+	if err == nil {
+		metrics.ObservePage(pageStats(result))
+	}
+	return result, err
+}
+
+// pageSource loads the page an analysis runs on
+type pageSource struct {
+	// step names the load in the analysis timings
+	step string
+	load func(ctx context.Context) (webPageInfo, error)
+}
+
+// fetchedPage fetches userURL, as normalized by ParseURL, with the request
+// header opts ask for
+func (a *Analyzer) fetchedPage(userURL string, opts models.AnalysisOptions) pageSource {
+	return pageSource{step: "getWebPage", load: func(ctx context.Context) (webPageInfo, error) {
+		u, err := ParseURL(userURL)
+		if err != nil {
+			return webPageInfo{}, err
+		}
+		if header := fetchHeader(opts); len(header) > 0 {
+			ctx = adaptors.ContextWithRequestHeader(ctx, header)
+		}
+		return getWebPage(ctx, u.String(), a.webClient, a.resourceLimits)
+	}}
+}
+
+// suppliedPage parses body as the page, within the same resource limits as a
+// fetched one
+func (a *Analyzer) suppliedPage(body []byte) pageSource {
+	return pageSource{step: "parseHTML", load: func(ctx context.Context) (webPageInfo, error) {
+		doc, err := parsePage(ctx, body, a.resourceLimits)
+		if err != nil {
+			return webPageInfo{}, err
+		}
+		return webPageInfo{bodyByte: body, htmlNode: doc, contentType: "text/html", header: http.Header{}}, nil
+	}}
+}
+
+func (a *Analyzer) analyze(ctx context.Context, userURL string, opts models.AnalysisOptions, page pageSource) (*models.AnalysisResult, error) {
+	a.logger(ctx).Debug(`analyze web page started...`)
+
+	ctx, span := tracing.Tracer().Start(ctx, `analyze`, trace.WithAttributes(attribute.String(`url.full`, userURL)))
+	defer span.End()
 
 	result := &models.AnalysisResult{}
-	g, ctx := errgroup.WithContext(ctx)
+	timings := newStepTimings()
+	// every return below comes after the goroutines recording timings are done
+	defer func() {
+		result.Timings = timings.snapshot()
+	}()
+	// Each group gets its own derived context: errgroup cancels it once Wait
+	// returns, so reusing it for the next stage would start that stage cancelled.
+	g, prepareCtx := errgroup.WithContext(ctx)
 
 	var (
 		parsedURL *url.URL
+		parseErr  error
 		pageInfo  webPageInfo
 	)
 
-	g.Go(func() error {
+	g.Go(func() (err error) {
+		defer a.recoverPanic(prepareCtx, "parseUrl", &err)
 		funcStartTime := time.Now()
 		defer func() {
-			a.log.Debugf("parseUrl took %v", time.Since(funcStartTime))
+			a.logger(ctx).Debugf("parseUrl took %v", timings.record("parseUrl", funcStartTime))
 		}()
-		u, err := parseUrl(ctx, userURL)
+		u, err := ParseURL(userURL)
 		if err != nil {
-			a.log.WithContext(ctx).WithError(err).Error(`failed to parse url`)
+			a.logger(prepareCtx).WithError(err).Error(`failed to parse url`)
+			parseErr = err
 			return err
 		}
 		parsedURL = u
+
+		if opts.BaseURL != "" {
+			u, err = ParseURL(opts.BaseURL)
+			if err != nil {
+				a.logger(prepareCtx).WithError(err).Error(`failed to parse base url`)
+				parseErr = err
+				return err
+			}
+			parsedURL = u
+		}
 		return nil
 	})
 
-	g.Go(func() error {
+	g.Go(func() (err error) {
+		defer a.recoverPanic(prepareCtx, page.step, &err)
 		funcStartTime := time.Now()
 		defer func() {
-			a.log.Debugf("getWebPage took %v", time.Since(funcStartTime))
+			a.logger(ctx).Debugf("%s took %v", page.step, timings.record(page.step, funcStartTime))
 		}()
-		pi, err := getWebPage(ctx, userURL, a.webClient)
+		pi, err := page.load(prepareCtx)
 		if err != nil {
-			a.log.WithContext(ctx).WithError(err).Error(`failed to get web page`)
+			a.logger(prepareCtx).WithError(err).Error(`failed to get web page`)
 			return err
 		}
 		pageInfo = pi
@@ -86,196 +687,373 @@ func (a *Analyzer) Analyze(ctx context.Context, userURL string) (*models.Analysi
 	})
 
 	if err := g.Wait(); err != nil {
+		// a bad url fails the fetch too, report the cause whichever came first
+		if parseErr != nil {
+			err = parseErr
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, `failed to prepare web page or URL`)
 		return result, errors.Wrap(err, "failed to prepare web page or URL")
 	}
 
 	result.BaseUrl = parsedURL
 	result.StatusCode = pageInfo.responseCode
 	result.BodyByte = pageInfo.bodyByte
+	result.ResponseHeader = pageInfo.header
 	result.HtmlNode = pageInfo.htmlNode
+	result.ContentType = pageInfo.contentType
+	result.Charset = pageInfo.charset
+	result.TTFBMs = pageInfo.ttfb.Milliseconds()
+	result.HTTPProtocol = pageInfo.proto
+	result.FetchRetries = pageInfo.retries
+	result.Truncated = pageInfo.truncated
+	result.FinalURL = pageInfo.finalURL
+	if result.FinalURL == "" {
+		result.FinalURL = userURL
+	}
+	result.RedirectCount = pageInfo.redirects
+	if pageInfo.finalURL != "" && opts.BaseURL == "" {
+		// links on a redirected page are relative to, and internal to, where it ended up
+		if u, err := url.Parse(pageInfo.finalURL); err == nil {
+			result.BaseUrl = u
+		}
+	}
+	if cert := pageInfo.certificate; cert != nil {
+		result.TLSSubject = cert.Subject.String()
+		result.TLSIssuer = cert.Issuer.String()
+		result.TLSNotAfter = cert.NotAfter
+	}
+	if opts.ResolveHost {
+		a.resolveHost(ctx, result, timings, resolveTarget(pageInfo.remoteAddr, parsedURL))
+	}
 
-	analyzeGroup, ctx := errgroup.WithContext(ctx)
+	if opts.OnPageFetched != nil {
+		opts.OnPageFetched(result)
+	}
+	if opts.OnLinkCheck != nil {
+		ctx = context.WithValue(ctx, linkCheckObserverKey{}, opts.OnLinkCheck)
+	}
 
-	analyzeGroup.Go(func() error {
-		funcStartTime := time.Now()
-		defer func() {
-			a.log.Debugf("checkLinksAccessibility took %v", time.Since(funcStartTime))
-		}()
-		links := collectLinks(ctx, result.HtmlNode, result.BaseUrl)
-		inaccessibleLinks := checkLinksAccessibility(ctx, links)
-		result.InaccessibleLinks = inaccessibleLinks
-		return nil
-	})
+	// The analyzers walk the tree recursively, so refuse pathologically deep
+	// documents instead of risking the goroutine stack
+	if a.maxDOMDepth > 0 && exceedsDepth(result.HtmlNode, a.maxDOMDepth) {
+		result.DOMTooDeep = true
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			`document is nested deeper than %d elements, analysis skipped`, a.maxDOMDepth))
+		a.logger(ctx).Warn(`document too deep, skipping analysis`)
+		return result, nil
+	}
 
-	analyzeGroup.Go(func() error {
-		funcStartTime := time.Now()
-		defer func() {
-			a.log.Debugf("countLinks took %v", time.Since(funcStartTime))
-		}()
-		internal, external := countLinks(ctx, result.HtmlNode, result.BaseUrl)
-		result.InternalLinks = internal
-		result.ExternalLinks = external
-		return nil
-	})
+	// Walk the document once for the facts several steps share
+	walkOpts := a.walkOptions()
+	if opts.AnchorText {
+		walkOpts.anchorTextMaxChars, walkOpts.anchorTextMaxLinks = a.anchorTextMaxChars, a.anchorTextMaxLinks
+	}
+	facts := walkDocument(ctx, result.HtmlNode, result.BaseUrl, walkOpts)
+	facts.normalizedLinks = a.linkNormalization.apply(facts.links)
+	ctx = context.WithValue(ctx, documentFactsKey{}, &facts)
+	if robots := a.robotsFor(ctx); robots != nil {
+		// one cache for the link, image and stylesheet checks, so each robots.txt is fetched once
+		ctx = context.WithValue(ctx, robotsCacheKey{}, robots)
+	}
+	ctx = context.WithValue(ctx, probeSlotsKey{}, a.newProbeSlots())
 
-	analyzeGroup.Go(func() error {
-		funcStartTime := time.Now()
-		defer func() {
-			a.log.Debugf("countHeadings took %v", time.Since(funcStartTime))
-		}()
-		result.Headings = countHeadings(ctx, result.HtmlNode)
-		return nil
-	})
+	// Network analyzers run in their own group on the request context so a
+	// failing probe can't cancel the DOM analyzers below mid-traversal.
+	networkGroup := new(errgroup.Group)
+	networkSteps, steps := a.analysisSteps(opts)
+	networkErrs := make([]error, len(networkSteps))
+	for i, na := range networkSteps {
+		networkGroup.Go(func() error {
+			defer a.recoverPanic(ctx, na.name, &networkErrs[i])
+			funcStartTime := time.Now()
+			defer func() {
+				a.logger(ctx).Debugf("%s took %v", na.name, timings.record(na.name, funcStartTime))
+			}()
+			stepCtx, stepSpan := tracing.Tracer().Start(ctx, na.name)
+			defer stepSpan.End()
+			networkErrs[i] = na.run(stepCtx, result)
+			if networkErrs[i] != nil {
+				stepSpan.RecordError(networkErrs[i])
+			}
+			return nil
+		})
+	}
 
-	analyzeGroup.Go(func() error {
-		funcStartTime := time.Now()
-		defer func() {
-			a.log.Debugf("getTitle took %v", time.Since(funcStartTime))
-		}()
-		result.Title = getTitle(ctx, result.HtmlNode)
-		return nil
-	})
+	analyzeGroup, analyzeCtx := errgroup.WithContext(ctx)
+	if a.analyzerConcurrency > 0 {
+		analyzeGroup.SetLimit(a.analyzerConcurrency)
+	}
+	for _, step := range steps {
+		analyzeGroup.Go(func() (err error) {
+			defer a.recoverPanic(analyzeCtx, step.name, &err)
+			funcStartTime := time.Now()
+			defer func() {
+				a.logger(ctx).Debugf("%s took %v", step.name, timings.record(step.name, funcStartTime))
+			}()
+			stepCtx, stepSpan := tracing.Tracer().Start(analyzeCtx, step.name)
+			defer stepSpan.End()
+			err = step.run(stepCtx, result)
+			if err != nil {
+				stepSpan.RecordError(err)
+			}
+			return err
+		})
+	}
 
-	analyzeGroup.Go(func() error {
-		funcStartTime := time.Now()
-		defer func() {
-			a.log.Debugf("getHTMLVersion took %v", time.Since(funcStartTime))
-		}()
-		result.HTMLVersion = getHTMLVersion(ctx, result.BodyByte)
-		return nil
-	})
+	domErr := analyzeGroup.Wait()
+	networkGroup.Wait()
+	if domErr != nil {
+		span.RecordError(domErr)
+		span.SetStatus(codes.Error, `failed to analyze web page`)
+		return result, errors.Wrap(domErr, "failed to analyze web page")
+	}
+	// link checks cut short by the deadline leave the counts incomplete, so
+	// report the timeout instead of a partial result
+	if err := ctx.Err(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, `analysis timed out`)
+		return result, &AnalyzeError{Kind: KindTimeout, Err: err}
+	}
 
-	analyzeGroup.Go(func() error {
-		funcStartTime := time.Now()
-		defer func() {
-			a.log.Debugf("checkLoginForm took %v", time.Since(funcStartTime))
-		}()
-		result.HasLoginForm = hasLoginForm(ctx, result.HtmlNode)
-		return nil
-	})
+	for i, err := range networkErrs {
+		if err == nil {
+			continue
+		}
+		a.logger(ctx).WithError(err).Errorf(`%s failed`, networkSteps[i].name)
+		result.Warnings = append(result.Warnings, fmt.Sprintf(`%s failed, results may be incomplete`, networkSteps[i].name))
+	}
 
-	if err := analyzeGroup.Wait(); err != nil {
-		return result, errors.Wrap(err, "failed to analyze web page")
+	if result.Truncated {
+		result.Warnings = append(result.Warnings, `page body was cut off mid-transfer, results may be incomplete`)
 	}
 
-	a.log.Debug(`analyze web page ended...`)
+	if !result.TLSNotAfter.IsZero() && a.certExpiryWarning > 0 {
+		if remaining := time.Until(result.TLSNotAfter); remaining < a.certExpiryWarning {
+			result.Warnings = append(result.Warnings, fmt.Sprintf(
+				`TLS certificate expires on %s`, result.TLSNotAfter.UTC().Format(time.DateOnly)))
+		}
+	}
+
+	if result.Landmarks["main"] == 0 {
+		result.Warnings = append(result.Warnings, `page has no main landmark`)
+	}
+
+	if result.MetaRefreshURL != "" {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			`page redirects to %s after %d seconds via meta refresh`, result.MetaRefreshURL, result.MetaRefreshDelay))
+	}
+
+	if result.LikelyClientRendered {
+		result.Warnings = append(result.Warnings, `page looks client-side rendered, results may be incomplete`)
+	}
+
+	if result.InlineEventHandlers > 0 && isStrictCSP(result.ContentSecurityPolicy) {
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			`%d inline event handlers found but the content security policy blocks inline scripts`, result.InlineEventHandlers))
+	}
+
+	a.logger(ctx).Debug(`analyze web page ended...`)
 	return result, nil
 }
 
-func parseUrl(ctx context.Context, userUrl string) (*url.URL, error) {
+// ErrInvalidURL is returned when the url to analyze, or the base url, is not
+// an absolute http(s) url. ErrMalformedURL, ErrUnsupportedScheme and
+// ErrMissingHost tell why and match ErrInvalidURL too.
+var ErrInvalidURL = errors.New(`invalid url`)
+
+var (
+	ErrMalformedURL      = errors.Wrap(ErrInvalidURL, `url is malformed`)
+	ErrUnsupportedScheme = errors.Wrap(ErrInvalidURL, `url scheme is not http or https`)
+	ErrMissingHost       = errors.Wrap(ErrInvalidURL, `url has no host`)
+)
+
+// ParseURL parses an absolute http(s) url, lowercasing its scheme and host.
+// It is the one check every url a request names has to pass, so errors are
+// AnalyzeErrors of KindInvalidURL matching ErrMalformedURL,
+// ErrUnsupportedScheme or ErrMissingHost.
+func ParseURL(userUrl string) (*url.URL, error) {
+	// url.Parse lets spaces through outside the host
+	if strings.ContainsFunc(userUrl, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) {
+		return nil, &AnalyzeError{Kind: KindInvalidURL, Err: errors.Wrap(ErrMalformedURL, "url contains spaces or control characters")}
+	}
+
 	baseURL, err := url.Parse(userUrl)
 	if err != nil {
-		return nil, err
+		return nil, &AnalyzeError{Kind: KindInvalidURL, Err: errors.Wrap(ErrMalformedURL, err.Error())}
 	}
 
+	baseURL.Scheme = strings.ToLower(baseURL.Scheme)
 	if baseURL.Scheme != "http" && baseURL.Scheme != "https" {
-		return nil, errors.New("url is invalid")
+		return nil, &AnalyzeError{Kind: KindInvalidURL, Err: errors.Wrap(ErrUnsupportedScheme, "url is invalid")}
 	}
 
+	// http:///path parses fine but leaves nothing to fetch or to compare links against
+	if baseURL.Hostname() == "" {
+		return nil, &AnalyzeError{Kind: KindInvalidURL, Err: errors.Wrap(ErrMissingHost, "url is invalid")}
+	}
+	baseURL.Host = strings.ToLower(baseURL.Host)
+
 	return baseURL, nil
 }
 
-func getWebPage(ctx context.Context, userURL string, httpClient adaptors.WebClient) (webPageInfo, error) {
+// fetchHeader returns the extra request header the page fetch needs for opts
+func fetchHeader(opts models.AnalysisOptions) http.Header {
+	header := http.Header{}
+	if opts.BearerToken != "" {
+		header.Set("Authorization", "Bearer "+opts.BearerToken)
+	}
+	if opts.HostHeader != "" {
+		header.Set("Host", opts.HostHeader)
+	}
+	if opts.UserAgent != "" {
+		header.Set("User-Agent", opts.UserAgent)
+	}
+	return header
+}
+
+func getWebPage(ctx context.Context, userURL string, httpClient adaptors.WebClient, limits resourceLimits) (webPageInfo, error) {
 	var info webPageInfo
-	bodyByte, responseCode, err := httpClient.Do(ctx, userURL, http.MethodGet)
+	fetchCtx, fetchSpan := tracing.Tracer().Start(ctx, `fetch`)
+	resp, err := httpClient.Do(fetchCtx, userURL, http.MethodGet)
+	fetchSpan.End()
 	if err != nil {
-		return info, err
+		return info, &AnalyzeError{Kind: fetchErrorKind(err), Err: err}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return info, &AnalyzeError{Kind: KindUpstreamStatus, StatusCode: resp.StatusCode,
+			Err: errors.New(fmt.Sprintf(`url is invalid states code is %d`, resp.StatusCode))}
 	}
 
-	if responseCode != http.StatusOK {
-		return info, errors.New(fmt.Sprintf(`url is invalid states code is %d`, responseCode))
+	contentType, charset := normalizeContentType(resp.Header.Get("Content-Type"))
+	if !isHTMLContentType(contentType) {
+		return info, &AnalyzeError{Kind: KindUnsupportedContentType,
+			Err: errors.Wrap(ErrNotHTML, fmt.Sprintf(`content type is %q`, contentType))}
 	}
 
-	doc, err := html.Parse(bytes.NewReader(bodyByte))
+	doc, err := parsePage(ctx, resp.Body, limits)
 	if err != nil {
 		return info, err
 	}
 
-	info.bodyByte = bodyByte
-	info.responseCode = responseCode
+	info.bodyByte = resp.Body
+	info.responseCode = resp.StatusCode
 	info.htmlNode = doc
+	info.ttfb = resp.TTFB
+	info.proto = resp.Proto
+	info.retries = resp.Retries
+	info.truncated = resp.Truncated
+	info.finalURL = resp.FinalURL
+	info.redirects = len(resp.RedirectChain)
+	info.remoteAddr = resp.RemoteAddr
+	info.header = resp.Header
+	info.certificate = resp.PeerCertificate
+	info.contentType, info.charset = contentType, charset
 
 	return info, nil
 }
 
-func getHTMLVersion(ctx context.Context, body []byte) string {
+// parsePage parses a page body, failing with an AnalyzeError when it is over
+// limits or not parsable
+func parsePage(ctx context.Context, body []byte, limits resourceLimits) (*html.Node, error) {
+	if err := limits.checkBody(body); err != nil {
+		return nil, &AnalyzeError{Kind: KindResourceLimit, Err: err}
+	}
+
+	_, parseSpan := tracing.Tracer().Start(ctx, `parse`)
+	doc, err := html.Parse(bytes.NewReader(body))
+	parseSpan.End()
+	if err != nil {
+		return nil, &AnalyzeError{Kind: KindParseFailed, Err: err}
+	}
+
+	if err := limits.checkDocument(doc); err != nil {
+		return nil, &AnalyzeError{Kind: KindResourceLimit, Err: err}
+	}
+	return doc, nil
+}
+
+// doctypeVersions maps the public identifiers of the legacy doctypes, lower
+// cased, to their version labels
+var doctypeVersions = map[string]string{
+	"-//ietf//dtd html 2.0//en":                 "HTML 2.0",
+	"-//w3c//dtd html 3.2 final//en":            "HTML 3.2",
+	"-//w3c//dtd html 4.01//en":                 "HTML 4.01 Strict",
+	"-//w3c//dtd html 4.01 transitional//en":    "HTML 4.01 Transitional",
+	"-//w3c//dtd html 4.01 frameset//en":        "HTML 4.01 Frameset",
+	"-//w3c//dtd xhtml 1.0 strict//en":          "XHTML 1.0 Strict",
+	"-//w3c//dtd xhtml 1.0 transitional//en":    "XHTML 1.0 Transitional",
+	"-//w3c//dtd xhtml 1.0 frameset//en":        "XHTML 1.0 Frameset",
+	"-//w3c//dtd xhtml 1.1//en":                 "XHTML 1.1",
+	"-//w3c//dtd xhtml basic 1.1//en":           "XHTML Basic 1.1",
+	"-//w3c//dtd xhtml 1.1 plus mathml 2.0//en": "XHTML 1.1 plus MathML 2.0",
+}
+
+// getHTMLVersion classifies the doctype of doc by its name and its public and
+// system identifiers. Pages without a doctype, or with one that isn't for
+// html, render in quirks mode and are reported as "Unknown/Quirks". A legacy
+// public identifier that isn't recognized is returned as is.
+func getHTMLVersion(ctx context.Context, doc *html.Node) string {
+	doctype := findDoctype(doc)
+	if doctype == nil || !strings.EqualFold(doctype.Data, "html") {
+		return "Unknown/Quirks"
+	}
+	public := strings.TrimSpace(getAttr(doctype, "public"))
+	system := strings.TrimSpace(getAttr(doctype, "system"))
+	if public == "" && (system == "" || strings.EqualFold(system, "about:legacy-compat")) {
+		return "HTML5"
+	}
+	if version, ok := doctypeVersions[strings.ToLower(public)]; ok {
+		return version
+	}
+	if public != "" {
+		return public
+	}
+	return system
+}
+
+// findDoctype returns the doctype node of doc, if it has one
+func findDoctype(doc *html.Node) *html.Node {
+	if doc == nil {
+		return nil
+	}
+	for c := doc.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.DoctypeNode {
+			return c
+		}
+	}
+	return nil
+}
+
+// readDoctype returns the first doctype in body exactly as written
+func readDoctype(body []byte) string {
 	tokenizer := html.NewTokenizer(bytes.NewReader(body))
-	var doctype string
-loop:
 	for {
-		tt := tokenizer.Next()
-		switch tt {
+		switch tokenizer.Next() {
 		case html.DoctypeToken:
-			tokens := tokenizer.Token()
-			doctype = tokens.String()
-			break loop
+			// Raw is only valid until the next call to Next
+			return string(tokenizer.Raw())
 		case html.ErrorToken:
-			break loop
+			return ""
 		}
 	}
-	doctypeLower := strings.ToLower(doctype)
-	switch {
-	case strings.Contains(doctypeLower, "html 4.01 strict"):
-		return "HTML 4.01 Strict"
-	case strings.Contains(doctypeLower, "html 4.01 transitional"):
-		return "HTML 4.01 Transitional"
-	case strings.Contains(doctypeLower, "xhtml 1.0 strict"):
-		return "XHTML 1.0 Strict"
-	case strings.Contains(doctypeLower, "xhtml 1.0 transitional"):
-		return "XHTML 1.0 Transitional"
-	case strings.Contains(doctypeLower, "html 5") || strings.TrimSpace(doctypeLower) == "<!doctype html>":
-		return "HTML5"
-	default:
-		return doctype
-	}
 }
 
 func getTitle(ctx context.Context, n *html.Node) string {
-	var title string
-	var traverse func(*html.Node)
-	traverse = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "title" && n.FirstChild != nil {
-			title = n.FirstChild.Data
-			return
-		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			traverse(c)
-		}
-	}
-	traverse(n)
-	return title
+	return walkDocument(ctx, n, nil, walkOptions{}).title
 }
 
 func countHeadings(ctx context.Context, n *html.Node) map[string]int {
-	counts := map[string]int{"h1": 0, "h2": 0, "h3": 0, "h4": 0, "h5": 0, "h6": 0}
-	var traverse func(*html.Node)
-	traverse = func(n *html.Node) {
-		if n.Type == html.ElementNode {
-			switch n.Data {
-			case "h1":
-				counts["h1"]++
-			case "h2":
-				counts["h2"]++
-			case "h3":
-				counts["h3"]++
-			case "h4":
-				counts["h4"]++
-			case "h5":
-				counts["h5"]++
-			case "h6":
-				counts["h6"]++
-			}
-		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			traverse(c)
-		}
-	}
-	traverse(n)
-	return counts
+	return walkDocument(ctx, n, nil, walkOptions{}).headings
 }
 
-func countLinks(ctx context.Context, doc *html.Node, baseURL *url.URL) (int, int) {
-	links := collectLinks(ctx, doc, baseURL)
+func countLinks(ctx context.Context, doc *html.Node, baseURL *url.URL, norm linkNormalization) (int, int) {
+	return tallyLinks(norm.apply(collectLinks(ctx, doc, baseURL)))
+}
+
+// tallyLinks returns the number of internal and external links
+func tallyLinks(links []linkInfo) (int, int) {
 	internal, external := 0, 0
 	for _, link := range links {
 		if link.isInternal {
@@ -288,30 +1066,24 @@ func countLinks(ctx context.Context, doc *html.Node, baseURL *url.URL) (int, int
 }
 
 func collectLinks(ctx context.Context, doc *html.Node, baseURL *url.URL) []linkInfo {
-	var links []linkInfo
-	var traverse func(*html.Node)
-	traverse = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "a" {
-			href := getHref(ctx, n)
-			if href == "" {
-				return
-			}
-			absoluteURL, err := baseURL.Parse(href)
-			if err != nil {
-				return
-			}
-			if absoluteURL.Scheme != "http" && absoluteURL.Scheme != "https" {
-				return
-			}
-			isInternal := getCanonicalHost(ctx, absoluteURL) == getCanonicalHost(ctx, baseURL)
-			links = append(links, linkInfo{url: absoluteURL.String(), isInternal: isInternal})
-		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			traverse(c)
-		}
+	return walkDocument(ctx, doc, baseURL, walkOptions{}).links
+}
+
+// hrefScheme returns the lower-cased scheme key an anchor's href is counted
+// under in LinksByScheme. Hrefs without a scheme are "fragment" when they only
+// point within the page and "relative" otherwise
+func hrefScheme(href string) (string, bool) {
+	if strings.HasPrefix(href, "#") {
+		return "fragment", true
 	}
-	traverse(doc)
-	return links
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	if u.Scheme == "" {
+		return "relative", true
+	}
+	return strings.ToLower(u.Scheme), true
 }
 
 func getHref(ctx context.Context, n *html.Node) string {
@@ -335,82 +1107,400 @@ func getCanonicalHost(ctx context.Context, u *url.URL) string {
 	return host + ":" + port
 }
 
-func checkLinksAccessibility(ctx context.Context, links []linkInfo) int {
-	var wg sync.WaitGroup
-	results := make(chan bool, len(links))
-	sem := make(chan struct{}, 20)
-	client := http.Client{Timeout: 1 * time.Second}
-	defer client.CloseIdleConnections()
-
-	for _, link := range links {
-		wg.Add(1)
-		go func(url string) {
-			defer wg.Done()
-			sem <- struct{}{}
-			defer func() { <-sem }()
+// checkLinksAccessibility returns how many links failed their check and the
+// links that redirected. Once ctx is done it returns early: probes cut short
+// count as failed and links not yet probed are not counted.
+func (a *Analyzer) checkLinksAccessibility(ctx context.Context, links []linkInfo) (int, []models.LinkRedirect) {
+	observe := linkCheckObserver(ctx)
+	inaccessible := 0
+	var redirects []models.LinkRedirect
+	for check := range a.probeLinks(ctx, links) {
+		if !check.Accessible {
+			inaccessible++
+		}
+		if check.Redirect != nil {
+			redirects = append(redirects, *check.Redirect)
+		}
+		if observe != nil {
+			observe(check)
+		}
+	}
+	return inaccessible, redirects
+}
 
-			resp, err := client.Head(url)
-			if err != nil {
-				results <- false
-				return
-			}
-			defer resp.Body.Close()
-			if resp.StatusCode >= 400 {
-				results <- false
-			} else {
-				results <- true
-			}
-		}(link.url)
+// probeLinks checks links concurrently and streams the results. The channel
+// is closed once every link has been checked, or once ctx is done and the
+// probes in flight have given up; links not probed by then are left out.
+func (a *Analyzer) probeLinks(ctx context.Context, links []linkInfo) <-chan models.LinkCheck {
+	var wg sync.WaitGroup
+	results := make(chan models.LinkCheck, len(links))
+	sem := a.probeSlots(ctx)
+	client := a.probeClient()
+	if a.linkRedirects {
+		client.CheckRedirect = followOneRedirect
 	}
 
 	go func() {
+	spawn:
+		for _, link := range links {
+			// acquire before spawning so at most cap(sem) probe goroutines exist at a time
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				break spawn
+			}
+			if ctx.Err() != nil {
+				<-sem
+				break
+			}
+			wg.Add(1)
+			go func(link linkInfo) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				results <- a.probeLink(ctx, client, link)
+			}(link)
+		}
 		wg.Wait()
+		client.CloseIdleConnections()
 		close(results)
 	}()
+	return results
+}
 
-	inaccessible := 0
-	for res := range results {
-		if !res {
-			inaccessible++
+// probeSlots returns the probe semaphore shared by the analysis on ctx, or a
+// new one outside an analysis
+func (a *Analyzer) probeSlots(ctx context.Context) chan struct{} {
+	if sem, ok := ctx.Value(probeSlotsKey{}).(chan struct{}); ok {
+		return sem
+	}
+	return a.newProbeSlots()
+}
+
+func (a *Analyzer) newProbeSlots() chan struct{} {
+	return make(chan struct{}, a.linkCheckConcurrency)
+}
+
+// logger returns the analyzer's log entry for ctx, tagged with the request ID
+// of the inbound request when there is one
+func (a *Analyzer) logger(ctx context.Context) *log.Entry {
+	entry := a.log.WithContext(ctx)
+	if reqID, ok := requestid.FromContext(ctx); ok {
+		entry = entry.WithField(`request_id`, reqID)
+	}
+	return entry
+}
+
+// probeLink checks a single link. A panic while probing is reported as a
+// failed check so one bad link can't take down the whole analysis.
+func (a *Analyzer) probeLink(ctx context.Context, client *http.Client, link linkInfo) (check models.LinkCheck) {
+	check = models.LinkCheck{URL: link.url, Internal: link.isInternal}
+	defer func() {
+		if r := recover(); r != nil {
+			a.logger(ctx).WithField(`stack`, string(debug.Stack())).
+				Errorf(`link check for %s panicked: %v`, link.url, r)
+			check = models.LinkCheck{URL: link.url, Internal: link.isInternal,
+				Err: errors.Errorf("link check panicked: %v", r)}
 		}
+	}()
+
+	resp, err := sendProbe(ctx, client, http.MethodHead, link.url)
+	if err == nil && rejectsHead(resp.StatusCode) {
+		// plenty of servers refuse HEAD but serve the same URL to a GET
+		resp.Body.Close()
+		resp, err = sendProbe(ctx, client, http.MethodGet, link.url)
+	}
+	if err != nil {
+		check.Err = adaptors.ClassifyTransportError(err)
+		return check
+	}
+	defer resp.Body.Close()
+	// drain a small GET body so the connection can be reused; a larger one is
+	// cut off and its connection closed rather than tying up the probe
+	io.CopyN(io.Discard, resp.Body, maxProbeDrainBytes)
+	check.StatusCode = resp.StatusCode
+	check.Accessible = resp.StatusCode < 400
+	if a.linkRedirects {
+		check.Redirect = linkRedirect(link.url, resp)
+	}
+	return check
+}
+
+// probeClient returns a client for link, image and robots.txt checks, which
+// passes the inbound request ID on only when the analyzer forwards it
+func (a *Analyzer) probeClient() *http.Client {
+	transport := a.linkCheckTransport
+	if a.forwardRequestID {
+		transport = requestIDTransport{base: transport}
+	}
+	return &http.Client{Timeout: a.linkCheckTimeout, Transport: transport}
+}
+
+// requestIDTransport sets X-Request-ID from the request context's request ID
+type requestIDTransport struct {
+	base http.RoundTripper
+}
+
+func (t requestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if reqID, ok := requestid.FromContext(req.Context()); ok {
+		// a RoundTripper must not modify the request it was given
+		req = req.Clone(req.Context())
+		req.Header.Set(`X-Request-ID`, reqID)
+	}
+	return base.RoundTrip(req)
+}
+
+// sendProbe sends a link check request with ctx and the client's timeout
+func sendProbe(ctx context.Context, client *http.Client, method string, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
 	}
-	return inaccessible
+	return client.Do(req)
+}
+
+// rejectsHead reports whether a HEAD response status may just mean the server
+// doesn't answer HEAD, so the link is worth a GET before calling it broken
+func rejectsHead(status int) bool {
+	return status == http.StatusMethodNotAllowed || status == http.StatusForbidden || status == http.StatusNotImplemented
+}
+
+// recoverPanic is deferred by the goroutines the analyzer spawns. It turns a
+// panic into an error on errp so the step fails instead of the process.
+func (a *Analyzer) recoverPanic(ctx context.Context, name string, errp *error) {
+	if r := recover(); r != nil {
+		a.logger(ctx).WithField(`stack`, string(debug.Stack())).
+			Errorf(`%s panicked: %v`, name, r)
+		*errp = errors.Errorf("%s panicked: %v", name, r)
+	}
+}
+
+// linkCheckObserver returns the AnalysisOptions.OnLinkCheck callback carried by
+// ctx, or nil
+func linkCheckObserver(ctx context.Context) func(models.LinkCheck) {
+	observe, _ := ctx.Value(linkCheckObserverKey{}).(func(models.LinkCheck))
+	return observe
 }
 
 func hasLoginForm(ctx context.Context, doc *html.Node) bool {
-	var hasLogin bool
-	var traverse func(*html.Node)
-	traverse = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "form" {
-			if formHasPassword(ctx, n) {
-				hasLogin = true
-				return
+	return len(walkDocument(ctx, doc, nil, walkOptions{}).loginForms) > 0
+}
+
+// formHasPassword reports whether a password input inside form belongs to it.
+// Inputs are assigned to forms the way the document walk does, so inputs with
+// a form attribute belong to the form they name instead.
+func formHasPassword(ctx context.Context, form *html.Node) bool {
+	return len(walkDocument(ctx, form, nil, walkOptions{}).loginForms) > 0
+}
+
+// getAttr returns the value of the named attribute, or "" when it is absent
+func getAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+func countInlineEventHandlers(ctx context.Context, doc *html.Node) int {
+	return walkDocument(ctx, doc, nil, walkOptions{}).inlineEventHandlers
+}
+
+// hasInlineEventHandler reports whether element n has an on* attribute
+func hasInlineEventHandler(n *html.Node) bool {
+	for _, attr := range n.Attr {
+		if len(attr.Key) > 2 && strings.HasPrefix(attr.Key, "on") {
+			return true
+		}
+	}
+	return false
+}
+
+// getMetaCSP returns the content security policy declared through a
+// <meta http-equiv="Content-Security-Policy"> tag
+func getMetaCSP(ctx context.Context, tags []*html.Node) string {
+	for _, n := range tags {
+		if strings.EqualFold(getAttr(n, "http-equiv"), "content-security-policy") {
+			return getAttr(n, "content")
+		}
+	}
+	return ""
+}
+
+// isStrictCSP reports whether the policy restricts scripts without allowing
+// 'unsafe-inline', which makes inline event handlers inert
+func isStrictCSP(csp string) bool {
+	directives := map[string]string{}
+	for _, directive := range strings.Split(csp, ";") {
+		fields := strings.Fields(strings.ToLower(directive))
+		if len(fields) == 0 {
+			continue
+		}
+		directives[fields[0]] = strings.Join(fields[1:], " ")
+	}
+
+	sources, ok := directives["script-src-attr"]
+	if !ok {
+		sources, ok = directives["script-src"]
+	}
+	if !ok {
+		sources, ok = directives["default-src"]
+	}
+	if !ok {
+		return false
+	}
+	return !strings.Contains(sources, "'unsafe-inline'")
+}
+
+// ErrNotHTML is returned when the page is served with a content type other
+// than HTML, e.g. a PDF or a JSON api
+var ErrNotHTML = errors.New(`page is not HTML`)
+
+// isHTMLContentType reports whether a normalized media type is one the HTML
+// parser can make sense of. A missing Content-Type is given the benefit of the
+// doubt.
+func isHTMLContentType(mediaType string) bool {
+	switch mediaType {
+	case "", "text/html", "application/xhtml+xml":
+		return true
+	}
+	return false
+}
+
+// normalizeContentType splits a Content-Type header value into its lowercased
+// media type and charset, so "TEXT/HTML; Charset=UTF-8" becomes "text/html" and "utf-8"
+func normalizeContentType(value string) (string, string) {
+	mediaType, params, err := mime.ParseMediaType(value)
+	if err != nil {
+		// fall back to a manual split for values mime rejects, e.g. duplicate parameters
+		parts := strings.Split(value, ";")
+		mediaType = parts[0]
+		params = map[string]string{}
+		for _, part := range parts[1:] {
+			key, val, ok := strings.Cut(part, "=")
+			if ok {
+				params[strings.ToLower(strings.TrimSpace(key))] = val
 			}
 		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			traverse(c)
+	}
+	return normalizeHeaderToken(mediaType), normalizeHeaderToken(params["charset"])
+}
+
+// normalizeHeaderToken lowercases a header token and strips surrounding whitespace and quotes
+func normalizeHeaderToken(value string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(value), `"'`))
+}
+
+// spaMountPoints are element ids commonly used as the mount point of client-side frameworks
+var spaMountPoints = map[string]bool{"root": true, "app": true, "__next": true, "__nuxt": true}
+
+const (
+	// clientRenderedMaxText is the most visible body text a client-rendered shell is expected to have
+	clientRenderedMaxText = 100
+	// clientRenderedMinScript is the inline script size treated as a bundled application
+	clientRenderedMinScript = 1000
+)
+
+// isLikelyClientRendered flags pages whose content is probably produced by
+// JavaScript: an empty framework mount point, or a near-empty body that ships
+// a script bundle
+func isLikelyClientRendered(ctx context.Context, doc *html.Node) bool {
+	return walkDocument(ctx, doc, nil, walkOptions{}).likelyClientRendered
+}
+
+// isScriptBundle reports whether script n loads its code or inlines enough of
+// it to be a bundled application
+func isScriptBundle(n *html.Node) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == "src" && attr.Val != "" {
+			return true
+		}
+	}
+	return n.FirstChild != nil && len(n.FirstChild.Data) >= clientRenderedMinScript
+}
+
+func hasElementChild(n *html.Node) bool {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			return true
 		}
 	}
-	traverse(doc)
-	return hasLogin
+	return false
 }
 
-func formHasPassword(ctx context.Context, form *html.Node) bool {
-	var hasPassword bool
-	var traverseForm func(*html.Node)
-	traverseForm = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "input" {
-			for _, attr := range n.Attr {
-				if attr.Key == "type" && attr.Val == "password" {
-					hasPassword = true
-					return
-				}
+// getMetaRefresh returns the absolute target and delay of a
+// <meta http-equiv="refresh" content="5;url=..."> tag. The url is empty when
+// the tag only reloads the page.
+func getMetaRefresh(ctx context.Context, tags []*html.Node, baseURL *url.URL) (string, int) {
+	var content string
+	var found bool
+	for _, n := range tags {
+		if strings.EqualFold(strings.TrimSpace(getAttr(n, "http-equiv")), "refresh") {
+			content = getAttr(n, "content")
+			found = true
+			break
+		}
+	}
+	if !found {
+		return "", 0
+	}
+
+	delayPart, target, _ := strings.Cut(content, ";")
+	if !strings.Contains(delayPart, "=") {
+		// some pages separate delay and url with a comma instead of a semicolon
+		if d, t, ok := strings.Cut(delayPart, ","); ok {
+			delayPart, target = d, t
+		}
+	}
+	delay, err := strconv.Atoi(strings.TrimSpace(strings.SplitN(delayPart, ".", 2)[0]))
+	if err != nil || delay < 0 {
+		delay = 0
+	}
+
+	target = strings.TrimSpace(target)
+	if key, value, ok := strings.Cut(target, "="); ok && strings.EqualFold(strings.TrimSpace(key), "url") {
+		target = strings.TrimSpace(value)
+	}
+	target = strings.Trim(target, `"'`)
+	if target == "" {
+		return "", delay
+	}
+
+	if baseURL == nil {
+		return target, delay
+	}
+	absoluteURL, err := baseURL.Parse(target)
+	if err != nil {
+		return target, delay
+	}
+	return absoluteURL.String(), delay
+}
+
+// exceedsDepth reports whether any node sits more than limit levels below
+// root. It walks the tree iteratively so it is safe on any depth.
+func exceedsDepth(root *html.Node, limit int) bool {
+	depth := 0
+	n := root
+	for {
+		if n.FirstChild != nil {
+			n = n.FirstChild
+			depth++
+			if depth > limit {
+				return true
 			}
+			continue
+		}
+		for n != root && n.NextSibling == nil {
+			n = n.Parent
+			depth--
 		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			traverseForm(c)
+		if n == root {
+			return false
 		}
+		n = n.NextSibling
 	}
-	traverseForm(form)
-	return hasPassword
 }
@@ -2,15 +2,34 @@ package service
 
 import (
 	"context"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"runtime"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
+	"web_page_analyzer/internal/domain/adaptors"
 	"web_page_analyzer/internal/domain/models"
+	"web_page_analyzer/internal/pkg/requestid"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
 	"golang.org/x/net/html"
 )
 
@@ -19,9 +38,19 @@ type MockWebClient struct {
 	mock.Mock
 }
 
-func (m *MockWebClient) Do(ctx context.Context, url string, method string) ([]byte, int, error) {
+func (m *MockWebClient) Do(ctx context.Context, url string, method string) (*adaptors.WebResponse, error) {
 	args := m.Called(ctx, url, method)
-	return args.Get(0).([]byte), args.Int(1), args.Error(2)
+	resp, _ := args.Get(0).(*adaptors.WebResponse)
+	return resp, args.Error(1)
+}
+
+// htmlResponse builds a 200 text/html response for the mocked web client
+func htmlResponse(body string) *adaptors.WebResponse {
+	return &adaptors.WebResponse{
+		Body:       []byte(body),
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+	}
 }
 
 func TestAnalyze(t *testing.T) {
@@ -34,7 +63,7 @@ func TestAnalyze(t *testing.T) {
 
 	// Mock the responses for the HTTP client
 	htmlContent := "<!DOCTYPE html><html><head><title>Test Page</title></head><body><h1>Header</h1><a href='http://example.com/test'>Test Link</a></body></html>"
-	mockWebClient.On("Do", mock.Anything, testURL, http.MethodGet).Return([]byte(htmlContent), http.StatusOK, nil)
+	mockWebClient.On("Do", mock.Anything, testURL, http.MethodGet).Return(htmlResponse(htmlContent), nil)
 
 	result, err := analyzer.Analyze(ctx, testURL)
 	if err != nil {
@@ -70,46 +99,83 @@ func TestAnalyze(t *testing.T) {
 	mockWebClient.AssertExpectations(t)
 }
 
-func TestParseUrl(t *testing.T) {
-	ctx := context.Background()
-
+func TestParseURL(t *testing.T) {
 	tests := []struct {
-		name      string
-		inputUrl  string
-		expected  *url.URL
-		expectErr bool
+		name     string
+		inputUrl string
+		expected *url.URL
+		wantErr  error
 	}{
 		{
-			name:      "valid http URL",
-			inputUrl:  "http://example.com",
-			expected:  &url.URL{Scheme: "http", Host: "example.com"},
-			expectErr: false,
+			name:     "valid http URL",
+			inputUrl: "http://example.com",
+			expected: &url.URL{Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:     "valid https URL",
+			inputUrl: "https://example.com",
+			expected: &url.URL{Scheme: "https", Host: "example.com"},
+		},
+		{
+			name:     "uppercase scheme and host",
+			inputUrl: "HTTPS://Example.COM:8443/Path",
+			expected: &url.URL{Scheme: "https", Host: "example.com:8443"},
+		},
+		{
+			name:     "invalid URL",
+			inputUrl: "ftp://example.com",
+			wantErr:  ErrUnsupportedScheme,
 		},
 		{
-			name:      "valid https URL",
-			inputUrl:  "https://example.com",
-			expected:  &url.URL{Scheme: "https", Host: "example.com"},
-			expectErr: false,
+			name:     "empty URL",
+			inputUrl: "",
+			wantErr:  ErrUnsupportedScheme,
 		},
 		{
-			name:      "invalid URL",
-			inputUrl:  "ftp://example.com",
-			expected:  nil,
-			expectErr: true,
+			name:     "no host with path",
+			inputUrl: "http:///path",
+			wantErr:  ErrMissingHost,
 		},
 		{
-			name:      "empty URL",
-			inputUrl:  "",
-			expected:  nil,
-			expectErr: true,
+			name:     "no host",
+			inputUrl: "https://",
+			wantErr:  ErrMissingHost,
+		},
+		{
+			name:     "bare scheme",
+			inputUrl: "http://",
+			wantErr:  ErrMissingHost,
+		},
+		{
+			name:     "space in host",
+			inputUrl: "http://exa mple.com",
+			wantErr:  ErrMalformedURL,
+		},
+		{
+			name:     "space in path",
+			inputUrl: "http://example.com/a page",
+			wantErr:  ErrMalformedURL,
+		},
+		{
+			name:     "control character",
+			inputUrl: "http://example.com/\x7f",
+			wantErr:  ErrMalformedURL,
+		},
+		{
+			name:     "unparsable",
+			inputUrl: "http://[::1",
+			wantErr:  ErrMalformedURL,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result, err := parseUrl(ctx, tt.inputUrl)
-			if tt.expectErr {
-				assert.NotNil(t, err)
+			result, err := ParseURL(tt.inputUrl)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.ErrorIs(t, err, ErrInvalidURL)
+				kind, _ := ErrorKindOf(err)
+				assert.Equal(t, KindInvalidURL, kind)
 			} else {
 				assert.Nil(t, err)
 				assert.Equal(t, tt.expected.Scheme, result.Scheme)
@@ -119,6 +185,16 @@ func TestParseUrl(t *testing.T) {
 	}
 }
 
+func TestAnalyzeFetchesNormalizedURL(t *testing.T) {
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "https://example.com/Path", http.MethodGet).Return(htmlResponse(`<html></html>`), nil)
+
+	_, err := NewAnalyzer(log.New(), mockWebClient).Analyze(context.Background(), "HTTPS://Example.COM/Path")
+
+	assert.NoError(t, err)
+	mockWebClient.AssertExpectations(t)
+}
+
 func TestFormHasPassword(t *testing.T) {
 	ctx := context.Background()
 
@@ -187,6 +263,137 @@ func TestFormHasPassword(t *testing.T) {
 	}
 }
 
+func TestAnalyzeHTMLVersion(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		htmlStr     string
+		wantVersion string
+		wantDoctype string
+	}{
+		{
+			name:        "html5",
+			htmlStr:     `<!doctype HTML><html><body></body></html>`,
+			wantVersion: "HTML5",
+			wantDoctype: "<!doctype HTML>",
+		},
+		{
+			name:        "html 4.01 transitional",
+			htmlStr:     `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN" "http://www.w3.org/TR/html4/loose.dtd"><html></html>`,
+			wantVersion: "HTML 4.01 Transitional",
+			wantDoctype: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Transitional//EN" "http://www.w3.org/TR/html4/loose.dtd">`,
+		},
+		{
+			name:        "html5 legacy compat",
+			htmlStr:     `<!DOCTYPE html SYSTEM "about:legacy-compat"><html></html>`,
+			wantVersion: "HTML5",
+			wantDoctype: `<!DOCTYPE html SYSTEM "about:legacy-compat">`,
+		},
+		{
+			name:        "html 4.01 strict",
+			htmlStr:     `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01//EN" "http://www.w3.org/TR/html4/strict.dtd"><html></html>`,
+			wantVersion: "HTML 4.01 Strict",
+			wantDoctype: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01//EN" "http://www.w3.org/TR/html4/strict.dtd">`,
+		},
+		{
+			name:        "html 4.01 frameset",
+			htmlStr:     `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Frameset//EN" "http://www.w3.org/TR/html4/frameset.dtd"><html></html>`,
+			wantVersion: "HTML 4.01 Frameset",
+			wantDoctype: `<!DOCTYPE HTML PUBLIC "-//W3C//DTD HTML 4.01 Frameset//EN" "http://www.w3.org/TR/html4/frameset.dtd">`,
+		},
+		{
+			name:        "xhtml 1.0 strict",
+			htmlStr:     `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd"><html></html>`,
+			wantVersion: "XHTML 1.0 Strict",
+			wantDoctype: `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd">`,
+		},
+		{
+			name:        "xhtml 1.0 transitional",
+			htmlStr:     `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd"><html></html>`,
+			wantVersion: "XHTML 1.0 Transitional",
+			wantDoctype: `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Transitional//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd">`,
+		},
+		{
+			name:        "xhtml 1.1",
+			htmlStr:     `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.1//EN" "http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd"><html></html>`,
+			wantVersion: "XHTML 1.1",
+			wantDoctype: `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.1//EN" "http://www.w3.org/TR/xhtml11/DTD/xhtml11.dtd">`,
+		},
+		{
+			name:        "unrecognized public identifier",
+			htmlStr:     `<!DOCTYPE html PUBLIC "-//W3C//DTD HTML 4.0 Transitional//EN"><html></html>`,
+			wantVersion: "-//W3C//DTD HTML 4.0 Transitional//EN",
+			wantDoctype: `<!DOCTYPE html PUBLIC "-//W3C//DTD HTML 4.0 Transitional//EN">`,
+		},
+		{
+			name:        "not an html doctype",
+			htmlStr:     `<!DOCTYPE svg><html></html>`,
+			wantVersion: "Unknown/Quirks",
+			wantDoctype: `<!DOCTYPE svg>`,
+		},
+		{
+			name:        "no doctype",
+			htmlStr:     `<html><body></body></html>`,
+			wantVersion: "Unknown/Quirks",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &models.AnalysisResult{BodyByte: []byte(tt.htmlStr), HtmlNode: parseHTMLString(t, tt.htmlStr)}
+			assert.NoError(t, analyzeHTMLVersion(ctx, result))
+			assert.Equal(t, tt.wantVersion, result.HTMLVersion)
+			assert.Equal(t, tt.wantDoctype, result.Doctype)
+		})
+	}
+}
+
+func TestHasLoginForm(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		htmlStr  string
+		expected bool
+	}{
+		{
+			name:     "password inside form",
+			htmlStr:  `<form><input type="text" name="user"><input type="PASSWORD" name="pass"></form>`,
+			expected: true,
+		},
+		{
+			name: "password outside form linked by form attribute",
+			htmlStr: `<form id="loginform" action="/login"><input type="text" name="user"></form>
+				<div><input type="password" name="pass" form="loginform"></div>`,
+			expected: true,
+		},
+		{
+			name: "form attribute naming a missing form",
+			htmlStr: `<form id="search"><input type="text" name="q"></form>
+				<input type="password" name="pass" form="loginform">`,
+			expected: false,
+		},
+		{
+			name: "password inside one form but owned by another",
+			htmlStr: `<form id="search"><input type="text" name="q"><input type="password" form="elsewhere"></form>
+				<form id="elsewhere"></form>`,
+			expected: true,
+		},
+		{
+			name:     "password without any form",
+			htmlStr:  `<div><input type="password" name="pin"></div>`,
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, hasLoginForm(ctx, parseHTMLString(t, tt.htmlStr)))
+		})
+	}
+}
+
 func parseHTMLString(t *testing.T, htmlStr string) *html.Node {
 	doc, err := html.Parse(strings.NewReader(htmlStr))
 	if err != nil {
@@ -226,3 +433,946 @@ func TestCheckLinksAccessibility(t *testing.T) {
 		})
 	}
 }
+
+func TestCountInlineEventHandlers(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		htmlStr  string
+		expected int
+	}{
+		{
+			name:     "no handlers",
+			htmlStr:  `<html><body><button>Click</button></body></html>`,
+			expected: 0,
+		},
+		{
+			name: "onclick and onmouseover",
+			htmlStr: `<html><body>
+				<button onclick="doSomething()">Click</button>
+				<div onmouseover="highlight(this)">Hover</div>
+				<a href="/" onclick="track()" onmouseover="preview()">Link</a>
+			</body></html>`,
+			expected: 3,
+		},
+		{
+			name:     "body onload",
+			htmlStr:  `<html><body onload="init()"><p>Text</p></body></html>`,
+			expected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseHTMLString(t, tt.htmlStr)
+			assert.Equal(t, tt.expected, countInlineEventHandlers(ctx, doc))
+		})
+	}
+}
+
+func TestIsStrictCSP(t *testing.T) {
+	tests := []struct {
+		name     string
+		csp      string
+		expected bool
+	}{
+		{name: "empty policy", csp: "", expected: false},
+		{name: "script-src self", csp: "default-src *; script-src 'self'", expected: true},
+		{name: "unsafe-inline allowed", csp: "script-src 'self' 'unsafe-inline'", expected: false},
+		{name: "default-src fallback", csp: "default-src 'self'", expected: true},
+		{name: "no script directive", csp: "img-src *", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isStrictCSP(tt.csp))
+		})
+	}
+}
+
+func TestAnalyzeInlineEventHandlerWarning(t *testing.T) {
+	logger := log.New()
+	mockWebClient := new(MockWebClient)
+	analyzer := NewAnalyzer(logger, mockWebClient)
+
+	testURL := "http://example.com"
+	htmlContent := `<!DOCTYPE html><html><head>
+		<meta http-equiv="Content-Security-Policy" content="script-src 'self'">
+		</head><body><main><button onclick="go()">Go</button></main></body></html>`
+	mockWebClient.On("Do", mock.Anything, testURL, http.MethodGet).Return(htmlResponse(htmlContent), nil)
+
+	result, err := analyzer.Analyze(context.Background(), testURL)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.InlineEventHandlers)
+	assert.Len(t, result.Warnings, 1)
+}
+
+func TestNormalizeContentType(t *testing.T) {
+	tests := []struct {
+		name            string
+		header          string
+		wantContentType string
+		wantCharset     string
+	}{
+		{name: "uppercase with charset", header: "TEXT/HTML; charset=UTF-8", wantContentType: "text/html", wantCharset: "utf-8"},
+		{name: "mixed case parameter name", header: "text/html;Charset=ISO-8859-1", wantContentType: "text/html", wantCharset: "iso-8859-1"},
+		{name: "quoted charset and spaces", header: "  Application/XHTML+XML ;  charset=\"Windows-1252\" ", wantContentType: "application/xhtml+xml", wantCharset: "windows-1252"},
+		{name: "no charset", header: "text/html", wantContentType: "text/html", wantCharset: ""},
+		{name: "duplicate parameters", header: "Text/Html; charset=UTF-8; charset=utf-8", wantContentType: "text/html", wantCharset: "utf-8"},
+		{name: "empty header", header: "", wantContentType: "", wantCharset: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contentType, charset := normalizeContentType(tt.header)
+			assert.Equal(t, tt.wantContentType, contentType)
+			assert.Equal(t, tt.wantCharset, charset)
+		})
+	}
+}
+
+func TestAnalyzeNetworkAnalyzerFailureKeepsDOMResults(t *testing.T) {
+	logger := log.New()
+	mockWebClient := new(MockWebClient)
+	analyzer := NewAnalyzer(logger, mockWebClient)
+	analyzer.networkAnalyzers = append(analyzer.networkAnalyzers, analysisStep{
+		name: "failingProbe",
+		run: func(ctx context.Context, result *models.AnalysisResult) error {
+			return errors.New("probe failed")
+		},
+	})
+
+	testURL := "http://example.com"
+	htmlContent := `<!DOCTYPE html><html><head><title>Partial</title></head><body>
+		<h1>One</h1><h2>Two</h2>
+		<form><input type="password" /></form>
+		</body></html>`
+	mockWebClient.On("Do", mock.Anything, testURL, http.MethodGet).Return(htmlResponse(htmlContent), nil)
+
+	result, err := analyzer.Analyze(context.Background(), testURL)
+	assert.NoError(t, err)
+	assert.Equal(t, "Partial", result.Title)
+	assert.Equal(t, "HTML5", result.HTMLVersion)
+	assert.Equal(t, map[string]int{"h1": 1, "h2": 1, "h3": 0, "h4": 0, "h5": 0, "h6": 0}, result.Headings)
+	assert.True(t, result.HasLoginForm)
+	assert.Contains(t, result.Warnings, "failingProbe failed, results may be incomplete")
+}
+
+func TestIsLikelyClientRendered(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		htmlStr  string
+		expected bool
+	}{
+		{
+			name: "react shell",
+			htmlStr: `<!DOCTYPE html><html><head><title>App</title></head><body>
+				<noscript>You need to enable JavaScript to run this app.</noscript>
+				<div id="root"></div>
+				<script src="/static/js/main.8f2c1a.js"></script>
+			</body></html>`,
+			expected: true,
+		},
+		{
+			name:     "angular shell",
+			htmlStr:  `<html><body><app-root></app-root><script src="main.js"></script></body></html>`,
+			expected: true,
+		},
+		{
+			name: "near-empty body with inline bundle",
+			htmlStr: `<html><body><div id="container"></div><script>` +
+				strings.Repeat("var a=1;", 200) + `</script></body></html>`,
+			expected: true,
+		},
+		{
+			name: "server rendered page",
+			htmlStr: `<html><body><div id="root"><h1>Welcome</h1><p>` +
+				strings.Repeat("Plenty of server rendered content. ", 10) +
+				`</p></div><script src="/hydrate.js"></script></body></html>`,
+			expected: false,
+		},
+		{
+			name:     "static page without scripts",
+			htmlStr:  `<html><body><p>Short page</p></body></html>`,
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseHTMLString(t, tt.htmlStr)
+			assert.Equal(t, tt.expected, isLikelyClientRendered(ctx, doc))
+		})
+	}
+}
+
+func TestAnalyzeAnalyzerConcurrencyLimit(t *testing.T) {
+	mockWebClient := new(MockWebClient)
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithAnalyzerConcurrency(2))
+
+	var active, peak int32
+	analyzer.domAnalyzers = nil
+	for i := 0; i < 8; i++ {
+		analyzer.domAnalyzers = append(analyzer.domAnalyzers, analysisStep{
+			name: fmt.Sprintf("step%d", i),
+			run: func(ctx context.Context, result *models.AnalysisResult) error {
+				n := atomic.AddInt32(&active, 1)
+				defer atomic.AddInt32(&active, -1)
+				for {
+					p := atomic.LoadInt32(&peak)
+					if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				return nil
+			},
+		})
+	}
+
+	testURL := "http://example.com"
+	mockWebClient.On("Do", mock.Anything, testURL, http.MethodGet).Return(htmlResponse("<html></html>"), nil)
+
+	_, err := analyzer.Analyze(context.Background(), testURL)
+	assert.NoError(t, err)
+	assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(2))
+	assert.Greater(t, atomic.LoadInt32(&peak), int32(0))
+}
+
+// BenchmarkConcurrentAnalyze reports the peak goroutine count while many
+// analyses run at once; compare -benchtime runs with and without a limit
+func BenchmarkConcurrentAnalyze(b *testing.B) {
+	for _, limit := range []int{0, 2} {
+		b.Run(fmt.Sprintf("limit=%d", limit), func(b *testing.B) {
+			logger := log.New()
+			logger.SetLevel(log.PanicLevel)
+			mockWebClient := new(MockWebClient)
+			mockWebClient.On("Do", mock.Anything, mock.Anything, http.MethodGet).
+				Return(htmlResponse("<html><body><h1>Bench</h1></body></html>"), nil)
+			analyzer := NewAnalyzer(logger, mockWebClient, WithAnalyzerConcurrency(limit))
+
+			var peak int64
+			stop := make(chan struct{})
+			go func() {
+				for {
+					select {
+					case <-stop:
+						return
+					default:
+						if n := int64(runtime.NumGoroutine()); n > atomic.LoadInt64(&peak) {
+							atomic.StoreInt64(&peak, n)
+						}
+						runtime.Gosched()
+					}
+				}
+			}()
+
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					analyzer.Analyze(context.Background(), "http://example.com")
+				}
+			})
+			close(stop)
+			b.ReportMetric(float64(atomic.LoadInt64(&peak)), "peak-goroutines")
+		})
+	}
+}
+
+func TestGetMetaRefresh(t *testing.T) {
+	ctx := context.Background()
+	baseURL, _ := url.Parse("https://example.com/old/page")
+
+	tests := []struct {
+		name      string
+		meta      string
+		wantURL   string
+		wantDelay int
+	}{
+		{name: "immediate relative redirect", meta: `<meta http-equiv="refresh" content="0;url=/new/page">`, wantURL: "https://example.com/new/page", wantDelay: 0},
+		{name: "delayed absolute redirect", meta: `<meta http-equiv="Refresh" content="5; URL='https://other.com/landing'">`, wantURL: "https://other.com/landing", wantDelay: 5},
+		{name: "url without key", meta: `<meta http-equiv="refresh" content="3; next.html">`, wantURL: "https://example.com/old/next.html", wantDelay: 3},
+		{name: "reload only", meta: `<meta http-equiv="refresh" content="30">`, wantURL: "", wantDelay: 30},
+		{name: "no refresh", meta: `<meta name="description" content="0;url=/nope">`, wantURL: "", wantDelay: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseHTMLString(t, `<html><head>`+tt.meta+`</head><body></body></html>`)
+			tags := walkDocument(ctx, doc, nil, walkOptions{}).metaTags
+			gotURL, gotDelay := getMetaRefresh(ctx, tags, baseURL)
+			assert.Equal(t, tt.wantURL, gotURL)
+			assert.Equal(t, tt.wantDelay, gotDelay)
+		})
+	}
+}
+
+func TestAnalyzeWithBaseURLOverride(t *testing.T) {
+	archive := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer archive.Close()
+
+	mockWebClient := new(MockWebClient)
+	analyzer := NewAnalyzer(log.New(), mockWebClient)
+
+	testURL := "http://example.com/snapshot"
+	htmlContent := `<html><body>
+		<a href="/docs">Docs</a>
+		<a href="guide/intro">Intro</a>
+		<a href="` + archive.URL + `/about">About</a>
+	</body></html>`
+	mockWebClient.On("Do", mock.Anything, testURL, http.MethodGet).Return(htmlResponse(htmlContent), nil)
+
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), testURL, models.AnalysisOptions{BaseURL: archive.URL + "/site/"})
+	assert.NoError(t, err)
+	assert.Equal(t, archive.URL+"/site/", result.BaseUrl.String())
+	assert.Equal(t, 3, result.InternalLinks)
+	assert.Equal(t, 0, result.ExternalLinks)
+	assert.Equal(t, 0, result.InaccessibleLinks)
+
+	links := collectLinks(context.Background(), result.HtmlNode, result.BaseUrl)
+	assert.Equal(t, archive.URL+"/docs", links[0].url)
+	assert.Equal(t, archive.URL+"/site/guide/intro", links[1].url)
+
+	_, err = analyzer.AnalyzeWithOptions(context.Background(), testURL, models.AnalysisOptions{BaseURL: "ftp://example.com"})
+	assert.Error(t, err)
+}
+
+func TestExceedsDepth(t *testing.T) {
+	// build the chain by hand; parsing this much nesting would be slow
+	root := &html.Node{Type: html.DocumentNode}
+	parent := root
+	for i := 0; i < 100000; i++ {
+		child := &html.Node{Type: html.ElementNode, Data: "div"}
+		parent.AppendChild(child)
+		parent = child
+	}
+	sibling := &html.Node{Type: html.ElementNode, Data: "p"}
+	root.AppendChild(sibling)
+
+	assert.True(t, exceedsDepth(root, 2000))
+	assert.False(t, exceedsDepth(root, 100000))
+	assert.False(t, exceedsDepth(parseHTMLString(t, "<html><body><p>shallow</p></body></html>"), 10))
+}
+
+func TestAnalyzeDOMTooDeep(t *testing.T) {
+	mockWebClient := new(MockWebClient)
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithMaxDOMDepth(100))
+
+	testURL := "http://example.com"
+	htmlContent := "<html><body>" + strings.Repeat("<div>", 500) + "deep" + strings.Repeat("</div>", 500) + "</body></html>"
+	mockWebClient.On("Do", mock.Anything, testURL, http.MethodGet).Return(htmlResponse(htmlContent), nil)
+
+	result, err := analyzer.Analyze(context.Background(), testURL)
+	assert.NoError(t, err)
+	assert.True(t, result.DOMTooDeep)
+	assert.NotEmpty(t, result.Warnings)
+}
+
+func TestAnalyzeSchemeLinks(t *testing.T) {
+	baseURL, _ := url.Parse("https://example.com")
+	doc := parseHTMLString(t, `<html><body>
+		<a href="mailto:info@example.com">Mail</a>
+		<a href="MAILTO:sales@example.com">Sales</a>
+		<a href="tel:+15551234">Call</a>
+		<a href="ftp://files.example.com/pub">Files</a>
+		<a href="javascript:void(0)">Menu</a>
+		<a href="/about">About</a>
+		<a href="https://other.com">Other</a>
+	</body></html>`)
+	result := &models.AnalysisResult{HtmlNode: doc, BaseUrl: baseURL}
+
+	assert.NoError(t, analyzeSchemeLinks(context.Background(), result))
+	assert.Equal(t, 2, result.MailtoLinks)
+	assert.Equal(t, 1, result.TelLinks)
+	assert.Equal(t, map[string]int{"ftp": 1, "javascript": 1}, result.OtherSchemeLinks)
+
+	internal, external := countLinks(context.Background(), doc, baseURL, linkNormalization{})
+	assert.Equal(t, 1, internal)
+	assert.Equal(t, 1, external)
+}
+
+func TestAnalyzeSchemeLinksByScheme(t *testing.T) {
+	baseURL, _ := url.Parse("https://example.com")
+	doc := parseHTMLString(t, `<html><body>
+		<a href="http://example.com/plain">Plain</a>
+		<a href="https://other.com">Other</a>
+		<a href="mailto:info@example.com">Mail</a>
+		<a href="tel:+15551234">Call</a>
+		<a href="ftp://files.example.com/pub">Files</a>
+		<a href="javascript:void(0)">Menu</a>
+		<a href="#top">Top</a>
+		<a href="/about">About</a>
+		<a>No href</a>
+	</body></html>`)
+	result := &models.AnalysisResult{HtmlNode: doc, BaseUrl: baseURL}
+
+	assert.NoError(t, analyzeSchemeLinks(context.Background(), result))
+	assert.Equal(t, map[string]int{
+		"http":       1,
+		"https":      1,
+		"mailto":     1,
+		"tel":        1,
+		"ftp":        1,
+		"javascript": 1,
+		"fragment":   1,
+		"relative":   1,
+	}, result.LinksByScheme)
+	assert.Equal(t, map[string]int{"ftp": 1, "javascript": 1}, result.OtherSchemeLinks)
+}
+
+func TestAnalyzeReportsFetchRetries(t *testing.T) {
+	mockWebClient := new(MockWebClient)
+	resp := htmlResponse(`<html><body><main></main></body></html>`)
+	resp.Retries = 1
+	mockWebClient.On("Do", mock.Anything, "https://example.com", http.MethodGet).Return(resp, nil)
+
+	result, err := NewAnalyzer(log.New(), mockWebClient).Analyze(context.Background(), "https://example.com")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.FetchRetries)
+}
+
+func TestAnalyzeRecordsSpans(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "https://example.com", http.MethodGet).
+		Return(htmlResponse(`<html><body><main></main></body></html>`), nil)
+
+	_, err := NewAnalyzer(log.New(), mockWebClient).Analyze(context.Background(), "https://example.com")
+	assert.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	byName := map[string]tracetest.SpanStub{}
+	for _, span := range spans {
+		byName[span.Name] = span
+	}
+
+	root, ok := byName["analyze"]
+	assert.True(t, ok, "analyze span recorded")
+	for _, name := range []string{"fetch", "parse", "checkLinksAccessibility", "countLinks", "getTitle"} {
+		span, ok := byName[name]
+		if assert.True(t, ok, "%s span recorded", name) {
+			assert.Equal(t, root.SpanContext.TraceID(), span.SpanContext.TraceID())
+			assert.Equal(t, root.SpanContext.SpanID(), span.Parent.SpanID(), "%s is a child of analyze", name)
+		}
+	}
+}
+
+func TestAnalyzeReportsTLSCertificate(t *testing.T) {
+	newCert := func(notAfter time.Time) *x509.Certificate {
+		return &x509.Certificate{
+			Subject:  pkix.Name{CommonName: "example.com"},
+			Issuer:   pkix.Name{CommonName: "Example CA", Organization: []string{"Example Org"}},
+			NotAfter: notAfter,
+		}
+	}
+
+	tests := []struct {
+		name        string
+		notAfter    time.Time
+		wantWarning bool
+	}{
+		{name: "valid for months", notAfter: time.Now().Add(90 * 24 * time.Hour)},
+		{name: "expires soon", notAfter: time.Now().Add(5 * 24 * time.Hour), wantWarning: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := htmlResponse(`<html><body><main></main></body></html>`)
+			resp.PeerCertificate = newCert(tt.notAfter)
+			mockWebClient := new(MockWebClient)
+			mockWebClient.On("Do", mock.Anything, "https://example.com", http.MethodGet).Return(resp, nil)
+			analyzer := NewAnalyzer(log.New(), mockWebClient, WithCertExpiryWarning(30*24*time.Hour))
+
+			result, err := analyzer.Analyze(context.Background(), "https://example.com")
+
+			assert.NoError(t, err)
+			assert.Equal(t, "CN=example.com", result.TLSSubject)
+			assert.Equal(t, "CN=Example CA,O=Example Org", result.TLSIssuer)
+			assert.True(t, tt.notAfter.Equal(result.TLSNotAfter))
+			hasWarning := false
+			for _, w := range result.Warnings {
+				if strings.HasPrefix(w, "TLS certificate expires") {
+					hasWarning = true
+				}
+			}
+			assert.Equal(t, tt.wantWarning, hasWarning)
+		})
+	}
+}
+
+func TestAnalyzeSkipsLinkChecksForMatchingHosts(t *testing.T) {
+	var probed atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probed.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer target.Close()
+
+	// .invalid hosts never resolve, so probing them would report them inaccessible
+	htmlContent := `<html><body>
+		<a href="https://fonts.googleapis.invalid/css">Fonts</a>
+		<a href="https://www.analytics.invalid/collect">Analytics</a>
+		<a href="` + target.URL + `/missing">Missing</a>
+	</body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithSkippedLinkHosts("*.googleapis.invalid", "www.analytics.invalid"))
+
+	var checks []models.LinkCheck
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), "http://example.com", models.AnalysisOptions{
+		OnLinkCheck: func(check models.LinkCheck) { checks = append(checks, check) },
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.ExternalLinks, "skipped links are still counted")
+	assert.Equal(t, 2, result.SkippedLinks)
+	assert.Equal(t, 1, result.InaccessibleLinks)
+	assert.Equal(t, int32(1), probed.Load())
+
+	skipped := 0
+	for _, check := range checks {
+		if check.Skipped {
+			skipped++
+		}
+	}
+	assert.Len(t, checks, 3)
+	assert.Equal(t, 2, skipped)
+}
+
+// panicTransport panics for requests to panicHost and answers 200 otherwise
+type panicTransport struct {
+	panicHost string
+}
+
+func (p panicTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Host == p.panicHost {
+		panic("boom")
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+func TestAnalyzeRecoversLinkCheckPanic(t *testing.T) {
+	htmlContent := `<html><body>
+		<a href="http://panics.example/">Panics</a>
+		<a href="http://fine.example/">Fine</a>
+	</body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithLinkCheckTransport(panicTransport{panicHost: "panics.example"}))
+
+	var checks []models.LinkCheck
+	result, err := analyzer.AnalyzeWithOptions(context.Background(), "http://example.com", models.AnalysisOptions{
+		OnLinkCheck: func(check models.LinkCheck) { checks = append(checks, check) },
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.InaccessibleLinks)
+	assert.Len(t, checks, 2)
+	for _, check := range checks {
+		if check.URL == "http://panics.example/" {
+			assert.False(t, check.Accessible)
+			assert.ErrorContains(t, check.Err, "panicked")
+		}
+	}
+}
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestAnalyzePropagatesRequestID(t *testing.T) {
+	htmlContent := `<html><body><a href="http://example.com/about">About</a></body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+	mockWebClient.On("Do", mock.Anything, "http://down.example.com", http.MethodGet).Return(nil, errors.New("connection refused"))
+
+	var probeID atomic.Value
+	transport := WithLinkCheckTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		probeID.Store(req.Header.Get("X-Request-ID"))
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+	}))
+	logger, hook := test.NewNullLogger()
+	ctx := requestid.NewContext(context.Background(), "req-7")
+
+	_, err := NewAnalyzer(logger, mockWebClient, transport).Analyze(ctx, "http://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "", probeID.Load(), "link checks keep the request id by default")
+
+	analyzer := NewAnalyzer(logger, mockWebClient, transport, WithRequestIDForwarding())
+	_, err = analyzer.Analyze(ctx, "http://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "req-7", probeID.Load(), "link checks pass the request id on when forwarding")
+
+	_, err = analyzer.Analyze(ctx, "http://down.example.com")
+	assert.Error(t, err)
+	if entry := hook.LastEntry(); assert.NotNil(t, entry) {
+		assert.Equal(t, "req-7", entry.Data["request_id"])
+	}
+}
+
+func TestAnalyzeTimesOutOnSlowLinkChecks(t *testing.T) {
+	htmlContent := `<html><body><a href="http://slow.example/">Slow</a></body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithLinkCheckTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(5 * time.Second):
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+		}
+	})))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := analyzer.Analyze(ctx, "http://example.com")
+
+	kind, ok := ErrorKindOf(err)
+	assert.True(t, ok)
+	assert.Equal(t, KindTimeout, kind)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestAnalyzeClassifiesLinkCheckErrors(t *testing.T) {
+	htmlContent := `<html><body>
+		<a href="http://missing.example/">Missing</a>
+		<a href="http://down.example/">Down</a>
+	</body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithLinkCheckTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host == "missing.example" {
+			return nil, &net.DNSError{Err: "no such host", Name: req.URL.Host, IsNotFound: true}
+		}
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}
+	})))
+
+	checks := map[string]models.LinkCheck{}
+	_, err := analyzer.AnalyzeWithOptions(context.Background(), "http://example.com", models.AnalysisOptions{
+		OnLinkCheck: func(check models.LinkCheck) { checks[check.URL] = check },
+	})
+
+	assert.NoError(t, err)
+	assert.ErrorIs(t, checks["http://missing.example/"].Err, adaptors.ErrHostNotFound)
+	assert.ErrorIs(t, checks["http://down.example/"].Err, adaptors.ErrConnectionRefused)
+}
+
+func TestCheckLinksAccessibilityStopsOnCancel(t *testing.T) {
+	var probed atomic.Int32
+	analyzer := NewAnalyzer(log.New(), nil, WithLinkCheckTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		probed.Add(1)
+		// hang until the request is cancelled
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})))
+	links := make([]linkInfo, 100)
+	for i := range links {
+		links[i] = linkInfo{url: fmt.Sprintf("http://example.com/%d", i)}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	inaccessible, _ := analyzer.checkLinksAccessibility(ctx, links)
+
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "link checks outlived the cancelled context")
+	assert.Equal(t, int(probed.Load()), inaccessible)
+	assert.Less(t, inaccessible, len(links))
+}
+
+func TestLinkCheckFallsBackToGet(t *testing.T) {
+	analyzer := NewAnalyzer(log.New(), nil, WithLinkCheckTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		status := http.StatusOK
+		switch {
+		case strings.HasSuffix(req.URL.Path, "/gone"):
+			status = http.StatusNotFound
+		case strings.HasSuffix(req.URL.Path, "/forbidden"):
+			status = http.StatusForbidden
+		case req.Method == http.MethodHead && strings.HasSuffix(req.URL.Path, "/no-head"):
+			status = http.StatusMethodNotAllowed
+		case req.Method == http.MethodHead && strings.HasSuffix(req.URL.Path, "/head-unimplemented"):
+			status = http.StatusNotImplemented
+		}
+		return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("body")), Request: req}, nil
+	})))
+	links := []linkInfo{
+		{url: "http://example.com/no-head"},
+		{url: "http://example.com/head-unimplemented"},
+		{url: "http://example.com/forbidden"},
+		{url: "http://example.com/gone"},
+	}
+
+	checks := map[string]models.LinkCheck{}
+	ctx := context.WithValue(context.Background(), linkCheckObserverKey{}, func(check models.LinkCheck) { checks[check.URL] = check })
+	inaccessible, _ := analyzer.checkLinksAccessibility(ctx, links)
+
+	assert.Equal(t, 2, inaccessible)
+	assert.True(t, checks["http://example.com/no-head"].Accessible)
+	assert.True(t, checks["http://example.com/head-unimplemented"].Accessible)
+	assert.Equal(t, http.StatusForbidden, checks["http://example.com/forbidden"].StatusCode, "GET is forbidden too")
+	assert.Equal(t, http.StatusNotFound, checks["http://example.com/gone"].StatusCode)
+}
+
+// endlessBody is a response body that never ends, counting the bytes read
+type endlessBody struct {
+	read atomic.Int64
+}
+
+func (b *endlessBody) Read(p []byte) (int, error) {
+	b.read.Add(int64(len(p)))
+	return len(p), nil
+}
+
+func (b *endlessBody) Close() error { return nil }
+
+func TestLinkCheckCapsDrainedBody(t *testing.T) {
+	body := &endlessBody{}
+	analyzer := NewAnalyzer(log.New(), nil, WithLinkCheckTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.Method == http.MethodHead {
+			return &http.Response{StatusCode: http.StatusMethodNotAllowed, Body: http.NoBody, Request: req}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Body: body, Request: req}, nil
+	})))
+
+	inaccessible, _ := analyzer.checkLinksAccessibility(context.Background(), []linkInfo{{url: "http://example.com/stream"}})
+
+	assert.Equal(t, 0, inaccessible)
+	assert.LessOrEqual(t, body.read.Load(), int64(maxProbeDrainBytes))
+}
+
+func TestLinkCheckConcurrency(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+	analyzer := NewAnalyzer(log.New(), nil, WithLinkCheckConcurrency(1), WithLinkCheckTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})))
+	links := make([]linkInfo, 10)
+	for i := range links {
+		links[i] = linkInfo{url: fmt.Sprintf("http://example.com/%d", i)}
+	}
+
+	inaccessible, _ := analyzer.checkLinksAccessibility(context.Background(), links)
+
+	assert.Equal(t, 0, inaccessible)
+	assert.Equal(t, int32(1), maxInFlight.Load())
+}
+
+func TestLinkCheckConcurrencySharedAcrossProbes(t *testing.T) {
+	var inFlight, maxInFlight, probes atomic.Int32
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		probes.Add(1)
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	})
+	htmlContent := `<html><head>
+		<link rel="stylesheet" href="http://cdn.example.net/a.css">
+		<link rel="stylesheet" href="http://cdn.example.net/b.css">
+	</head><body>
+		<a href="http://other.example.net/1">1</a><a href="http://other.example.net/2">2</a>
+		<img src="http://cdn.example.net/1.png"><img src="http://cdn.example.net/2.png">
+	</body></html>`
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(htmlContent), nil)
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithLinkCheckConcurrency(1), WithLinkCheckTransport(transport))
+
+	_, err := analyzer.AnalyzeWithOptions(context.Background(), "http://example.com", models.AnalysisOptions{CheckStylesheets: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(6), probes.Load())
+	assert.Equal(t, int32(1), maxInFlight.Load(), "link, image and stylesheet probes share one limit")
+}
+
+func TestLinkCheckTimeout(t *testing.T) {
+	analyzer := NewAnalyzer(log.New(), nil, WithLinkCheckTimeout(50*time.Millisecond), WithLinkCheckTransport(roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		// never answers before the client gives up
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})))
+
+	start := time.Now()
+	inaccessible, _ := analyzer.checkLinksAccessibility(context.Background(), []linkInfo{{url: "http://slow.example/"}})
+
+	assert.Equal(t, 1, inaccessible)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "link check outlived its timeout")
+}
+
+func TestAnalyzeRecoversAnalyzerPanic(t *testing.T) {
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(htmlResponse(`<html></html>`), nil)
+	analyzer := NewAnalyzer(log.New(), mockWebClient)
+	analyzer.domAnalyzers = append(analyzer.domAnalyzers, analysisStep{
+		name: "panickingStep",
+		run: func(ctx context.Context, result *models.AnalysisResult) error {
+			panic("boom")
+		},
+	})
+	analyzer.networkAnalyzers = append(analyzer.networkAnalyzers, analysisStep{
+		name: "panickingProbe",
+		run: func(ctx context.Context, result *models.AnalysisResult) error {
+			panic("boom")
+		},
+	})
+
+	_, err := analyzer.Analyze(context.Background(), "http://example.com")
+	assert.ErrorContains(t, err, "panickingStep panicked")
+}
+
+func TestFetchHeader(t *testing.T) {
+	assert.Empty(t, fetchHeader(models.AnalysisOptions{}))
+	header := fetchHeader(models.AnalysisOptions{BearerToken: "t0ken", HostHeader: "www.example.com", UserAgent: "preview-bot/2.0"})
+	assert.Equal(t, "Bearer t0ken", header.Get("Authorization"))
+	assert.Equal(t, "www.example.com", header.Get("Host"))
+	assert.Equal(t, "preview-bot/2.0", header.Get("User-Agent"))
+}
+
+func TestAnalyzeAcceptsOnlyHTML(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		wantErr     bool
+	}{
+		{name: "html", contentType: "text/html; charset=utf-8"},
+		{name: "xhtml", contentType: "application/xhtml+xml"},
+		{name: "missing", contentType: ""},
+		{name: "json", contentType: "application/json", wantErr: true},
+		{name: "pdf", contentType: "application/pdf", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := htmlResponse(`<html><head><title>Page</title></head></html>`)
+			response.Header = http.Header{}
+			if tt.contentType != "" {
+				response.Header.Set("Content-Type", tt.contentType)
+			}
+			mockWebClient := new(MockWebClient)
+			mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(response, nil)
+
+			result, err := NewAnalyzer(log.New(), mockWebClient).Analyze(context.Background(), "http://example.com")
+
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrNotHTML)
+				kind, _ := ErrorKindOf(err)
+				assert.Equal(t, KindUnsupportedContentType, kind)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "Page", result.Title)
+		})
+	}
+}
+
+func TestAnalyzeReportsRedirects(t *testing.T) {
+	redirected := htmlResponse(`<html></html>`)
+	redirected.FinalURL = "https://www.example.com/home"
+	redirected.RedirectChain = []string{"http://example.com", "https://example.com/"}
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(redirected, nil)
+	mockWebClient.On("Do", mock.Anything, "http://direct.example.com", http.MethodGet).Return(htmlResponse(`<html></html>`), nil)
+	analyzer := NewAnalyzer(log.New(), mockWebClient)
+
+	result, err := analyzer.Analyze(context.Background(), "http://example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://www.example.com/home", result.FinalURL)
+	assert.Equal(t, 2, result.RedirectCount)
+
+	result, err = analyzer.Analyze(context.Background(), "http://direct.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "http://direct.example.com", result.FinalURL)
+	assert.Equal(t, 0, result.RedirectCount)
+}
+
+func TestAnalyzeClassifiesLinksAgainstRedirectTarget(t *testing.T) {
+	redirected := htmlResponse(`<html><body>
+		<a href="/about">About</a>
+		<a href="https://other.example/contact">Contact</a>
+		<a href="http://example.com/old">Old</a>
+	</body></html>`)
+	redirected.FinalURL = "https://other.example/home"
+	redirected.RedirectChain = []string{"http://example.com"}
+	mockWebClient := new(MockWebClient)
+	mockWebClient.On("Do", mock.Anything, "http://example.com", http.MethodGet).Return(redirected, nil)
+
+	result, err := NewAnalyzer(log.New(), mockWebClient).AnalyzeWithOptions(context.Background(), "http://example.com",
+		models.AnalysisOptions{SkipNetworkChecks: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://other.example/home", result.BaseUrl.String())
+	assert.Equal(t, 2, result.InternalLinks, "/about resolves on the redirect target's host")
+	assert.Equal(t, 1, result.ExternalLinks, "the submitted host is external once the page moved")
+}
+
+func TestAnalyzeHTML(t *testing.T) {
+	htmlContent := `<!DOCTYPE html><html><head><title>Local</title></head><body>
+		<h1>Heading</h1>
+		<a href="/about">About</a>
+		<a href="https://other.example/">Other</a>
+		<img src="/logo.png">
+	</body></html>`
+	mockWebClient := new(MockWebClient)
+	var probed atomic.Int32
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		probed.Add(1)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+	})
+	analyzer := NewAnalyzer(log.New(), mockWebClient, WithLinkCheckTransport(transport))
+
+	result, err := analyzer.AnalyzeHTMLWithOptions(context.Background(), []byte(htmlContent), "https://example.com/",
+		models.AnalysisOptions{SkipNetworkChecks: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Local", result.Title)
+	assert.Equal(t, "HTML5", result.HTMLVersion)
+	assert.Equal(t, 1, result.Headings["h1"])
+	assert.Equal(t, 1, result.InternalLinks)
+	assert.Equal(t, 1, result.ExternalLinks)
+	assert.Equal(t, "https://example.com/", result.BaseUrl.String())
+	assert.Contains(t, result.Timings, "parseHTML")
+	assert.Zero(t, probed.Load(), "network checks were skipped")
+	mockWebClient.AssertNotCalled(t, "Do", mock.Anything, mock.Anything, mock.Anything)
+
+	result, err = analyzer.AnalyzeHTML(context.Background(), []byte(htmlContent), "https://example.com/")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.ImagesTotal)
+	assert.Equal(t, int32(3), probed.Load(), "both links and the image are checked by default")
+}
+
+func TestAnalyzeHTMLRequiresBaseURL(t *testing.T) {
+	analyzer := NewAnalyzer(log.New(), new(MockWebClient))
+
+	_, err := analyzer.AnalyzeHTML(context.Background(), []byte("<html></html>"), "")
+
+	assert.ErrorIs(t, err, ErrInvalidURL)
+	kind, _ := ErrorKindOf(err)
+	assert.Equal(t, KindInvalidURL, kind)
+}
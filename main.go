@@ -6,6 +6,8 @@ import (
 	"time"
 	"web_page_analyzer/internal/application/config"
 	"web_page_analyzer/internal/http"
+	"web_page_analyzer/internal/pkg/metrics"
+	"web_page_analyzer/internal/pkg/tracing"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -36,6 +38,28 @@ func main() {
 	// Get context
 	ctx := context.WithoutCancel(context.Background())
 
+	shutdownTracing, err := tracing.Init(ctx, cfg.OTelExporterEndpoint, `web_page_analyzer`)
+	if err != nil {
+		logInstance.WithError(err).Fatal(`Failed to init tracing`)
+		return
+	}
+	defer func() {
+		if err := shutdownTracing(ctx); err != nil {
+			logInstance.WithError(err).Error(`Failed to shutdown tracing`)
+		}
+	}()
+
+	stopStatsD, err := metrics.InitStatsD(cfg.StatsDAddress, cfg.StatsDPrefix)
+	if err != nil {
+		logInstance.WithError(err).Fatal(`Failed to init statsd`)
+		return
+	}
+	defer func() {
+		if err := stopStatsD(); err != nil {
+			logInstance.WithError(err).Error(`Failed to stop statsd`)
+		}
+	}()
+
 	// Init HTTP
 	http.Init(ctx, logInstance, cfg)
 }